@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"dockerops/internal/puller"
+)
+
+// runBatchPull 从镜像列表文件批量拉取镜像，返回失败的数量
+func runBatchPull(imagePuller *puller.MultiRegistryImagePuller, listFile, defaultArch string, options puller.PullOptions, security *outputSecurity, partSize int64) int {
+	entries, err := puller.LoadImageList(listFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载镜像列表失败: %v\n", err)
+		return 1
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("镜像列表 %s 为空\n", listFile)
+		return 0
+	}
+
+	fmt.Printf("正在批量拉取 %d 个镜像（并发数: %d）...\n", len(entries), concurrency)
+
+	start := time.Now()
+	results := imagePuller.PullBatch(entries, defaultArch, username, password, concurrency, options)
+
+	failed := printBatchSummary(results)
+
+	if loadAfterPull {
+		failed += loadBatchResults(results)
+	}
+
+	var files []string
+	for _, result := range results {
+		if result.Err == nil && result.OutputFile != "" && !result.Skipped && !(loadAfterPull && removeArchive) {
+			files = append(files, result.OutputFile)
+		}
+	}
+	finalizeArchives(files, security, partSize)
+
+	fmt.Printf("\n总耗时: %s，成功 %d 个，失败 %d 个\n", time.Since(start).Round(time.Second), len(results)-failed, failed)
+
+	return failed
+}
+
+// loadBatchResults 依次导入批量拉取成功的镜像，已存在的归档同样导入但不会被 --rm 删除，返回导入失败的数量
+func loadBatchResults(results []puller.BatchPullResult) int {
+	failed := 0
+	fmt.Println()
+	for i := range results {
+		result := &results[i]
+		if result.Err != nil || result.PullResult == nil || result.OutputFile == "" {
+			continue
+		}
+		if err := loadPulledImage(result.PullResult); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 导入 %s 失败: %v\n", result.Entry.Reference(), err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// printBatchSummary 打印批量拉取结果表格，返回失败数量
+func printBatchSummary(results []puller.BatchPullResult) int {
+	failed := 0
+
+	fmt.Fprintln(messages)
+	w := tabwriter.NewWriter(messages, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "镜像\t架构\t状态\t大小\t仓库\t耗时\t输出文件")
+	for _, result := range results {
+		status := "✅ 成功"
+		if result.Err != nil {
+			status = "❌ 失败"
+			failed++
+		} else if result.Skipped {
+			status = "⏭️ 已存在"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.Entry.Reference(),
+			result.Arch,
+			status,
+			formatSize(result.Size),
+			valueOrDash(result.Registry),
+			result.Duration.Round(time.Second),
+			valueOrDash(result.OutputFile),
+		)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fmt.Fprintln(messages, "\n失败原因:")
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Fprintf(messages, "  %s: %v\n", result.Entry.Reference(), result.Err)
+			}
+		}
+	}
+
+	return failed
+}
+
+// formatSize 将字节数格式化为易读的大小
+func formatSize(size int64) string {
+	return puller.FormatSize(size)
+}
+
+// valueOrDash 空字符串显示为 -
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
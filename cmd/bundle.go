@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+// bundleCmd 多镜像打包命令
+var bundleCmd = &cobra.Command{
+	Use:   "bundle [IMAGE...]",
+	Short: "拉取多个镜像并打包为一个归档",
+	Long: `拉取多个镜像并打包为一个 docker-archive 归档，镜像之间共享的层只保存一次。
+归档的 manifest.json 包含所有镜像的 RepoTags，使用一次 docker load 或 DockerOps load 即可全部导入。`,
+	Run: runBundle,
+}
+
+func init() {
+	bundleCmd.Flags().StringVarP(&imageList, "file", "f", "", "镜像列表文件（.txt 每行一个镜像，或 .yaml/.json）")
+	bundleCmd.Flags().StringVarP(&arch, "arch", "a", "", "默认架构（默认使用配置中的 default_architecture）")
+	bundleCmd.Flags().StringVarP(&username, "username", "u", "", "Docker 仓库用户名")
+	bundleCmd.Flags().StringVarP(&password, "password", "p", "", "Docker 仓库密码")
+	bundleCmd.Flags().StringVarP(&output, "output", "o", "", "输出文件路径，使用 - 写入标准输出")
+	bundleCmd.Flags().StringVar(&compress, "compress", "none", "输出压缩格式：none、gzip、zstd")
+	bundleCmd.Flags().StringVar(&tagMode, "tag-mode", "", "打标签模式：default、original、mirror、custom，可用逗号组合")
+	bundleCmd.Flags().IntVarP(&concurrency, "concurrency", "j", 3, "并发拉取的镜像数")
+	bundleCmd.Flags().BoolVar(&writeManifest, "manifest", false, "在输出目录写入离线传输清单 "+puller.BundleManifestName)
+	addOutputFlags(bundleCmd)
+	addSplitFlag(bundleCmd)
+	addBaselineFlag(bundleCmd)
+	addEncryptFlags(bundleCmd)
+
+	rootCmd.AddCommand(bundleCmd)
+}
+
+// runBundle 执行多镜像打包命令
+func runBundle(cmd *cobra.Command, args []string) {
+	if debug {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+
+	var entries []puller.ImageListEntry
+	if imageList != "" {
+		listEntries, err := puller.LoadImageList(imageList)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载镜像列表失败: %v\n", err)
+			os.Exit(1)
+		}
+		entries = append(entries, listEntries...)
+	}
+	for _, arg := range args {
+		entries = append(entries, puller.ImageListEntry{Image: arg})
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintf(os.Stderr, "错误：请指定镜像或使用 --file 指定镜像列表\n")
+		os.Exit(1)
+	}
+
+	compression, err := puller.NormalizeCompression(compress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+	if err := puller.ValidateTagMode(tagMode); err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+	partSize := parseSplitSize()
+	if partSize > 0 && output == "-" {
+		fmt.Fprintf(os.Stderr, "错误：--split-size 不能与 -o - 同时使用\n")
+		os.Exit(1)
+	}
+	security := loadOutputSecurity()
+
+	// 输出到标准输出时，其余提示信息全部改写到标准错误
+	if output == "-" {
+		messages = os.Stderr
+	}
+
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+	defer imagePuller.CleanupTmpDir()
+
+	if arch == "" {
+		arch = configManager.GetConfig().Settings.DefaultArchitecture
+	}
+
+	showBanner()
+	fmt.Fprintf(messages, "正在打包 %d 个镜像（并发数: %d）...\n", len(entries), concurrency)
+
+	result, err := imagePuller.PullBundle(entries, arch, username, password, concurrency, puller.PullOptions{
+		Output:       output,
+		OutputDir:    outputDir,
+		NameTemplate: nameTmpl,
+		Force:        force,
+		Compression:  compression,
+		TagMode:      tagMode,
+		Stdout:       os.Stdout,
+		SplitSize:    security.streamSplitSize(partSize),
+		Baseline:     baseline,
+	})
+	if result != nil && len(result.Images) > 0 {
+		printBatchSummary(result.Images)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\n打包失败: %v\n", err)
+		imagePuller.CleanupTmpDir()
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(messages, "\n🎉 打包完成！共 %d 个层，复用共享层 %d 次\n", result.Layers, result.SharedLayers)
+	if baseline != "" {
+		fmt.Fprintf(messages, "增量归档：%d 个层已在基线中，未写入归档，导入前请在目标环境执行 DockerOps merge\n", result.BaselineLayers)
+	}
+	if result.OutputFile != "-" {
+		result.OutputFile = finalizeArchives([]string{result.OutputFile}, security, partSize)[0]
+		fmt.Fprintf(messages, "输出文件：%s (%s)\n", result.OutputFile, formatSize(result.Size))
+		if partSize > 0 {
+			fmt.Fprintf(messages, "可使用以下命令导入全部镜像: DockerOps load %s\n", result.OutputFile)
+		} else {
+			fmt.Fprintf(messages, "可使用以下命令导入全部镜像: docker load -i %s\n", result.OutputFile)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	catalogFilter string
+	catalogTags   bool
+	catalogJSON   bool
+)
+
+// catalogCmd 仓库目录命令
+var catalogCmd = &cobra.Command{
+	Use:   "catalog <仓库名称>",
+	Short: "列出配置中某个镜像仓库的全部镜像路径",
+	Long: `通过 /v2/_catalog 分页列出 config.json 中配置的镜像仓库（例如内部 Harbor）中的镜像路径，
+可按正则表达式过滤镜像路径，并可同时列出每个镜像路径的标签。
+仓库名称可以是配置中的 name 或 url。`,
+	Example: `  DockerOps catalog harbor -u admin -p secret
+  DockerOps catalog harbor --filter "^library/" --tags
+  DockerOps catalog harbor --json | jq -r '.repositories[].name'`,
+	Args: cobra.ExactArgs(1),
+	Run:  runCatalog,
+}
+
+func init() {
+	catalogCmd.Flags().StringVar(&catalogFilter, "filter", "", "只显示匹配正则表达式的镜像路径")
+	catalogCmd.Flags().BoolVar(&catalogTags, "tags", false, "同时列出每个镜像路径的标签")
+	catalogCmd.Flags().BoolVar(&catalogJSON, "json", false, "以 JSON 格式输出")
+	catalogCmd.Flags().StringVarP(&username, "username", "u", "", "仓库用户名")
+	catalogCmd.Flags().StringVarP(&password, "password", "p", "", "仓库密码")
+	catalogCmd.Flags().BoolVar(&plainHTTP, "plain-http", false, "使用 HTTP 访问仓库")
+
+	rootCmd.AddCommand(catalogCmd)
+}
+
+// runCatalog 执行仓库目录命令
+func runCatalog(cmd *cobra.Command, args []string) {
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+
+	result, err := imagePuller.Catalog(args[0], puller.CatalogOptions{
+		Username:  username,
+		Password:  password,
+		Filter:    catalogFilter,
+		Tags:      catalogTags,
+		PlainHTTP: plainHTTP,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取仓库目录失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if catalogJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "序列化失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if catalogTags {
+		fmt.Fprintln(w, "镜像路径\t标签数\t标签")
+	} else {
+		fmt.Fprintln(w, "镜像路径")
+	}
+	for _, repository := range result.Repositories {
+		if !catalogTags {
+			fmt.Fprintln(w, repository.Name)
+			continue
+		}
+		if repository.Error != "" {
+			fmt.Fprintf(w, "%s\t-\t❌ %s\n", repository.Name, repository.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", repository.Name, len(repository.Tags), valueOrDash(strings.Join(repository.Tags, ", ")))
+	}
+	w.Flush()
+
+	fmt.Printf("\n%s (%s) 共 %d 个镜像路径，显示 %d 个\n", result.Registry, result.URL, result.Total, len(result.Repositories))
+}
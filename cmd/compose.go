@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dockerops/internal/compose"
+	"dockerops/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	composeFiles    []string
+	composeEnvFiles []string
+	composeProfiles []string
+)
+
+func init() {
+	saveComposeCmd.Flags().StringArrayVarP(&composeFiles, "file", "f", nil, "Compose 文件，可重复指定，后面的覆盖前面的")
+	saveComposeCmd.Flags().StringArrayVar(&composeEnvFiles, "env-file", nil, "变量插值使用的 env 文件，可重复指定（默认使用项目目录下的 .env）")
+	saveComposeCmd.Flags().StringArrayVar(&composeProfiles, "profile", nil, "启用的 profile，可重复指定，* 表示全部")
+}
+
+// runSaveCompose 执行保存compose镜像命令
+func runSaveCompose(cmd *cobra.Command, args []string) {
+	project, err := compose.Load(compose.Options{
+		Files:    composeFiles,
+		EnvFiles: composeEnvFiles,
+		Profiles: composeProfiles,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载 Compose 文件失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("正在从 %s 中提取并保存镜像...\n", strings.Join(project.Files, "、"))
+
+	if len(project.Disabled) > 0 {
+		fmt.Printf("未启用 profile 的服务（已忽略）: %s\n", strings.Join(project.Disabled, ", "))
+	}
+	for _, service := range project.BuildOnly() {
+		fmt.Fprintf(os.Stderr, "⚠️ 服务 %s 只定义了 build 没有 image，未保存；请为其设置 image，或构建后保存 %s\n", service.Name, project.BuiltImageName(service))
+	}
+
+	images := project.Images()
+	if len(images) == 0 {
+		fmt.Println("Compose 文件中未找到镜像定义")
+		return
+	}
+
+	configManager := config.NewConfigManager(configFile)
+
+	// 创建images目录
+	dirName := resolveOutputDir(configManager, "images")
+	if err := os.MkdirAll(dirName, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "创建images目录失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 保存每个镜像
+	for _, image := range images {
+		_, repo, tag := splitImageReference(image)
+
+		// 生成文件名
+		fileName := fmt.Sprintf("%s_%s.tar", strings.ReplaceAll(repo, "/", "_"), tag)
+		filePath, err := resolveSavePath(configManager, dirName, image, fileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ 跳过镜像 %s: %v\n", image, err)
+			fmt.Println("------------------------")
+			continue
+		}
+		fileName = filepath.Base(filePath)
+
+		fmt.Printf("正在保存镜像: %s 到 %s\n", image, fileName)
+		if err := saveImages(filePath, image); err != nil {
+			fmt.Fprintf(os.Stderr, "保存镜像 %s 失败: %v\n", image, err)
+		} else {
+			fmt.Printf("✅ 镜像已保存: %s\n", fileName)
+		}
+		fmt.Println("------------------------")
+	}
+
+	fmt.Println("所有镜像已保存完毕。")
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	copyPlatforms    []string
+	copyAllPlatforms bool
+	destUsername     string
+	destPassword     string
+)
+
+// copyCmd 仓库间复制命令
+var copyCmd = &cobra.Command{
+	Use:   "copy <源镜像> <目标镜像>",
+	Short: "将镜像从镜像源直接复制到目标仓库",
+	Long: `在配置的镜像源中搜索源镜像，将 blob 边下载边上传到目标仓库（例如内部 Harbor），
+不需要本地 Docker，也不在本地落盘。目标仓库中已存在的 blob 会被跳过。
+默认只复制 --arch 指定的平台；使用 --platform 或 --all-platforms 复制多架构镜像。`,
+	Example: `  DockerOps copy nginx:1.25 harbor.local/library/nginx:1.25
+  DockerOps copy bitnami/redis:7 harbor.local/proj/redis:7 --platform linux/amd64,linux/arm64
+  DockerOps copy nginx:1.25 harbor.local/library/nginx:1.25 --all-platforms --dest-username admin --dest-password secret`,
+	Args: cobra.ExactArgs(2),
+	Run:  runCopy,
+}
+
+func init() {
+	copyCmd.Flags().StringVarP(&arch, "arch", "a", "", "单架构复制时的架构，默认：amd64")
+	copyCmd.Flags().StringSliceVar(&copyPlatforms, "platform", nil, "要复制的平台，可用逗号分隔或重复指定（例如 linux/amd64,linux/arm64）")
+	copyCmd.Flags().BoolVar(&copyAllPlatforms, "all-platforms", false, "复制源镜像的全部平台")
+	copyCmd.Flags().StringVarP(&username, "username", "u", "", "源仓库用户名")
+	copyCmd.Flags().StringVarP(&password, "password", "p", "", "源仓库密码")
+	copyCmd.Flags().StringVar(&destUsername, "dest-username", "", "目标仓库用户名")
+	copyCmd.Flags().StringVar(&destPassword, "dest-password", "", "目标仓库密码")
+	copyCmd.Flags().StringVar(&pushChunkSize, "chunk-size", "", "分块上传的块大小（例如 16M），默认整体上传")
+	copyCmd.Flags().StringArrayVar(&pushMountFrom, "mount-from", nil, "目标仓库中可能已有相同层的镜像路径，尝试跨仓库挂载，可重复指定")
+	copyCmd.Flags().BoolVar(&plainHTTP, "plain-http", false, "使用 HTTP 访问目标仓库")
+
+	rootCmd.AddCommand(copyCmd)
+}
+
+// parseChunkSize 解析 --chunk-size，出错时退出
+func parseChunkSize() int64 {
+	if pushChunkSize == "" {
+		return 0
+	}
+	size, err := puller.ParseSize(pushChunkSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+	return size
+}
+
+// runCopy 执行仓库间复制命令
+func runCopy(cmd *cobra.Command, args []string) {
+	source, target := args[0], args[1]
+
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+
+	if arch == "" {
+		arch = configManager.GetConfig().Settings.DefaultArchitecture
+	}
+
+	fmt.Printf("正在复制 %s 到 %s...\n", source, target)
+	result, err := imagePuller.CopyImage(source, target, puller.CopyOptions{
+		Arch:         arch,
+		Platforms:    copyPlatforms,
+		AllPlatforms: copyAllPlatforms,
+		Username:     username,
+		Password:     password,
+		Dest: puller.PushOptions{
+			Username:  destUsername,
+			Password:  destPassword,
+			ChunkSize: parseChunkSize(),
+			MountFrom: pushMountFrom,
+			PlainHTTP: plainHTTP,
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "复制失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n🎉 复制完成：%s\n", result.Target)
+	fmt.Printf("源仓库：%s\n", result.Registry)
+	fmt.Printf("清单digest：%s\n", result.Digest)
+	if len(result.Platforms) > 0 {
+		fmt.Printf("平台：%s\n", strings.Join(result.Platforms, ", "))
+	}
+	fmt.Printf("共 %d 个 blob：传输 %d 个 (%s)，跨仓库挂载 %d 个，已存在 %d 个\n",
+		result.Blobs, result.Copied, formatSize(result.Size), result.Mounted, result.Existing)
+}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// passphraseEnv 提供加密口令的环境变量，适用于非交互环境
+const passphraseEnv = "DOCKEROPS_PASSPHRASE"
+
+var (
+	encryptOutput  bool
+	recipients     []string
+	signKeyFile    string
+	identities     []string
+	trustedKeys    []string
+	skipSignature  bool
+	passphraseFile string
+	keyType        string
+)
+
+// keygenCmd 生成密钥对命令
+var keygenCmd = &cobra.Command{
+	Use:   "keygen <名称>",
+	Short: "生成加密或签名密钥对",
+	Long: `生成密钥对，写入 <名称>.key（私钥）和 <名称>.pub（公钥），均为 PEM 格式。
+  x25519: 加密接收方密钥，使用 --recipient <名称>.pub 加密，--identity <名称>.key 解密
+  ed25519: 签名密钥，使用 --sign <名称>.key 签名清单，--pubkey <名称>.pub 校验签名`,
+	Args: cobra.ExactArgs(1),
+	Run:  runKeygen,
+}
+
+// decryptCmd 解密归档命令
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt <加密归档>",
+	Short: "校验签名后解密归档",
+	Long: `解密 ` + puller.EncryptedSuffix + ` 归档（支持分卷索引）。指定 --pubkey（或配置 trusted_keys）时先校验离线传输清单的签名和文件 sha256，
+校验通过后才会解密；清单已签名但没有受信任的公钥时拒绝解密，除非指定 --insecure-skip-signature。`,
+	Args: cobra.ExactArgs(1),
+	Run:  runDecrypt,
+}
+
+func init() {
+	keygenCmd.Flags().StringVar(&keyType, "type", "x25519", "密钥类型：x25519（加密）或 ed25519（签名）")
+
+	decryptCmd.Flags().StringVarP(&output, "output", "o", "", "解密后的文件路径（默认去掉 "+puller.EncryptedSuffix+" 后缀）")
+	decryptCmd.Flags().BoolVar(&force, "force", false, "覆盖已存在的输出文件")
+	addDecryptFlags(decryptCmd)
+
+	rootCmd.AddCommand(keygenCmd)
+	rootCmd.AddCommand(decryptCmd)
+}
+
+// addEncryptFlags 为生成归档的命令添加加密和签名参数
+func addEncryptFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&encryptOutput, "encrypt", false, "使用口令加密输出（口令来自 --passphrase-file、环境变量 "+passphraseEnv+" 或交互输入）")
+	cmd.Flags().StringArrayVar(&recipients, "recipient", nil, "使用接收方的 X25519 公钥加密输出，可重复指定")
+	cmd.Flags().StringVar(&signKeyFile, "sign", "", "使用 Ed25519 私钥签名离线传输清单（隐含 --manifest）")
+	cmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "从文件读取口令")
+}
+
+// addDecryptFlags 为读取归档的命令添加解密和验签参数
+func addDecryptFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&identities, "identity", nil, "用于解密的 X25519 私钥，可重复指定")
+	cmd.Flags().StringArrayVar(&trustedKeys, "pubkey", nil, "受信任的 Ed25519 签名公钥，指定后必须通过清单签名校验，可重复指定")
+	cmd.Flags().StringVar(&passphraseFile, "passphrase-file", "", "从文件读取解密口令")
+	cmd.Flags().BoolVar(&skipSignature, "insecure-skip-signature", false, "清单已签名但没有受信任的公钥时仍然继续，不校验签名（不推荐）")
+}
+
+// readPassphrase 依次从 --passphrase-file、环境变量和终端读取口令，confirm 为 true 时需要输入两次
+func readPassphrase(confirm bool) ([]byte, error) {
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取口令文件失败: %v", err)
+		}
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+	if value := os.Getenv(passphraseEnv); value != "" {
+		return []byte(value), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("需要口令：请使用 --passphrase-file 或环境变量 %s", passphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, "请输入口令: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("口令不能为空")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "请再次输入口令: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, fmt.Errorf("两次输入的口令不一致")
+		}
+	}
+	return passphrase, nil
+}
+
+// outputSecurity 生成归档后的加密和签名设置
+type outputSecurity struct {
+	encryption *puller.EncryptOptions
+	signKey    ed25519.PrivateKey
+}
+
+// loadOutputSecurity 根据命令行参数加载加密口令、接收方公钥和签名私钥，出错时退出
+func loadOutputSecurity() *outputSecurity {
+	security := &outputSecurity{}
+
+	if encryptOutput || len(recipients) > 0 {
+		options := &puller.EncryptOptions{}
+		for _, path := range recipients {
+			recipient, err := puller.LoadRecipient(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+				os.Exit(1)
+			}
+			options.Recipients = append(options.Recipients, recipient)
+		}
+		if encryptOutput {
+			passphrase, err := readPassphrase(true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+				os.Exit(1)
+			}
+			options.Passphrase = passphrase
+		}
+		security.encryption = options
+	}
+
+	if signKeyFile != "" {
+		key, err := puller.LoadSigningKey(signKeyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+			os.Exit(1)
+		}
+		security.signKey = key
+	}
+
+	if security.encryption != nil && output == "-" {
+		fmt.Fprintf(os.Stderr, "错误：加密输出不能与 -o - 同时使用\n")
+		os.Exit(1)
+	}
+	return security
+}
+
+// streamSplitSize 生成归档时直接切分的分卷大小；加密时需要先加密再切分，由 finalizeArchives 处理
+func (s *outputSecurity) streamSplitSize(partSize int64) int64 {
+	if s.encryption != nil {
+		return 0
+	}
+	return partSize
+}
+
+// finalizeArchives 对生成的归档依次写入清单、加密、切分分卷并签名清单，返回最终的文件路径，出错时退出
+func finalizeArchives(files []string, security *outputSecurity, partSize int64) []string {
+	if len(files) == 0 {
+		return files
+	}
+	if writeManifest || security.signKey != nil {
+		writeBundleManifest(files)
+	}
+
+	final := make([]string, 0, len(files))
+	for _, file := range files {
+		if security.encryption != nil {
+			fmt.Printf("正在加密 %s...\n", file)
+			encrypted, err := puller.EncryptFile(file, security.encryption)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+				os.Exit(1)
+			}
+			if err := puller.RecordArchiveReplacement(file, encrypted); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️ 更新离线传输清单失败: %v\n", err)
+			}
+			file = encrypted
+		}
+
+		if partSize > 0 && !puller.IsSplitIndex(file) {
+			indexPath, err := puller.SplitFile(file, partSize)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+				os.Exit(1)
+			}
+			if err := puller.RecordArchiveReplacement(file, indexPath); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️ 更新离线传输清单失败: %v\n", err)
+			}
+			file = indexPath
+		}
+		final = append(final, file)
+	}
+
+	if security.signKey != nil {
+		signed := make(map[string]bool)
+		for _, file := range final {
+			dir := filepath.Dir(file)
+			if signed[dir] {
+				continue
+			}
+			signed[dir] = true
+			if err := puller.SignBundleManifest(dir, security.signKey); err != nil {
+				fmt.Fprintf(os.Stderr, "错误：签名清单失败: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ 已签名离线传输清单: %s\n", filepath.Join(dir, puller.BundleSignatureName))
+		}
+	}
+	return final
+}
+
+// loadKeyring 加载解密使用的私钥和口令，需要口令而未提供时交互输入
+func loadKeyring() *puller.Keyring {
+	keys := &puller.Keyring{
+		PromptPassphrase: func() ([]byte, error) { return readPassphrase(false) },
+	}
+	for _, path := range identities {
+		identity, err := puller.LoadIdentity(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+			os.Exit(1)
+		}
+		keys.Identities = append(keys.Identities, identity)
+	}
+	return keys
+}
+
+// loadTrustedKeys 加载受信任的签名公钥：命令行参数 > 配置文件中的 trusted_keys
+func loadTrustedKeys() []ed25519.PublicKey {
+	paths := trustedKeys
+	if len(paths) == 0 {
+		paths = config.NewConfigManager(configFile).GetConfig().Settings.TrustedKeys
+	}
+	var keys []ed25519.PublicKey
+	for _, path := range paths {
+		key, err := puller.LoadVerifyKey(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+			os.Exit(1)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// checkArchiveTrust 校验归档所在目录的清单签名，并确认归档的大小和 sha256 与已签名的清单一致
+func checkArchiveTrust(archive string, trusted []ed25519.PublicKey) error {
+	signer, err := puller.VerifyBundleSignature(filepath.Dir(archive), trusted)
+	if err != nil {
+		return err
+	}
+
+	results, err := puller.VerifyPath(archive, nil)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if !result.InManifest {
+			return fmt.Errorf("%s 不在已签名的清单中", result.Name)
+		}
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("%s 与已签名的清单不一致: %v", result.Name, result.Errors)
+		}
+	}
+	fmt.Printf("✅ 签名校验通过（签名者 %s）\n", signer)
+	return nil
+}
+
+// checkUnsignedUse 目录中的清单已签名但没有受信任的公钥时拒绝继续，除非指定了 --insecure-skip-signature
+func checkUnsignedUse(dir string) error {
+	if !puller.HasBundleSignature(dir) {
+		return nil
+	}
+	if !skipSignature {
+		return fmt.Errorf("%s 中的清单已签名，但未指定受信任的公钥（--pubkey 或配置 trusted_keys），如确需跳过签名校验请使用 --insecure-skip-signature", dir)
+	}
+	fmt.Fprintf(os.Stderr, "⚠️ %s 中的清单已签名，已按 --insecure-skip-signature 跳过签名校验\n", dir)
+	return nil
+}
+
+// runKeygen 执行生成密钥对命令
+func runKeygen(cmd *cobra.Command, args []string) {
+	fingerprint, err := puller.GenerateKeyPair(keyType, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成密钥失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已生成 %s 密钥对: %s.key（私钥，请妥善保管）、%s.pub（公钥）\n", keyType, args[0], args[0])
+	fmt.Printf("公钥指纹: %s\n", fingerprint)
+}
+
+// runDecrypt 执行解密命令
+func runDecrypt(cmd *cobra.Command, args []string) {
+	archive := args[0]
+	if indexPath, ok := puller.ResolveSplitIndex(archive); ok {
+		archive = indexPath
+	}
+
+	if trusted := loadTrustedKeys(); len(trusted) > 0 {
+		if err := checkArchiveTrust(archive, trusted); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := checkUnsignedUse(filepath.Dir(archive)); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	outputFile := output
+	if outputFile == "" {
+		outputFile = puller.DecryptedName(archive)
+	}
+
+	size, err := puller.DecryptFile(archive, outputFile, loadKeyring(), force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "解密失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 解密完成: %s (%s)\n", outputFile, formatSize(size))
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+var inspectJSON bool
+
+// inspectCmd 远程镜像查看命令
+var inspectCmd = &cobra.Command{
+	Use:   "inspect <镜像>",
+	Short: "查看远程镜像的大小、层、平台和配置，不下载层",
+	Long: `通过配置的镜像源搜索镜像，只获取清单（或多架构清单）和配置 blob，
+显示压缩后的总大小、层列表、可用平台、创建时间、Entrypoint 和环境变量等信息。`,
+	Example: `  DockerOps inspect nginx:1.25
+  DockerOps inspect pytorch/pytorch:2.3.0-cuda12.1-cudnn8-runtime --arch arm64
+  DockerOps inspect nginx:1.25 --json | jq .size`,
+	Args: cobra.ExactArgs(1),
+	Run:  runInspect,
+}
+
+func init() {
+	inspectCmd.Flags().StringVarP(&arch, "arch", "a", "", "要查看的架构，默认：amd64")
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "以 JSON 格式输出")
+	inspectCmd.Flags().StringVarP(&username, "username", "u", "", "仓库用户名")
+	inspectCmd.Flags().StringVarP(&password, "password", "p", "", "仓库密码")
+
+	rootCmd.AddCommand(inspectCmd)
+}
+
+// runInspect 执行远程镜像查看命令
+func runInspect(cmd *cobra.Command, args []string) {
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+
+	if arch == "" {
+		arch = configManager.GetConfig().Settings.DefaultArchitecture
+	}
+
+	result, err := imagePuller.Inspect(args[0], arch, username, password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查看镜像失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if inspectJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "序列化失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	printInspectResult(result)
+}
+
+// printInspectResult 打印远程镜像信息
+func printInspectResult(result *puller.InspectResult) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "镜像:\t%s:%s\n", result.Repository, result.Tag)
+	fmt.Fprintf(w, "仓库:\t%s (%s)\n", result.RegistryName, result.Registry)
+	fmt.Fprintf(w, "平台:\t%s\n", result.Platform)
+	if result.IndexDigest != "" {
+		fmt.Fprintf(w, "多架构清单:\t%s\n", result.IndexDigest)
+		fmt.Fprintf(w, "可用平台:\t%s\n", strings.Join(result.Platforms, ", "))
+	}
+	fmt.Fprintf(w, "清单digest:\t%s\n", valueOrDash(result.Digest))
+	fmt.Fprintf(w, "配置digest:\t%s\n", result.ConfigDigest)
+	fmt.Fprintf(w, "创建时间:\t%s\n", valueOrDash(result.Created))
+	if result.Author != "" {
+		fmt.Fprintf(w, "作者:\t%s\n", result.Author)
+	}
+	fmt.Fprintf(w, "大小:\t%s（压缩后，%d 个层）\n", formatSize(result.Size), len(result.Layers))
+	fmt.Fprintf(w, "Entrypoint:\t%s\n", valueOrDash(strings.Join(result.Entrypoint, " ")))
+	fmt.Fprintf(w, "Cmd:\t%s\n", valueOrDash(strings.Join(result.Cmd, " ")))
+	if result.WorkingDir != "" {
+		fmt.Fprintf(w, "工作目录:\t%s\n", result.WorkingDir)
+	}
+	if result.User != "" {
+		fmt.Fprintf(w, "用户:\t%s\n", result.User)
+	}
+	if len(result.ExposedPorts) > 0 {
+		fmt.Fprintf(w, "端口:\t%s\n", strings.Join(result.ExposedPorts, ", "))
+	}
+	w.Flush()
+
+	if len(result.Env) > 0 {
+		fmt.Println("\n环境变量:")
+		for _, env := range result.Env {
+			fmt.Printf("  %s\n", env)
+		}
+	}
+
+	if len(result.Labels) > 0 {
+		fmt.Println("\n标签:")
+		keys := make([]string, 0, len(result.Labels))
+		for key := range result.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %s=%s\n", key, result.Labels[key])
+		}
+	}
+
+	fmt.Println("\n层:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, layer := range result.Layers {
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", i+1, layer.Digest, formatSize(layer.Size))
+	}
+	w.Flush()
+}
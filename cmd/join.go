@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+// joinCmd 合并分卷命令
+var joinCmd = &cobra.Command{
+	Use:   "join <分卷索引|分卷>",
+	Short: "校验并合并分卷",
+	Long: `根据分卷索引（*` + puller.SplitIndexSuffix + `）校验每个分卷的大小和 sha256，并按顺序合并为原始归档。
+可以传入索引文件、任一分卷（例如 app.tar.001）或合并后的文件名，任一分卷缺失或损坏时不会生成输出文件。`,
+	Args: cobra.ExactArgs(1),
+	Run:  runJoin,
+}
+
+func init() {
+	joinCmd.Flags().StringVarP(&output, "output", "o", "", "合并后的文件路径（默认与索引同目录的原文件名）")
+	joinCmd.Flags().BoolVar(&force, "force", false, "覆盖已存在的输出文件")
+
+	rootCmd.AddCommand(joinCmd)
+}
+
+// runJoin 执行合并分卷命令
+func runJoin(cmd *cobra.Command, args []string) {
+	indexPath, ok := puller.ResolveSplitIndex(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未找到 %s 对应的分卷索引\n", args[0])
+		os.Exit(1)
+	}
+
+	index, err := puller.LoadSplitIndex(indexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载分卷索引失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("正在校验并合并 %d 个分卷（共 %s）...\n", len(index.Parts), formatSize(index.Size))
+	outputFile, err := puller.JoinSplit(indexPath, output, force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "合并分卷失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✅ 合并完成: %s\n", outputFile)
+	fmt.Printf("可使用以下命令导入镜像: docker load -i %s\n", outputFile)
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"dockerops/internal/backend"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+// loadConcurrency 同时导入的归档数量
+var loadConcurrency int
+
+// 导入结果状态
+const (
+	loadStatusLoaded  = "✅ 已导入"
+	loadStatusSkipped = "⏭️ 已存在"
+	loadStatusFailed  = "❌ 失败"
+)
+
+// loadResult 单个归档的导入结果
+type loadResult struct {
+	Path     string
+	Status   string
+	Images   []string
+	Size     int64
+	Duration time.Duration
+	Err      error
+}
+
+// runLoad 执行加载命令
+func runLoad(cmd *cobra.Command, args []string) {
+	targets, errs := collectLoadTargets(args)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "⚠️ %v\n", err)
+	}
+	if len(targets) == 0 {
+		fmt.Println("未找到可导入的镜像归档")
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	runtime, err := containerRuntime()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+
+	concurrency := loadConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(targets) {
+		concurrency = len(targets)
+	}
+	fmt.Printf("正在导入 %d 个镜像归档到 %s（并发数: %d）...\n", len(targets), runtime.Name(), concurrency)
+
+	trusted := loadTrustedKeys()
+	var keys *puller.Keyring
+	for _, target := range targets {
+		if puller.IsEncryptedFile(target) {
+			keys = loadKeyring()
+			break
+		}
+	}
+
+	// 加密归档可能需要交互输入口令，同一时间只解密一个
+	var decryptMu sync.Mutex
+	results := make([]loadResult, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if puller.IsEncryptedFile(target) {
+				decryptMu.Lock()
+				defer decryptMu.Unlock()
+			}
+			results[i] = loadTarget(runtime, target, trusted, keys, concurrency == 1)
+		}(i, target)
+	}
+	wg.Wait()
+
+	failed := printLoadSummary(results)
+	if len(errs) > 0 || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// collectLoadTargets 展开命令行参数中的文件、通配符和目录：目录会递归查找镜像归档和 OCI 镜像布局，
+// 未指定参数时使用当前目录下的镜像归档；分卷会统一为分卷索引并去重
+func collectLoadTargets(args []string) ([]string, []error) {
+	var targets []string
+	var errs []error
+	seen := make(map[string]bool)
+	add := func(target string) {
+		if index, ok := puller.ResolveSplitIndex(target); ok {
+			target = index
+		}
+		target = filepath.Clean(target)
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	if len(args) == 0 {
+		entries, err := os.ReadDir(".")
+		if err != nil {
+			return nil, []error{err}
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && puller.IsArchiveFile(entry.Name()) {
+				add(entry.Name())
+			}
+		}
+		return targets, nil
+	}
+
+	for _, arg := range args {
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil || len(matches) == 0 {
+				errs = append(errs, fmt.Errorf("没有匹配 %s 的文件", arg))
+				continue
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				// 分卷集允许直接指定合并后的文件名
+				if _, ok := puller.ResolveSplitIndex(path); ok {
+					add(path)
+					continue
+				}
+				errs = append(errs, err)
+				continue
+			}
+			if !info.IsDir() {
+				add(path)
+				continue
+			}
+			if err := walkLoadTargets(path, add); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return targets, errs
+}
+
+// walkLoadTargets 递归查找目录中的镜像归档和 OCI 镜像布局
+func walkLoadTargets(dir string, add func(string)) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if puller.IsOCILayout(path) {
+				add(path)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if puller.IsArchiveFile(entry.Name()) {
+			add(path)
+		}
+		return nil
+	})
+}
+
+// loadTarget 校验并导入单个归档或 OCI 镜像布局；本地已有相同 ID 的全部镜像时跳过（--force 时总是导入）
+func loadTarget(runtime backend.Runtime, target string, trusted []ed25519.PublicKey, keys *puller.Keyring, progress bool) loadResult {
+	start := time.Now()
+	result := loadResult{Path: target, Size: targetSize(target)}
+	fail := func(err error) loadResult {
+		result.Status = loadStatusFailed
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	if len(trusted) > 0 {
+		if err := checkArchiveTrust(target, trusted); err != nil {
+			return fail(err)
+		}
+	} else if err := checkUnsignedUse(filepath.Dir(target)); err != nil {
+		return fail(err)
+	}
+
+	if puller.IsSplitIndex(target) {
+		if _, err := puller.VerifySplit(target); err != nil {
+			return fail(err)
+		}
+	}
+
+	ociLayout := puller.IsOCILayout(target)
+	// 加密归档需要解密才能读取 manifest.json，不做预检查
+	if !force && !ociLayout && !puller.IsEncryptedFile(target) {
+		if entries, err := puller.ReadArchiveManifest(target); err == nil {
+			if images, ok := imagesPresent(runtime, entries); ok {
+				result.Status = loadStatusSkipped
+				result.Images = images
+				result.Duration = time.Since(start)
+				return result
+			}
+		}
+	}
+
+	var reader io.ReadCloser
+	var err error
+	if ociLayout {
+		reader, err = puller.OpenOCILayout(target)
+	} else {
+		reader, err = puller.OpenImageTar(target, keys)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	defer reader.Close()
+
+	var stream io.Reader = reader
+	if progress {
+		size := int64(-1)
+		if compression, _ := puller.DetectFileCompression(target); compression == puller.CompressionNone && !ociLayout && !puller.IsSplitIndex(target) && !puller.IsEncryptedFile(target) {
+			size = result.Size
+		}
+		stream = withProgress(reader, newByteProgress(size, "导入 "+filepath.Base(target)))
+	}
+
+	images, err := runtime.LoadImages(stream, nil)
+	if err != nil {
+		return fail(err)
+	}
+	result.Status = loadStatusLoaded
+	result.Images = images
+	result.Duration = time.Since(start)
+	return result
+}
+
+// imagesPresent 检查归档中的每个镜像标签是否都已在运行时中且镜像 ID 相同
+func imagesPresent(runtime backend.Runtime, entries []puller.ArchiveManifestEntry) ([]string, bool) {
+	var images []string
+	for _, entry := range entries {
+		if len(entry.RepoTags) == 0 {
+			return nil, false
+		}
+		for _, tag := range entry.RepoTags {
+			info, err := runtime.InspectImage(tag)
+			if err != nil || info.ID != entry.ImageID() {
+				return nil, false
+			}
+			images = append(images, tag)
+		}
+	}
+	return images, len(images) > 0
+}
+
+// targetSize 返回归档大小：分卷集为合并后的大小，目录为全部文件之和
+func targetSize(target string) int64 {
+	if puller.IsSplitIndex(target) {
+		if index, err := puller.LoadSplitIndex(target); err == nil {
+			return index.Size
+		}
+		return 0
+	}
+
+	var size int64
+	filepath.WalkDir(target, func(_ string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// printLoadSummary 打印导入结果表格，返回失败数量
+func printLoadSummary(results []loadResult) int {
+	loaded, skipped, failed := 0, 0, 0
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "归档\t状态\t大小\t耗时\t镜像")
+	for _, result := range results {
+		switch result.Status {
+		case loadStatusLoaded:
+			loaded++
+		case loadStatusSkipped:
+			skipped++
+		default:
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			result.Path,
+			result.Status,
+			formatSize(result.Size),
+			result.Duration.Round(time.Millisecond),
+			valueOrDash(strings.Join(result.Images, ", ")),
+		)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fmt.Println("\n失败原因:")
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Printf("  %s: %v\n", result.Path, result.Err)
+			}
+		}
+	}
+
+	fmt.Printf("\n加载操作完成！导入 %d 个，已存在 %d 个，失败 %d 个\n", loaded, skipped, failed)
+	return failed
+}
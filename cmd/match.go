@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dockerops/internal/backend"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+// 镜像匹配方式
+const (
+	matchModePrefix = "prefix"
+	matchModeGlob   = "glob"
+	matchModeRegex  = "regex"
+)
+
+var (
+	matchMode          string
+	matchExcludes      []string
+	matchCreatedBefore string
+	matchCreatedAfter  string
+	matchMinSize       string
+	matchMaxSize       string
+	matchDangling      bool
+	matchJSON          bool
+)
+
+func init() {
+	matchCmd.Flags().BoolVar(&matchJSON, "json", false, "以 JSON 格式输出")
+	for _, cmd := range []*cobra.Command{matchCmd, pushCmd, saveCmd} {
+		addImageFilterFlags(cmd)
+	}
+}
+
+// addImageFilterFlags 为命令添加本地镜像的匹配和过滤参数，match、push、save 共用
+func addImageFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&matchMode, "match-mode", matchModePrefix, "匹配方式：prefix（镜像名前缀）、glob（通配符，例如 'bitnami/*:7*'）、regex（正则表达式，需匹配完整名称）")
+	cmd.Flags().StringArrayVar(&matchExcludes, "exclude", nil, "排除匹配该模式的镜像（与 --match-mode 使用相同的匹配方式），可重复指定")
+	cmd.Flags().StringVar(&matchCreatedBefore, "created-before", "", "只包含在此之前创建的镜像，支持时长（例如 720h、30d）或日期（例如 2024-01-31）")
+	cmd.Flags().StringVar(&matchCreatedAfter, "created-after", "", "只包含在此之后创建的镜像，格式同 --created-before")
+	cmd.Flags().StringVar(&matchMinSize, "min-size", "", "只包含不小于该大小的镜像（例如 100M）")
+	cmd.Flags().StringVar(&matchMaxSize, "max-size", "", "只包含不大于该大小的镜像（例如 1G）")
+	cmd.Flags().BoolVar(&matchDangling, "dangling", false, "只包含没有标签的悬空镜像（默认排除悬空镜像）")
+}
+
+// imageFilter 本地镜像的匹配和过滤条件
+type imageFilter struct {
+	include       func(string) bool
+	excludes      []func(string) bool
+	createdBefore time.Time
+	createdAfter  time.Time
+	minSize       int64
+	maxSize       int64
+	dangling      bool
+}
+
+// newImageFilter 根据匹配模式和命令行参数创建过滤条件
+func newImageFilter(pattern string) (*imageFilter, error) {
+	filter := &imageFilter{dangling: matchDangling}
+	var err error
+	if filter.include, err = compileImageMatcher(matchMode, pattern); err != nil {
+		return nil, err
+	}
+	for _, exclude := range matchExcludes {
+		matcher, err := compileImageMatcher(matchMode, exclude)
+		if err != nil {
+			return nil, err
+		}
+		filter.excludes = append(filter.excludes, matcher)
+	}
+
+	if matchCreatedBefore != "" {
+		if filter.createdBefore, err = parseCreatedFilter(matchCreatedBefore); err != nil {
+			return nil, err
+		}
+	}
+	if matchCreatedAfter != "" {
+		if filter.createdAfter, err = parseCreatedFilter(matchCreatedAfter); err != nil {
+			return nil, err
+		}
+	}
+	if matchMinSize != "" {
+		if filter.minSize, err = puller.ParseSize(matchMinSize); err != nil {
+			return nil, err
+		}
+	}
+	if matchMaxSize != "" {
+		if filter.maxSize, err = puller.ParseSize(matchMaxSize); err != nil {
+			return nil, err
+		}
+	}
+	return filter, nil
+}
+
+// compileImageMatcher 按匹配方式编译模式，返回判断单个镜像名称是否匹配的函数；空模式匹配全部镜像
+func compileImageMatcher(mode, pattern string) (func(string) bool, error) {
+	var matcher func(string) bool
+	switch mode {
+	case matchModePrefix, "":
+		matcher = func(name string) bool { return strings.HasPrefix(name, pattern) }
+	case matchModeGlob:
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的通配符 %s: %v", pattern, err)
+		}
+		matcher = func(name string) bool {
+			matched, _ := path.Match(pattern, name)
+			return matched
+		}
+	case matchModeRegex:
+		// 正则表达式需匹配完整名称，否则 nginx 会匹配 my-nginx-exporter
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %s: %v", pattern, err)
+		}
+		matcher = re.MatchString
+	default:
+		return nil, fmt.Errorf("不支持的匹配方式 %s，可选: prefix、glob、regex", mode)
+	}
+	if pattern == "" {
+		return func(string) bool { return true }, nil
+	}
+	return matcher, nil
+}
+
+// parseCreatedFilter 解析创建时间过滤条件：时长表示距今多久（支持 d 表示天），也可以是日期或 RFC 3339 时间
+func parseCreatedFilter(value string) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的时间: %s（示例：720h、30d、2024-01-31）", value)
+}
+
+// imageNames 返回用于匹配的镜像名称：完整引用，以及去掉仓库地址（Docker Hub 还去掉 library/）后的名称，
+// 悬空镜像使用镜像 ID
+func imageNames(image backend.Image) []string {
+	if image.Dangling() {
+		return []string{image.ID, strings.TrimPrefix(image.ID, "sha256:")}
+	}
+
+	names := []string{image.Ref}
+	registry, _, _ := splitImageReference(image.Ref)
+	if short, ok := strings.CutPrefix(image.Ref, registry+"/"); ok {
+		if registry == "docker.io" {
+			short = strings.TrimPrefix(short, "library/")
+		}
+		names = append(names, short)
+	}
+	return names
+}
+
+// matchesName 判断镜像的任一名称是否匹配
+func matchesName(matcher func(string) bool, image backend.Image) bool {
+	for _, name := range imageNames(image) {
+		if matcher(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match 判断镜像是否满足全部条件；运行时未提供创建时间的镜像不满足创建时间条件
+func (f *imageFilter) Match(image backend.Image) bool {
+	if image.Dangling() != f.dangling || !matchesName(f.include, image) {
+		return false
+	}
+	for _, exclude := range f.excludes {
+		if matchesName(exclude, image) {
+			return false
+		}
+	}
+	if !f.createdBefore.IsZero() && (image.Created.IsZero() || !image.Created.Before(f.createdBefore)) {
+		return false
+	}
+	if !f.createdAfter.IsZero() && (image.Created.IsZero() || !image.Created.After(f.createdAfter)) {
+		return false
+	}
+	if f.minSize > 0 && image.Size < f.minSize {
+		return false
+	}
+	if f.maxSize > 0 && image.Size > f.maxSize {
+		return false
+	}
+	return true
+}
+
+// findMatchingImages 列出本地镜像中满足匹配模式和过滤条件的镜像
+func findMatchingImages(pattern string) ([]backend.Image, error) {
+	filter, err := newImageFilter(pattern)
+	if err != nil {
+		return nil, err
+	}
+	runtime, err := containerRuntime()
+	if err != nil {
+		return nil, err
+	}
+	images, err := runtime.ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []backend.Image
+	for _, image := range images {
+		if filter.Match(image) {
+			matched = append(matched, image)
+		}
+	}
+	return matched, nil
+}
+
+// imageReference 返回用于 save、push 的镜像引用，悬空镜像使用镜像 ID
+func imageReference(image backend.Image) string {
+	if image.Dangling() {
+		return image.ID
+	}
+	return image.Ref
+}
+
+// describeMatch 返回匹配条件的描述，用于提示信息
+func describeMatch(pattern string) string {
+	switch matchMode {
+	case matchModeGlob:
+		return fmt.Sprintf("通配符 '%s'", pattern)
+	case matchModeRegex:
+		return fmt.Sprintf("正则表达式 '%s'", pattern)
+	default:
+		return fmt.Sprintf("前缀 '%s'", pattern)
+	}
+}
+
+// sanitizePatternDir 将匹配模式转换为可用作目录名的字符串，空模式返回 all
+func sanitizePatternDir(pattern string) string {
+	name := strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, pattern), "_")
+	if name == "" {
+		return "all"
+	}
+	return name
+}
+
+// matchedImage match --json 输出的镜像信息
+type matchedImage struct {
+	Ref      string `json:"ref,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Created  string `json:"created,omitempty"`
+	Size     int64  `json:"size"`
+	Dangling bool   `json:"dangling"`
+}
+
+// runMatch 执行匹配命令
+func runMatch(cmd *cobra.Command, args []string) {
+	pattern := ""
+	if len(args) > 0 {
+		pattern = args[0]
+	}
+	if !matchJSON {
+		fmt.Printf("匹配%s 的镜像:\n", describeMatch(pattern))
+	}
+
+	images, err := findMatchingImages(pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取镜像列表失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if matchJSON {
+		result := make([]matchedImage, 0, len(images))
+		for _, image := range images {
+			item := matchedImage{Ref: image.Ref, ID: image.ID, Size: image.Size, Dangling: image.Dangling()}
+			if !image.Created.IsZero() {
+				item.Created = image.Created.Format(time.RFC3339)
+			}
+			result = append(result, item)
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "序列化失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(images) == 0 {
+		fmt.Printf("未找到匹配%s 的镜像\n", describeMatch(pattern))
+		return
+	}
+
+	for _, image := range images {
+		fmt.Println(imageReference(image))
+	}
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"dockerops/internal/backend"
+)
+
+// testImages 过滤测试使用的本地镜像列表
+func testImages() []backend.Image {
+	now := time.Now()
+	days := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	return []backend.Image{
+		{Ref: "docker.io/library/nginx:1.25", ID: "sha256:1111", Created: days(10), Size: 150 << 20},
+		{Ref: "nginx:1.24", ID: "sha256:2222", Created: days(40), Size: 140 << 20},
+		{Ref: "my-nginx-exporter:0.11", ID: "sha256:3333", Created: days(2), Size: 20 << 20},
+		{Ref: "nginx-exporter:1.0", ID: "sha256:4444", Created: days(5), Size: 25 << 20},
+		{Ref: "harbor.local/bitnami/redis:7.2", ID: "sha256:5555", Created: days(1), Size: 100 << 20},
+		{Ref: "harbor.local/bitnami/redis:6.2", ID: "sha256:6666", Created: days(400), Size: 90 << 20},
+		{Ref: "bitnami/postgresql:16", ID: "sha256:7777", Size: 300 << 20},
+		{ID: "sha256:abcdef", Created: days(60), Size: 50 << 20},
+	}
+}
+
+// imageFilterOptions 对应 match、push、save 共用的过滤参数
+type imageFilterOptions struct {
+	mode          string
+	excludes      []string
+	createdBefore string
+	createdAfter  string
+	minSize       string
+	maxSize       string
+	dangling      bool
+}
+
+// setImageFilterFlags 设置过滤参数，测试结束后恢复默认值
+func setImageFilterFlags(t *testing.T, options imageFilterOptions) {
+	t.Helper()
+	t.Cleanup(func() {
+		matchMode, matchExcludes = matchModePrefix, nil
+		matchCreatedBefore, matchCreatedAfter = "", ""
+		matchMinSize, matchMaxSize = "", ""
+		matchDangling = false
+	})
+	matchMode = options.mode
+	if matchMode == "" {
+		matchMode = matchModePrefix
+	}
+	matchExcludes = options.excludes
+	matchCreatedBefore, matchCreatedAfter = options.createdBefore, options.createdAfter
+	matchMinSize, matchMaxSize = options.minSize, options.maxSize
+	matchDangling = options.dangling
+}
+
+func TestImageFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		options imageFilterOptions
+		want    []string
+	}{
+		{
+			name:    "前缀",
+			pattern: "nginx",
+			want:    []string{"docker.io/library/nginx:1.25", "nginx:1.24", "nginx-exporter:1.0"},
+		},
+		{
+			name:    "前缀匹配完整引用",
+			pattern: "harbor.local/bitnami/",
+			want:    []string{"harbor.local/bitnami/redis:7.2", "harbor.local/bitnami/redis:6.2"},
+		},
+		{
+			name: "空前缀匹配全部非悬空镜像",
+			want: []string{"docker.io/library/nginx:1.25", "nginx:1.24", "my-nginx-exporter:0.11", "nginx-exporter:1.0",
+				"harbor.local/bitnami/redis:7.2", "harbor.local/bitnami/redis:6.2", "bitnami/postgresql:16"},
+		},
+		{
+			name:    "通配符匹配去掉仓库地址的名称",
+			pattern: "bitnami/*:7*",
+			options: imageFilterOptions{mode: matchModeGlob},
+			want:    []string{"harbor.local/bitnami/redis:7.2"},
+		},
+		{
+			name:    "通配符包含",
+			pattern: "*nginx*",
+			options: imageFilterOptions{mode: matchModeGlob},
+			want:    []string{"docker.io/library/nginx:1.25", "nginx:1.24", "my-nginx-exporter:0.11", "nginx-exporter:1.0"},
+		},
+		{
+			name:    "空通配符匹配全部",
+			options: imageFilterOptions{mode: matchModeGlob, maxSize: "20M"},
+			want:    []string{"my-nginx-exporter:0.11"},
+		},
+		{
+			name:    "正则表达式需匹配完整名称",
+			pattern: "nginx",
+			options: imageFilterOptions{mode: matchModeRegex},
+			want:    nil,
+		},
+		{
+			name:    "正则表达式",
+			pattern: "nginx:.*",
+			options: imageFilterOptions{mode: matchModeRegex},
+			want:    []string{"docker.io/library/nginx:1.25", "nginx:1.24"},
+		},
+		{
+			name:    "正则表达式包含分支",
+			pattern: `bitnami/redis:7\.2|nginx:1\.24`,
+			options: imageFilterOptions{mode: matchModeRegex},
+			want:    []string{"nginx:1.24", "harbor.local/bitnami/redis:7.2"},
+		},
+		{
+			name:    "正则表达式包含",
+			pattern: ".*nginx.*",
+			options: imageFilterOptions{mode: matchModeRegex},
+			want:    []string{"docker.io/library/nginx:1.25", "nginx:1.24", "my-nginx-exporter:0.11", "nginx-exporter:1.0"},
+		},
+		{
+			name:    "空正则表达式匹配全部",
+			options: imageFilterOptions{mode: matchModeRegex, minSize: "300M"},
+			want:    []string{"bitnami/postgresql:16"},
+		},
+		{
+			name:    "排除",
+			pattern: "*nginx*",
+			options: imageFilterOptions{mode: matchModeGlob, excludes: []string{"*exporter*", "nginx:1.25"}},
+			want:    []string{"nginx:1.24"},
+		},
+		{
+			name:    "前缀排除",
+			pattern: "nginx",
+			options: imageFilterOptions{excludes: []string{"nginx-"}},
+			want:    []string{"docker.io/library/nginx:1.25", "nginx:1.24"},
+		},
+		{
+			name:    "创建时间晚于",
+			options: imageFilterOptions{createdAfter: "7d"},
+			want:    []string{"my-nginx-exporter:0.11", "nginx-exporter:1.0", "harbor.local/bitnami/redis:7.2"},
+		},
+		{
+			name:    "创建时间早于",
+			options: imageFilterOptions{createdBefore: "30d"},
+			want:    []string{"nginx:1.24", "harbor.local/bitnami/redis:6.2"},
+		},
+		{
+			name:    "创建时间范围",
+			pattern: "nginx",
+			options: imageFilterOptions{createdBefore: "72h", createdAfter: "720h"},
+			want:    []string{"docker.io/library/nginx:1.25", "nginx-exporter:1.0"},
+		},
+		{
+			name:    "创建时间早于日期",
+			options: imageFilterOptions{createdBefore: "2000-01-01"},
+			want:    nil,
+		},
+		{
+			name:    "大小范围",
+			options: imageFilterOptions{minSize: "90M", maxSize: "140M"},
+			want:    []string{"nginx:1.24", "harbor.local/bitnami/redis:7.2", "harbor.local/bitnami/redis:6.2"},
+		},
+		{
+			name:    "悬空镜像",
+			options: imageFilterOptions{dangling: true},
+			want:    []string{"sha256:abcdef"},
+		},
+		{
+			name:    "悬空镜像按 ID 前缀匹配",
+			pattern: "abc",
+			options: imageFilterOptions{dangling: true},
+			want:    []string{"sha256:abcdef"},
+		},
+		{
+			name:    "悬空镜像按完整 ID 匹配",
+			pattern: "sha256:abcdef",
+			options: imageFilterOptions{mode: matchModeRegex, dangling: true},
+			want:    []string{"sha256:abcdef"},
+		},
+		{
+			name:    "悬空镜像与创建时间",
+			options: imageFilterOptions{dangling: true, createdAfter: "30d"},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setImageFilterFlags(t, tt.options)
+			filter, err := newImageFilter(tt.pattern)
+			if err != nil {
+				t.Fatalf("newImageFilter: %v", err)
+			}
+			var got []string
+			for _, image := range testImages() {
+				if filter.Match(image) {
+					got = append(got, imageReference(image))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("匹配结果 = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageFilterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		options imageFilterOptions
+		wantErr string
+	}{
+		{"无效的正则表达式", "(", imageFilterOptions{mode: matchModeRegex}, "无效的正则表达式"},
+		{"无效的通配符", "[", imageFilterOptions{mode: matchModeGlob}, "无效的通配符"},
+		{"无效的排除模式", "nginx", imageFilterOptions{mode: matchModeGlob, excludes: []string{"["}}, "无效的通配符"},
+		{"不支持的匹配方式", "nginx", imageFilterOptions{mode: "bogus"}, "不支持的匹配方式"},
+		{"空模式也检查匹配方式", "", imageFilterOptions{mode: "bogus"}, "不支持的匹配方式"},
+		{"无效的时间", "", imageFilterOptions{createdBefore: "abc"}, "无效的时间"},
+		{"无效的大小", "", imageFilterOptions{minSize: "abc"}, "无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setImageFilterFlags(t, tt.options)
+			if _, err := newImageFilter(tt.pattern); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
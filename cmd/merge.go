@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeBases      []string
+	mergeFromDocker bool
+	mergeLoad       bool
+)
+
+// mergeCmd 合并增量归档命令
+var mergeCmd = &cobra.Command{
+	Use:   "merge <增量归档>",
+	Short: "使用基线补全增量归档",
+	Long: `增量归档（使用 --baseline 生成）只包含基线中没有的层。merge 从目标环境已有的基线归档中找到缺少的层，
+生成可以直接 docker load 的完整归档，或使用 --load 直接导入本地容器运行时。
+基线层可以来自已传输的归档（--base，可重复指定文件或目录），也可以来自本地容器运行时中已导入的镜像（--from-docker）。`,
+	Args: cobra.ExactArgs(1),
+	Run:  runMerge,
+}
+
+func init() {
+	mergeCmd.Flags().StringArrayVar(&mergeBases, "base", nil, "基线归档文件或目录，可重复指定")
+	mergeCmd.Flags().BoolVar(&mergeFromDocker, "from-docker", false, "从本地容器运行时导出基线镜像补全缺少的层")
+	mergeCmd.Flags().BoolVar(&mergeLoad, "load", false, "合并后直接导入本地容器运行时")
+	mergeCmd.Flags().StringVarP(&output, "output", "o", "", "合并后的完整归档路径")
+	mergeCmd.Flags().StringVar(&compress, "compress", "", "输出压缩格式：none、gzip、zstd（默认与增量归档相同）")
+	mergeCmd.Flags().BoolVar(&force, "force", false, "覆盖已存在的输出文件")
+	addRuntimeFlag(mergeCmd)
+
+	rootCmd.AddCommand(mergeCmd)
+}
+
+// runMerge 执行合并增量归档命令
+func runMerge(cmd *cobra.Command, args []string) {
+	deltaPath := args[0]
+	if indexPath, ok := puller.ResolveSplitIndex(deltaPath); ok {
+		deltaPath = indexPath
+	}
+
+	if output == "" && !mergeLoad {
+		fmt.Fprintf(os.Stderr, "错误：请使用 -o 指定输出文件，或使用 --load 直接导入\n")
+		os.Exit(1)
+	}
+	if len(mergeBases) == 0 && !mergeFromDocker {
+		fmt.Fprintf(os.Stderr, "错误：请使用 --base 指定基线归档，或使用 --from-docker 从本地容器运行时获取基线层\n")
+		os.Exit(1)
+	}
+
+	compression := ""
+	if compress != "" {
+		var err error
+		if compression, err = puller.NormalizeCompression(compress); err != nil {
+			fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	delta, err := puller.ReadDeltaInfo(deltaPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("增量归档 %s 缺少 %d 个层，正在从基线补全...\n", deltaPath, len(delta.Layers))
+
+	if err := os.MkdirAll("tmp", 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "创建临时目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	var tmpFiles []string
+	defer func() {
+		for _, file := range tmpFiles {
+			os.Remove(file)
+		}
+	}()
+
+	bases := mergeBases
+	if mergeFromDocker {
+		dockerBase, err := saveBaselineImages(delta)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ 从本地容器运行时导出基线镜像失败: %v\n", err)
+		} else {
+			tmpFiles = append(tmpFiles, dockerBase)
+			bases = append(bases, dockerBase)
+		}
+	}
+
+	outputFile := output
+	if outputFile == "" {
+		outputFile = filepath.Join("tmp", fmt.Sprintf("merged-%d.tar", os.Getpid()))
+		tmpFiles = append(tmpFiles, outputFile)
+	}
+
+	result, err := puller.MergeDelta(deltaPath, bases, outputFile, compression, force || output == "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "合并失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已补全 %d 个层，完整归档校验通过 (%s)\n", result.Restored, formatSize(result.Size))
+
+	if mergeLoad {
+		fmt.Println("正在导入本地容器运行时...")
+		if err := loadImageFile(result.OutputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "导入失败: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✅ 导入完成")
+	}
+	if output != "" {
+		fmt.Printf("完整归档：%s\n", result.OutputFile)
+	}
+}
+
+// saveBaselineImages 将增量归档引用的基线镜像从本地容器运行时导出为临时归档
+func saveBaselineImages(delta *puller.DeltaInfo) (string, error) {
+	var images []string
+	seen := make(map[string]bool)
+	for _, layer := range delta.Layers {
+		for _, tag := range layer.RepoTags {
+			if !seen[tag] && inspectLocalImage(tag) != nil {
+				seen[tag] = true
+				images = append(images, tag)
+			}
+		}
+	}
+	if len(images) == 0 {
+		return "", fmt.Errorf("本地容器运行时中没有增量归档引用的基线镜像")
+	}
+
+	tarFile := filepath.Join("tmp", fmt.Sprintf("baseline-%d.tar", os.Getpid()))
+	fmt.Printf("正在从本地容器运行时导出 %d 个基线镜像...\n", len(images))
+	if err := saveImages(tarFile, images...); err != nil {
+		return "", err
+	}
+	return tarFile, nil
+}
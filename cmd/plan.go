@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+)
+
+// runPullPlans 为每个镜像生成并打印拉取计划，不下载层；
+// 任一镜像无法生成计划或磁盘空间不足时返回 false
+func runPullPlans(imagePuller *puller.MultiRegistryImagePuller, entries []puller.ImageListEntry, defaultArch string, options puller.PullOptions) bool {
+	ok := true
+	for _, entry := range entries {
+		plan, err := imagePuller.PlanPull(entry.Reference(), entry.Arch(defaultArch), username, password, options)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %s: %v\n", entry.Reference(), err)
+			ok = false
+			continue
+		}
+		if !printPullPlan(plan) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// formatRegistry 格式化仓库名称、地址和探测到的响应时间
+func formatRegistry(registry config.RegistryConfig) string {
+	s := fmt.Sprintf("%s (%s)", registry.Name, registry.URL)
+	if registry.ResponseTime != nil {
+		s += fmt.Sprintf(" %dms", registry.ResponseTime.Milliseconds())
+	}
+	return s
+}
+
+// printPullPlan 打印单个镜像的拉取计划，磁盘空间不足时返回 false
+func printPullPlan(plan *puller.PullPlan) bool {
+	fmt.Printf("\n📋 拉取计划：%s\n", plan.Image)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if plan.Resolved != plan.Image {
+		fmt.Fprintf(w, "解析为:\t%s\n", plan.Resolved)
+	}
+	fmt.Fprintf(w, "仓库:\t%s\n", formatRegistry(*plan.Registry))
+	if len(plan.Fallbacks) > 0 {
+		var fallbacks []string
+		for _, registry := range plan.Fallbacks {
+			fallbacks = append(fallbacks, formatRegistry(registry))
+		}
+		fmt.Fprintf(w, "备选顺序:\t%s\n", strings.Join(fallbacks, " → "))
+	}
+	if len(plan.Unavailable) > 0 {
+		var unavailable []string
+		for _, registry := range plan.Unavailable {
+			unavailable = append(unavailable, fmt.Sprintf("%s (%s)", registry.Name, registry.URL))
+		}
+		fmt.Fprintf(w, "不可用:\t%s\n", strings.Join(unavailable, ", "))
+	}
+	fmt.Fprintf(w, "镜像:\t%s:%s\n", plan.ImageInfo.Repository, plan.ImageInfo.Tag)
+	fmt.Fprintf(w, "架构:\t%s\n", plan.Arch)
+	fmt.Fprintf(w, "配置digest:\t%s\n", plan.Digest)
+	fmt.Fprintf(w, "输出文件:\t%s\n", plan.OutputFile)
+	w.Flush()
+
+	fmt.Println("\n层:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	cached := 0
+	for i, layer := range plan.Layers {
+		status := "需下载"
+		if layer.Cached {
+			status = "✅ 已缓存"
+			cached++
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\n", i+1, layer.Digest, formatSize(layer.Size), status)
+	}
+	w.Flush()
+	fmt.Printf("共 %d 个层，压缩后 %s，需下载 %s（%d 个层已缓存）\n",
+		len(plan.Layers), formatSize(plan.Size), formatSize(plan.Download), cached)
+
+	if plan.Skip {
+		fmt.Println("⏭️ 输出文件中已存在相同digest的镜像，将跳过下载")
+		return true
+	}
+
+	fmt.Println("\n磁盘空间（估算）:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, requirement := range plan.Disk {
+		fmt.Fprintf(w, "  %s\t%s\t需要 %s\t可用 %s\n",
+			requirement.Purpose, requirement.Path, formatSize(requirement.Need), formatSize(requirement.Free))
+	}
+	w.Flush()
+
+	if err := plan.CheckDiskSpace(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return false
+	}
+	fmt.Println("✅ 磁盘空间充足")
+	return true
+}
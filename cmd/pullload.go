@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"dockerops/internal/backend"
+	"dockerops/internal/puller"
+)
+
+var (
+	loadAfterPull bool
+	removeArchive bool
+)
+
+// checkPullLoadFlags 检查 --load 和 --rm 与其他参数的组合，出错时退出
+func checkPullLoadFlags(security *outputSecurity) {
+	if removeArchive && !loadAfterPull {
+		fmt.Fprintf(os.Stderr, "错误：--rm 需要与 --load 同时使用\n")
+		os.Exit(1)
+	}
+	if !loadAfterPull {
+		return
+	}
+	if output == "-" {
+		fmt.Fprintf(os.Stderr, "错误：--load 不能与 -o - 同时使用\n")
+		os.Exit(1)
+	}
+	if baseline != "" {
+		fmt.Fprintf(os.Stderr, "错误：增量归档缺少基线中的层，无法使用 --load 导入\n")
+		os.Exit(1)
+	}
+	if removeArchive && (writeManifest || security.encryption != nil || security.signKey != nil) {
+		fmt.Fprintf(os.Stderr, "错误：--rm 会删除归档，不能与 --manifest、--encrypt 或 --sign 同时使用\n")
+		os.Exit(1)
+	}
+	if _, err := containerRuntime(); err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadPulledImage 将拉取的归档流式导入本地容器运行时，校验每个 RepoTag 都指向拉取的镜像 ID，
+// 指定 --rm 时在校验通过后删除本次运行生成的归档，运行前已存在的归档保留
+func loadPulledImage(result *puller.PullResult) error {
+	runtime, err := containerRuntime()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("正在导入 %s 到 %s...\n", result.OutputFile, runtime.Name())
+	reader, err := puller.OpenImageTar(result.OutputFile, nil)
+	if err != nil {
+		return err
+	}
+	var size int64 = -1
+	if compression, _ := puller.DetectFileCompression(result.OutputFile); compression == puller.CompressionNone && !puller.IsSplitIndex(result.OutputFile) {
+		size = result.Size
+	}
+	err = loadImageStream(withProgress(reader, newByteProgress(size, "导入")))
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := verifyLoadedImage(runtime, result.RepoTags, result.Digest); err != nil {
+		return err
+	}
+	fmt.Printf("✅ 已导入 %s，镜像 ID %s\n", runtime.Name(), result.Digest)
+
+	if removeArchive && result.Skipped {
+		fmt.Printf("归档 %s 不是本次拉取生成的，未删除\n", result.OutputFile)
+	} else if removeArchive {
+		if err := puller.RemoveArchive(result.OutputFile); err != nil {
+			return fmt.Errorf("删除归档 %s 失败: %v", result.OutputFile, err)
+		}
+		fmt.Printf("已删除归档: %s\n", result.OutputFile)
+	}
+	return nil
+}
+
+// verifyLoadedImage 校验运行时中每个标签都存在且镜像 ID 与预期一致
+func verifyLoadedImage(runtime backend.Runtime, repoTags []string, id string) error {
+	for _, tag := range repoTags {
+		info, err := runtime.InspectImage(tag)
+		if errors.Is(err, backend.ErrImageNotFound) {
+			return fmt.Errorf("导入后在 %s 中未找到镜像 %s", runtime.Name(), tag)
+		}
+		if err != nil {
+			return fmt.Errorf("查询镜像 %s 失败: %v", tag, err)
+		}
+		if info.ID != id {
+			return fmt.Errorf("镜像 %s 的 ID 为 %s，与拉取的 %s 不一致", tag, info.ID, id)
+		}
+	}
+	return nil
+}
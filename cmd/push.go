@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+)
+
+var (
+	pushFrom      string
+	pushImage     string
+	pushChunkSize string
+	pushMountFrom []string
+	plainHTTP     bool
+)
+
+func init() {
+	pushCmd.Flags().StringVar(&pushFrom, "from", "", "直接从镜像归档推送（支持压缩、分卷索引和加密归档），不需要 docker 命令")
+	pushCmd.Flags().StringVar(&pushImage, "image", "", "归档包含多个镜像时要推送的镜像标签")
+	pushCmd.Flags().StringVarP(&username, "username", "u", "", "目标仓库用户名")
+	pushCmd.Flags().StringVarP(&password, "password", "p", "", "目标仓库密码")
+	pushCmd.Flags().StringVar(&pushChunkSize, "chunk-size", "", "分块上传的块大小（例如 16M），默认整体上传")
+	pushCmd.Flags().StringArrayVar(&pushMountFrom, "mount-from", nil, "目标仓库中可能已有相同层的镜像路径（例如 proj/base），尝试跨仓库挂载，可重复指定")
+	pushCmd.Flags().BoolVar(&plainHTTP, "plain-http", false, "使用 HTTP 访问目标仓库")
+	addDecryptFlags(pushCmd)
+}
+
+// runArchivePush 将归档中的镜像直接推送到目标仓库
+func runArchivePush(target string) {
+	archive := pushFrom
+	if indexPath, ok := puller.ResolveSplitIndex(archive); ok {
+		archive = indexPath
+	}
+	if trusted := loadTrustedKeys(); len(trusted) > 0 {
+		if err := checkArchiveTrust(archive, trusted); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+	} else if err := checkUnsignedUse(filepath.Dir(archive)); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	var keys *puller.Keyring
+	if puller.IsEncryptedFile(archive) {
+		keys = loadKeyring()
+	}
+
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+
+	fmt.Printf("正在从 %s 推送镜像到 %s...\n", archive, target)
+	result, err := imagePuller.PushArchive(archive, target, puller.PushOptions{
+		Username:  username,
+		Password:  password,
+		Image:     pushImage,
+		ChunkSize: parseChunkSize(),
+		MountFrom: pushMountFrom,
+		PlainHTTP: plainHTTP,
+		Keys:      keys,
+	})
+	imagePuller.CleanupTmpDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "推送失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n🎉 推送完成：%s\n", result.Target)
+	fmt.Printf("清单digest：%s\n", result.Digest)
+	fmt.Printf("共 %d 个层：上传 %d 个 blob (%s)，跨仓库挂载 %d 个，已存在 %d 个，使用缓存的原始层 %d 个\n",
+		result.Layers, result.Uploaded, formatSize(result.Size), result.Mounted, result.Existing, result.Cached)
+}
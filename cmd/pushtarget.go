@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// defaultTargetTemplate 默认的目标镜像模板：保留镜像名和标签，仓库地址和命名空间替换为目标
+const defaultTargetTemplate = "{target}/{name}:{tag}"
+
+var (
+	pushTargetRegistry string
+	pushTargetTemplate string
+	pushRemoveTags     bool
+)
+
+func init() {
+	pushCmd.Flags().StringVar(&pushTargetRegistry, "target-registry", "", "推送前把镜像的仓库地址和命名空间替换为目标（例如 harbor.local/proj）后重新打标签")
+	pushCmd.Flags().StringVar(&pushTargetTemplate, "target-template", "", "目标镜像模板，支持 {target} {registry} {repo} {namespace} {name} {tag}，默认 "+defaultTargetTemplate)
+	pushCmd.Flags().StringVar(&prefix, "prefix", "", "推送前为镜像添加前缀（例如 myregistry.com/），原仓库地址会被去掉")
+	pushCmd.Flags().BoolVar(&dryRun, "dry-run", false, "只列出源镜像和目标镜像的对应关系，不打标签也不推送")
+	pushCmd.Flags().BoolVar(&pushRemoveTags, "rm-tags", false, "推送完成后删除为推送创建的临时标签")
+}
+
+// pushMapping 本地镜像与推送目标的对应关系
+type pushMapping struct {
+	Source string
+	Target string
+}
+
+// pushRetagging 是否需要在推送前重新打标签
+func pushRetagging() bool {
+	return pushTargetRegistry != "" || pushTargetTemplate != "" || prefix != ""
+}
+
+// checkPushFlags 检查推送标志的组合是否有效
+func checkPushFlags() error {
+	if pushFrom != "" && (pushRetagging() || dryRun || pushRemoveTags) {
+		return fmt.Errorf("--from 不能与 --target-registry、--target-template、--prefix、--dry-run 或 --rm-tags 同时使用")
+	}
+	if matchDangling {
+		return fmt.Errorf("悬空镜像没有标签，不能推送，--dangling 只能用于 match 和 save")
+	}
+	if prefix != "" && (pushTargetRegistry != "" || pushTargetTemplate != "") {
+		return fmt.Errorf("--prefix 不能与 --target-registry 或 --target-template 同时使用")
+	}
+	if pushTargetTemplate != "" && strings.Contains(pushTargetTemplate, "{target}") && pushTargetRegistry == "" {
+		return fmt.Errorf("--target-template 使用了 {target}，需要同时指定 --target-registry")
+	}
+	if pushRemoveTags && !pushRetagging() {
+		return fmt.Errorf("--rm-tags 需要与 --target-registry、--target-template 或 --prefix 一起使用")
+	}
+	return nil
+}
+
+// resolvePushTargets 计算每个镜像的推送目标，不同镜像映射到同一目标时返回错误
+func resolvePushTargets(images []string) ([]pushMapping, error) {
+	mappings := make([]pushMapping, 0, len(images))
+	sources := make(map[string]string)
+	for _, image := range images {
+		target := renderPushTarget(image)
+		if _, repo, tag := splitImageReference(target); repo == "" || tag == "" || strings.Contains(target, "{") {
+			return nil, fmt.Errorf("镜像 %s 的目标 %s 无效，请检查 --target-template", image, target)
+		}
+		if source, ok := sources[target]; ok {
+			return nil, fmt.Errorf("镜像 %s 和 %s 的推送目标相同: %s", source, image, target)
+		}
+		sources[target] = image
+		mappings = append(mappings, pushMapping{Source: image, Target: target})
+	}
+	return mappings, nil
+}
+
+// renderPushTarget 根据 --prefix 或目标模板计算镜像的推送目标，例如
+// bitnami/redis:7 使用 --target-registry harbor.local/proj 时为 harbor.local/proj/redis:7
+func renderPushTarget(image string) string {
+	if !pushRetagging() {
+		return image
+	}
+
+	registry, repo, tag := splitImageReference(image)
+	if registry == "docker.io" {
+		repo = strings.TrimPrefix(repo, "library/")
+	}
+	if prefix != "" {
+		return prefix + repo + ":" + tag
+	}
+
+	namespace, name := "", repo
+	if i := strings.LastIndex(repo, "/"); i != -1 {
+		namespace, name = repo[:i], repo[i+1:]
+	}
+	template := pushTargetTemplate
+	if template == "" {
+		template = defaultTargetTemplate
+	}
+	target := strings.NewReplacer(
+		"{target}", strings.TrimSuffix(pushTargetRegistry, "/"),
+		"{registry}", registry,
+		"{repo}", repo,
+		"{namespace}", namespace,
+		"{name}", name,
+		"{tag}", tag,
+	).Replace(template)
+	// 命名空间为空时去掉多余的斜杠
+	for strings.Contains(target, "//") {
+		target = strings.ReplaceAll(target, "//", "/")
+	}
+	return strings.Trim(target, "/")
+}
+
+// printPushMappings 打印源镜像和推送目标的对应关系
+func printPushMappings(mappings []pushMapping) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "本地镜像\t推送目标")
+	for _, mapping := range mappings {
+		fmt.Fprintf(w, "%s\t%s\n", mapping.Source, mapping.Target)
+	}
+	w.Flush()
+}
+
+// pushMappedImage 按需重新打标签后推送镜像，--rm-tags 时推送完成后删除新建的临时标签
+func pushMappedImage(mapping pushMapping) error {
+	if mapping.Target == mapping.Source {
+		return pushLocalImage(mapping.Source)
+	}
+
+	runtime, err := containerRuntime()
+	if err != nil {
+		return err
+	}
+	// 目标标签原本就存在时不是临时标签，推送后保留
+	_, err = runtime.InspectImage(mapping.Target)
+	existed := err == nil
+	if err := runtime.TagImage(mapping.Source, mapping.Target); err != nil {
+		return fmt.Errorf("打标签 %s 失败: %v", mapping.Target, err)
+	}
+	pushErr := runtime.PushImage(mapping.Target, username, password, os.Stdout)
+	if pushRemoveTags && !existed {
+		if err := runtime.RemoveTag(mapping.Target); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ 删除临时标签 %s 失败: %v\n", mapping.Target, err)
+		}
+	}
+	return pushErr
+}
@@ -3,11 +3,10 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"dockerops/internal/config"
@@ -19,16 +18,34 @@ import (
 const VERSION = "v2.0.0"
 
 var (
-	configFile string
-	image      string
-	arch       string
-	username   string
-	password   string
-	quiet      bool
-	debug      bool
-	prefix     string
+	configFile    string
+	image         string
+	arch          string
+	username      string
+	password      string
+	quiet         bool
+	debug         bool
+	prefix        string
+	output        string
+	compress      string
+	outputDir     string
+	nameTmpl      string
+	force         bool
+	tagMode       string
+	extraTags     []string
+	imageList     string
+	concurrency   int
+	writeManifest bool
+	splitSize     string
+	baseline      string
+	runtimeName   string
+	dryRun        bool
+	skipDiskCheck bool
 )
 
+// messages 提示信息的输出目标；-o - 时标准输出用于写入tar流，提示信息改写到标准错误
+var messages io.Writer = os.Stdout
+
 // rootCmd 根命令
 var rootCmd = &cobra.Command{
 	Use:   "DockerOps",
@@ -51,52 +68,70 @@ var rootCmd = &cobra.Command{
 var pullCmd = &cobra.Command{
 	Use:   "pull [IMAGE]",
 	Short: "拉取Docker镜像",
-	Long:  "从配置的多个镜像仓库中搜索并拉取Docker镜像",
+	Long:  "从配置的多个镜像仓库中搜索并拉取Docker镜像。标签可以是版本约束（例如 nginx:~1.25），会拉取满足约束的最高版本",
 	Args:  cobra.MaximumNArgs(1),
 	Run:   runPull,
 }
 
 // pushCmd 推送命令
 var pushCmd = &cobra.Command{
-	Use:   "push [PREFIX]",
+	Use:   "push [PATTERN | --from 归档 目标镜像]",
 	Short: "推送镜像到仓库",
-	Long:  "将匹配指定前缀的镜像推送到Docker仓库",
-	Args:  cobra.ExactArgs(1),
-	Run:   runPush,
+	Long: `将匹配指定模式的镜像推送到Docker仓库，匹配和过滤参数与 match 相同。
+指定 --target-registry（例如 harbor.local/proj）时先把镜像的仓库地址和命名空间替换为目标并重新打标签再推送，
+可用 --target-template 自定义目标名称，--dry-run 只列出对应关系，--rm-tags 推送后删除临时标签。
+使用 --from 时直接按 OCI distribution 协议把归档中的镜像推送到目标镜像（例如 registry.local/proj/app:tag），
+不需要本地 Docker；配置了 blob_cache_dir 时优先上传拉取时缓存的原始层。`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPush,
 }
 
 // loadCmd 加载命令
 var loadCmd = &cobra.Command{
-	Use:   "load",
+	Use:   "load [FILE|DIR|GLOB...]",
 	Short: "从本地tar文件加载镜像",
-	Long:  "从当前目录的所有.tar文件中加载Docker镜像",
-	Run:   runLoad,
+	Long: `从指定的文件、通配符或目录中加载镜像，支持 .tar、.tar.gz、.tar.zst、分卷索引（*` + puller.SplitIndexSuffix + `）、
+加密归档（*` + puller.EncryptedSuffix + `）和 OCI 镜像布局目录；目录会递归查找其中的归档。
+未指定参数时加载当前目录的所有镜像归档。分卷会先校验再直接导入，无需手动合并。
+导入前读取归档的 manifest.json，本地已有相同镜像 ID 的全部标签时跳过（--force 总是导入），多个归档按 -j 并发导入。
+加密归档在导入时流式解密，不会在磁盘上留下明文；指定 --pubkey 时先校验清单签名，校验失败则拒绝导入。`,
+	Run: runLoad,
 }
 
 // saveCmd 保存命令
 var saveCmd = &cobra.Command{
-	Use:   "save [PREFIX]",
+	Use:   "save [PATTERN]",
 	Short: "保存镜像到本地tar文件",
-	Long:  "将匹配指定前缀的镜像保存为tar文件",
-	Args:  cobra.ExactArgs(1),
-	Run:   runSave,
+	Long: `将匹配指定模式的镜像保存为tar文件。
+默认按镜像名前缀匹配，可用 --match-mode 改为通配符或正则表达式，并可按 --exclude、创建时间、大小过滤。
+默认每个镜像一个归档，文件名包含仓库地址和命名空间（例如 harbor.local_proj_nginx-1.25.tar），按 -j 并发导出；
+--single-archive 把全部镜像写入同一个归档 ` + singleArchiveName + `，共享的基础层只写入一次。`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSave,
 }
 
 // saveComposeCmd 保存compose镜像命令
 var saveComposeCmd = &cobra.Command{
 	Use:   "save-compose",
-	Short: "保存docker-compose.yml中的镜像",
-	Long:  "从docker-compose.yml文件中提取镜像并保存为tar文件",
-	Run:   runSaveCompose,
+	Short: "保存 Compose 文件中的镜像",
+	Long: `按 Compose 规范解析 Compose 文件并将服务使用的镜像保存为tar文件。
+未指定 -f 时依次查找 compose.yaml、compose.yml、docker-compose.yaml、docker-compose.yml（以及对应的 override 文件），
+也可以用 COMPOSE_FILE 指定；多个 -f 按顺序合并。支持 .env 和 --env-file 中的变量插值（例如 ${TAG:-1.0}）、
+extends、YAML 锚点和 profiles（--profile 或 COMPOSE_PROFILES），只定义了 build 没有 image 的服务会单独列出。`,
+	Run: runSaveCompose,
 }
 
 // matchCmd 匹配命令
 var matchCmd = &cobra.Command{
-	Use:   "match [PREFIX]",
-	Short: "匹配指定前缀的镜像",
-	Long:  "列出所有匹配指定前缀的Docker镜像",
-	Args:  cobra.ExactArgs(1),
-	Run:   runMatch,
+	Use:   "match [PATTERN]",
+	Short: "匹配指定模式的镜像",
+	Long: `列出本地容器运行时中匹配指定模式的镜像，未指定模式时列出全部镜像。
+默认按镜像名前缀匹配（nginx 匹配 nginx:1.25 和 docker.io/library/nginx:1.25，不匹配 my-nginx-exporter），
+--match-mode glob 使用通配符，--match-mode regex 使用正则表达式（均需匹配完整名称）；--exclude 排除匹配的镜像，
+--created-before/--created-after、--min-size/--max-size 按创建时间和大小过滤，--dangling 只列出悬空镜像。
+这些条件同样适用于 push 和 save。`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runMatch,
 }
 
 // listCmd 列出仓库命令
@@ -150,6 +185,36 @@ func init() {
 	pullCmd.Flags().StringVarP(&username, "username", "u", "", "Docker 仓库用户名")
 	pullCmd.Flags().StringVarP(&password, "password", "p", "", "Docker 仓库密码")
 	pullCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "静默模式，减少交互")
+	pullCmd.Flags().StringVarP(&output, "output", "o", "", "输出文件路径，使用 - 写入标准输出（例如：pull nginx -o - | docker load）")
+	pullCmd.Flags().StringVar(&compress, "compress", "none", "输出压缩格式：none、gzip、zstd")
+	pullCmd.Flags().StringVar(&tagMode, "tag-mode", "", "打标签模式：default、original、mirror、custom，可用逗号组合")
+	pullCmd.Flags().StringArrayVarP(&extraTags, "tag", "t", nil, "导入后的镜像标签，可重复指定（例如：myharbor.local/proj/redis:7）")
+	pullCmd.Flags().StringVarP(&imageList, "file", "f", "", "镜像列表文件（.txt 每行一个镜像，或 .yaml/.json）")
+	pullCmd.Flags().IntVarP(&concurrency, "concurrency", "j", 3, "批量拉取时的并发数")
+	pullCmd.Flags().BoolVar(&dryRun, "dry-run", false, "只探测仓库并解析清单，显示将使用的仓库、层大小、缓存情况和磁盘空间需求，不下载")
+	pullCmd.Flags().BoolVar(&skipDiskCheck, "skip-disk-check", false, "跳过下载前的磁盘空间检查")
+	pullCmd.Flags().BoolVar(&loadAfterPull, "load", false, "拉取后直接导入本地容器运行时并校验镜像标签和 ID")
+	pullCmd.Flags().BoolVar(&removeArchive, "rm", false, "与 --load 同时使用，导入并校验成功后删除本次生成的归档（批量拉取时已存在的归档保留）")
+	addOutputFlags(pullCmd)
+	addOutputFlags(saveCmd)
+	addOutputFlags(saveComposeCmd)
+	addSplitFlag(pullCmd)
+	addSplitFlag(saveCmd)
+	addBaselineFlag(pullCmd)
+	addBaselineFlag(saveCmd)
+	addEncryptFlags(pullCmd)
+	addEncryptFlags(saveCmd)
+	addDecryptFlags(loadCmd)
+	addRuntimeFlag(pullCmd)
+	addRuntimeFlag(pushCmd)
+	addRuntimeFlag(loadCmd)
+	loadCmd.Flags().IntVarP(&loadConcurrency, "concurrency", "j", 2, "同时导入的归档数量")
+	loadCmd.Flags().BoolVar(&force, "force", false, "本地已有相同镜像时仍然导入")
+	addRuntimeFlag(saveCmd)
+	addRuntimeFlag(saveComposeCmd)
+	addRuntimeFlag(matchCmd)
+	pullCmd.Flags().BoolVar(&writeManifest, "manifest", false, "在输出目录写入离线传输清单 "+puller.BundleManifestName)
+	saveCmd.Flags().BoolVar(&writeManifest, "manifest", false, "在输出目录写入离线传输清单 "+puller.BundleManifestName)
 
 	// 添加搜索命令标志
 	searchCmd.Flags().StringVarP(&arch, "arch", "a", "", "架构过滤，例如：amd64")
@@ -168,9 +233,39 @@ func init() {
 	configCmd.AddCommand(configInitCmd)
 }
 
+// addOutputFlags 添加输出目录、文件名模板和覆盖标志
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&outputDir, "output-dir", "", "输出目录（默认使用配置中的 output_dir）")
+	cmd.Flags().StringVar(&nameTmpl, "name-template", "", "文件名模板，支持 {registry} {repo} {tag} {arch} {digest} {date}")
+	cmd.Flags().BoolVar(&force, "force", false, "覆盖已存在的输出文件")
+}
+
+// addSplitFlag 为命令添加分卷输出参数
+func addSplitFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&splitSize, "split-size", "", "按大小切分输出为编号分卷并生成校验索引（例如：2G；FAT32 请使用 4095M）")
+}
+
+// addBaselineFlag 为命令添加增量归档的基线参数
+func addBaselineFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&baseline, "baseline", "", "基线离线传输清单（"+puller.BundleManifestName+" 或其所在目录），只输出基线中没有的层")
+}
+
+// parseSplitSize 解析 --split-size 参数，未指定时返回0
+func parseSplitSize() int64 {
+	if splitSize == "" {
+		return 0
+	}
+	size, err := puller.ParseSize(splitSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+	return size
+}
+
 // showBanner 显示DockerOps的ASCII艺术图案
 func showBanner() {
-	fmt.Println(`
+	fmt.Fprintln(messages, `
     ____             __              ____            
    / __ \____  _____/ /_____  _____ / __ \____  _____
   / / / / __ \/ ___/ //_/ _ \/ ___// / / / __ \/ ___/
@@ -190,10 +285,21 @@ func Execute() {
 // runPull 执行拉取命令
 func runPull(cmd *cobra.Command, args []string) {
 	// 显示帮助信息
-	if len(args) == 0 && !quiet {
+	if len(args) == 0 && !quiet && imageList == "" {
 		showBanner()
 		fmt.Println("\n这是一个多功能的 Docker 镜像管理工具，支持以下功能：")
 		fmt.Println("  - pull: 拉取Docker镜像")
+		fmt.Println("  - bundle: 拉取多个镜像并打包为一个归档")
+		fmt.Println("  - verify: 校验镜像归档的完整性")
+		fmt.Println("  - join: 校验并合并分卷")
+		fmt.Println("  - merge: 使用基线补全增量归档")
+		fmt.Println("  - keygen: 生成加密或签名密钥对")
+		fmt.Println("  - decrypt: 校验签名后解密归档")
+		fmt.Println("  - copy: 将镜像从镜像源直接复制到目标仓库")
+		fmt.Println("  - sync: 按配置文件将上游镜像同步到私有仓库")
+		fmt.Println("  - tags: 列出镜像的标签，支持版本约束过滤")
+		fmt.Println("  - inspect: 查看远程镜像的大小、层和配置")
+		fmt.Println("  - catalog: 列出镜像仓库中的全部镜像路径")
 		fmt.Println("  - push: 推送镜像到仓库")
 		fmt.Println("  - load: 从本地tar文件加载镜像")
 		fmt.Println("  - save: 保存镜像到本地tar文件")
@@ -206,6 +312,7 @@ func runPull(cmd *cobra.Command, args []string) {
 		fmt.Println("\n示例:")
 		fmt.Println("  DockerOps pull nginx:latest")
 		fmt.Println("  DockerOps pull nginx:latest --arch arm64")
+		fmt.Println("  DockerOps pull -f images.txt")
 		fmt.Println("  DockerOps list")
 		fmt.Println("  DockerOps config show")
 		fmt.Println("  DockerOps config init")
@@ -218,13 +325,93 @@ func runPull(cmd *cobra.Command, args []string) {
 		log.SetFlags(log.LstdFlags | log.Lshortfile)
 	}
 
+	compression, err := puller.NormalizeCompression(compress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+
+	if err := puller.ValidateTagMode(tagMode); err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+
+	partSize := parseSplitSize()
+	if partSize > 0 && output == "-" {
+		fmt.Fprintf(os.Stderr, "错误：--split-size 不能与 -o - 同时使用\n")
+		os.Exit(1)
+	}
+	security := loadOutputSecurity()
+	if !dryRun {
+		checkPullLoadFlags(security)
+	}
+
+	// 输出到标准输出时，其余提示信息全部改写到标准错误，避免污染tar流
+	if output == "-" {
+		messages = os.Stderr
+	}
+
 	// 加载配置
 	configManager := config.NewConfigManager(configFile)
 	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+	pullOptions := puller.PullOptions{
+		Output:        output,
+		OutputDir:     outputDir,
+		NameTemplate:  nameTmpl,
+		Force:         force,
+		Compression:   compression,
+		TagMode:       tagMode,
+		Tags:          extraTags,
+		Stdout:        os.Stdout,
+		SplitSize:     security.streamSplitSize(partSize),
+		Baseline:      baseline,
+		SkipDiskCheck: skipDiskCheck,
+	}
+	imagePuller.SetPullOptions(pullOptions)
 
 	// 确保在程序结束时清理临时目录
 	defer imagePuller.CleanupTmpDir()
 
+	// 批量拉取
+	if imageList != "" {
+		if output != "" || len(extraTags) > 0 {
+			fmt.Fprintf(os.Stderr, "错误：--file 不能与 --output 或 --tag 同时使用，请在列表文件中为每个镜像指定 tags\n")
+			os.Exit(1)
+		}
+		if arch == "" {
+			arch = configManager.GetConfig().Settings.DefaultArchitecture
+		}
+
+		showBanner()
+		if dryRun {
+			entries, err := puller.LoadImageList(imageList)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+				os.Exit(1)
+			}
+			pullOptions.SkipExisting = true
+			if !runPullPlans(imagePuller, entries, arch, pullOptions) {
+				os.Exit(1)
+			}
+			return
+		}
+		if failed := runBatchPull(imagePuller, imageList, arch, puller.PullOptions{
+			OutputDir:     outputDir,
+			NameTemplate:  nameTmpl,
+			Force:         force,
+			Compression:   compression,
+			TagMode:       tagMode,
+			SplitSize:     security.streamSplitSize(partSize),
+			Baseline:      baseline,
+			SkipDiskCheck: skipDiskCheck,
+			SkipExisting:  true,
+		}, security, partSize); failed > 0 {
+			imagePuller.CleanupTmpDir()
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 获取镜像名称
 	if len(args) > 0 {
 		image = args[0]
@@ -232,7 +419,7 @@ func runPull(cmd *cobra.Command, args []string) {
 
 	if image == "" {
 		if !quiet {
-			fmt.Print("请输入 Docker 镜像名称（例如：nginx:latest）：")
+			fmt.Fprint(messages, "请输入 Docker 镜像名称（例如：nginx:latest）：")
 			reader := bufio.NewReader(os.Stdin)
 			input, _ := reader.ReadString('\n')
 			image = strings.TrimSpace(input)
@@ -246,13 +433,25 @@ func runPull(cmd *cobra.Command, args []string) {
 
 	// 显示个性化欢迎信息
 	showBanner()
-	fmt.Printf("正在为您拉取镜像: %s\n", image)
+
+	// 只生成拉取计划，不需要交互输入
+	if dryRun {
+		if arch == "" {
+			arch = configManager.GetConfig().Settings.DefaultArchitecture
+		}
+		if !runPullPlans(imagePuller, []puller.ImageListEntry{{Image: image}}, arch, pullOptions) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(messages, "正在为您拉取镜像: %s\n", image)
 
 	// 获取架构
 	if arch == "" {
 		arch = configManager.GetConfig().Settings.DefaultArchitecture
 		if !quiet {
-			fmt.Printf("请输入架构（arm64/amd64，默认: %s）：", arch)
+			fmt.Fprintf(messages, "请输入架构（arm64/amd64，默认: %s）：", arch)
 			reader := bufio.NewReader(os.Stdin)
 			input, _ := reader.ReadString('\n')
 			input = strings.TrimSpace(input)
@@ -271,224 +470,102 @@ func runPull(cmd *cobra.Command, args []string) {
 
 	// 获取认证信息
 	if username == "" && !quiet {
-		fmt.Print("请输入镜像仓库用户名（可选）：")
+		fmt.Fprint(messages, "请输入镜像仓库用户名（可选）：")
 		reader := bufio.NewReader(os.Stdin)
 		input, _ := reader.ReadString('\n')
 		username = strings.TrimSpace(input)
 	}
 
 	if password == "" && !quiet && username != "" {
-		fmt.Print("请输入镜像仓库密码：")
+		fmt.Fprint(messages, "请输入镜像仓库密码：")
 		reader := bufio.NewReader(os.Stdin)
 		input, _ := reader.ReadString('\n')
 		password = strings.TrimSpace(input)
 	}
 
 	// 拉取镜像
-	outputFile, err := imagePuller.PullImage(image, arch, username, password)
+	result, err := imagePuller.Pull(image, arch, username, password, pullOptions)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "拉取镜像失败: %v\n", err)
 		os.Exit(1)
 	}
+	outputFile := result.OutputFile
 
-	fmt.Printf("\n🎉 镜像拉取成功！输出文件：%s\n", outputFile)
-}
-
-// runPush 执行推送命令
-func runPush(cmd *cobra.Command, args []string) {
-	prefix := args[0]
-	fmt.Printf("正在推送匹配前缀 '%s' 的镜像到仓库...\n", prefix)
-
-	// 获取匹配的镜像
-	images, err := getMatchingImages(prefix)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "获取镜像列表失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	if len(images) == 0 {
-		fmt.Printf("未找到匹配前缀 '%s' 的镜像\n", prefix)
-		return
+	// 在加密和切分之前导入，导入的数据流不需要解密
+	if loadAfterPull {
+		if err := loadPulledImage(result); err != nil {
+			fmt.Fprintf(os.Stderr, "导入镜像失败: %v\n", err)
+			os.Exit(1)
+		}
+		if removeArchive {
+			fmt.Fprintf(messages, "\n🎉 镜像拉取并导入成功！\n")
+			return
+		}
 	}
 
-	// 推送每个镜像
-	for _, image := range images {
-		fmt.Printf("正在推送镜像: %s\n", image)
-		if err := runDockerCommand("push", image); err != nil {
-			fmt.Fprintf(os.Stderr, "推送镜像 %s 失败: %v\n", image, err)
-		} else {
-			fmt.Printf("✅ 成功推送镜像: %s\n", image)
-		}
+	if outputFile != "-" {
+		outputFile = finalizeArchives([]string{outputFile}, security, partSize)[0]
 	}
 
-	fmt.Println("推送操作完成！")
+	fmt.Fprintf(messages, "\n🎉 镜像拉取成功！输出文件：%s\n", outputFile)
 }
 
-// runLoad 执行加载命令
-func runLoad(cmd *cobra.Command, args []string) {
-	fmt.Println("正在从本地tar文件加载镜像...")
-
-	// 查找当前目录下的所有.tar文件
-	tarFiles, err := filepath.Glob("*.tar")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "查找tar文件失败: %v\n", err)
+// runPush 执行推送命令
+func runPush(cmd *cobra.Command, args []string) {
+	if err := checkPushFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
 		os.Exit(1)
 	}
-
-	if len(tarFiles) == 0 {
-		fmt.Println("当前目录下未找到.tar文件")
+	if pushFrom != "" {
+		runArchivePush(args[0])
 		return
 	}
 
-	// 加载每个tar文件
-	for _, tarFile := range tarFiles {
-		fmt.Printf("正在加载镜像: %s\n", tarFile)
-		if err := runDockerCommand("load", "-i", tarFile); err != nil {
-			fmt.Fprintf(os.Stderr, "加载镜像 %s 失败: %v\n", tarFile, err)
-		} else {
-			fmt.Printf("✅ 成功加载镜像: %s\n", tarFile)
-		}
-	}
-
-	fmt.Println("加载操作完成！")
-}
-
-// runSave 执行保存命令
-func runSave(cmd *cobra.Command, args []string) {
-	prefix := args[0]
-	fmt.Printf("正在保存匹配前缀 '%s' 的镜像到本地tar文件...\n", prefix)
+	pattern := args[0]
+	fmt.Printf("正在推送匹配%s 的镜像到仓库...\n", describeMatch(pattern))
 
 	// 获取匹配的镜像
-	images, err := getMatchingImages(prefix)
+	images, err := getMatchingImages(pattern)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "获取镜像列表失败: %v\n", err)
 		os.Exit(1)
 	}
 
 	if len(images) == 0 {
-		fmt.Printf("未找到匹配前缀 '%s' 的镜像\n", prefix)
+		fmt.Printf("未找到匹配%s 的镜像\n", describeMatch(pattern))
 		return
 	}
 
-	// 创建保存目录
-	dirName := fmt.Sprintf("images_%s", strings.ReplaceAll(prefix, "/", "_"))
-	if err := os.MkdirAll(dirName, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "创建目录失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// 保存镜像列表
-	listFile := filepath.Join(dirName, "list.txt")
-	file, err := os.Create(listFile)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "创建列表文件失败: %v\n", err)
-		os.Exit(1)
-	}
-	defer file.Close()
-
-	for _, image := range images {
-		file.WriteString(image + "\n")
-	}
-
-	// 保存每个镜像
-	for i, image := range images {
-		parts := strings.Split(image, ":")
-		imageName := parts[0]
-		tag := "latest"
-		if len(parts) > 1 {
-			tag = parts[1]
-		}
-
-		// 生成文件名
-		baseImageName := filepath.Base(imageName)
-		tarFileName := fmt.Sprintf("%s-%s.tar", baseImageName, tag)
-		tarFilePath := filepath.Join(dirName, tarFileName)
-
-		fmt.Printf("正在保存镜像 %d/%d: %s\n", i+1, len(images), image)
-		if err := runDockerCommand("save", "-o", tarFilePath, image); err != nil {
-			fmt.Fprintf(os.Stderr, "保存镜像 %s 失败: %v\n", image, err)
-		} else {
-			fmt.Printf("✅ 成功保存镜像: %s -> %s\n", image, tarFileName)
-		}
-	}
-
-	fmt.Printf("保存操作完成！文件保存在目录: %s\n", dirName)
-}
-
-// runSaveCompose 执行保存compose镜像命令
-func runSaveCompose(cmd *cobra.Command, args []string) {
-	fmt.Println("正在从docker-compose.yml文件中提取并保存镜像...")
-
-	// 检查docker-compose.yml文件是否存在
-	if _, err := os.Stat("docker-compose.yml"); os.IsNotExist(err) {
-		fmt.Fprintf(os.Stderr, "当前目录下未找到docker-compose.yml文件\n")
-		os.Exit(1)
-	}
-
-	// 创建images目录
-	if err := os.MkdirAll("images", 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "创建images目录失败: %v\n", err)
-		os.Exit(1)
-	}
-
-	// 读取docker-compose.yml文件
-	content, err := os.ReadFile("docker-compose.yml")
+	mappings, err := resolvePushTargets(images)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "读取docker-compose.yml文件失败: %v\n", err)
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
 		os.Exit(1)
 	}
-
-	// 提取镜像名称
-	images := extractImagesFromCompose(string(content))
-	if len(images) == 0 {
-		fmt.Println("docker-compose.yml文件中未找到镜像定义")
+	if dryRun {
+		printPushMappings(mappings)
 		return
 	}
 
-	// 保存每个镜像
-	for _, image := range images {
-		parts := strings.Split(image, ":")
-		imageName := parts[0]
-		tag := "latest"
-		if len(parts) > 1 {
-			tag = parts[1]
+	// 推送每个镜像
+	failed := 0
+	for _, mapping := range mappings {
+		if mapping.Target != mapping.Source {
+			fmt.Printf("正在推送镜像: %s -> %s\n", mapping.Source, mapping.Target)
+		} else {
+			fmt.Printf("正在推送镜像: %s\n", mapping.Source)
 		}
-
-		// 生成文件名
-		fileName := fmt.Sprintf("%s_%s.tar", strings.ReplaceAll(imageName, "/", "_"), tag)
-		filePath := filepath.Join("images", fileName)
-
-		fmt.Printf("正在保存镜像: %s 到 %s\n", image, fileName)
-		if err := runDockerCommand("save", image, "-o", filePath); err != nil {
-			fmt.Fprintf(os.Stderr, "保存镜像 %s 失败: %v\n", image, err)
+		if err := pushMappedImage(mapping); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "推送镜像 %s 失败: %v\n", mapping.Target, err)
 		} else {
-			fmt.Printf("✅ 镜像已保存: %s\n", fileName)
+			fmt.Printf("✅ 成功推送镜像: %s\n", mapping.Target)
 		}
-		fmt.Println("------------------------")
 	}
 
-	fmt.Println("所有镜像已保存完毕。")
-}
-
-// runMatch 执行匹配命令
-func runMatch(cmd *cobra.Command, args []string) {
-	prefix := args[0]
-	fmt.Printf("匹配前缀 '%s' 的镜像:\n", prefix)
-
-	images, err := getMatchingImages(prefix)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "获取镜像列表失败: %v\n", err)
+	fmt.Println("推送操作完成！")
+	if failed > 0 {
 		os.Exit(1)
 	}
-
-	if len(images) == 0 {
-		fmt.Printf("未找到匹配前缀 '%s' 的镜像\n", prefix)
-		return
-	}
-
-	for _, image := range images {
-		fmt.Println(image)
-	}
 }
 
 // runList 执行列出仓库命令
@@ -528,6 +605,18 @@ func runConfigShow(cmd *cobra.Command, args []string) {
 	fmt.Printf("移除仓库前缀: %t\n", config.Settings.RemoveRegistryPrefix)
 	fmt.Printf("启用进度条: %t\n", config.Settings.EnableProgressBar)
 	fmt.Printf("清理临时文件: %t\n", config.Settings.CleanupTempFiles)
+	if config.Settings.OutputDir != "" {
+		fmt.Printf("输出目录: %s\n", config.Settings.OutputDir)
+	}
+	if config.Settings.FilenameTemplate != "" {
+		fmt.Printf("文件名模板: %s\n", config.Settings.FilenameTemplate)
+	}
+	if config.Settings.TagMode != "" {
+		fmt.Printf("打标签模式: %s\n", config.Settings.TagMode)
+	}
+	if config.Settings.Runtime != "" {
+		fmt.Printf("容器运行时: %s\n", config.Settings.Runtime)
+	}
 
 	fmt.Println("\n标签转换规则:")
 	for i, rule := range config.TagTransform.Rules {
@@ -575,52 +664,81 @@ func runConfigInit(cmd *cobra.Command, args []string) {
 
 // 辅助函数
 
-// getMatchingImages 获取匹配指定前缀的镜像列表
-func getMatchingImages(prefix string) ([]string, error) {
-	cmd := exec.Command("docker", "images", "--format", "{{.Repository}}:{{.Tag}}")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
+// writeBundleManifest 将归档写入所在目录的离线传输清单
+func writeBundleManifest(files []string) {
+	fmt.Printf("正在生成离线传输清单（计算 sha256）...\n")
+	if err := puller.UpdateBundleManifest(files); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️ 生成离线传输清单失败: %v\n", err)
+		return
 	}
+	fmt.Printf("✅ 已更新离线传输清单: %s\n", filepath.Join(filepath.Dir(files[0]), puller.BundleManifestName))
+}
 
-	var matchingImages []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && strings.Contains(line, prefix) {
-			matchingImages = append(matchingImages, line)
+// splitImageReference 将镜像引用拆分为仓库地址、仓库路径和标签
+// 未指定仓库地址时返回 docker.io，未指定标签时返回 latest
+func splitImageReference(image string) (string, string, string) {
+	registry := "docker.io"
+	name := image
+	if i := strings.Index(name, "/"); i != -1 {
+		first := name[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			registry = first
+			name = name[i+1:]
 		}
 	}
 
-	return matchingImages, nil
-}
+	// 去掉digest部分
+	if i := strings.Index(name, "@"); i != -1 {
+		name = name[:i]
+	}
 
-// runDockerCommand 执行Docker命令
-func runDockerCommand(args ...string) error {
-	cmd := exec.Command("docker", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
+	tag := "latest"
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		tag = name[i+1:]
+		name = name[:i]
+	}
 
-// extractImagesFromCompose 从docker-compose.yml内容中提取镜像名称
-func extractImagesFromCompose(content string) []string {
-	var images []string
+	return registry, name, tag
+}
 
-	// 使用正则表达式匹配image行
-	re := regexp.MustCompile(`(?m)^\s*image:\s*["']?([^"'\s]+)["']?`)
-	matches := re.FindAllStringSubmatch(content, -1)
+// resolveOutputDir 获取输出目录：命令行参数 > 配置文件 > 默认目录
+func resolveOutputDir(configManager *config.ConfigManager, defaultDir string) string {
+	if outputDir != "" {
+		return outputDir
+	}
+	if dir := configManager.GetConfig().Settings.OutputDir; dir != "" {
+		return dir
+	}
+	return defaultDir
+}
 
-	for _, match := range matches {
-		if len(match) > 1 {
-			image := strings.TrimSpace(match[1])
-			if image != "" {
-				images = append(images, image)
+// resolveSavePath 根据文件名模板生成本地镜像的保存路径
+// 未配置模板时使用 defaultName，文件已存在且未指定 --force 时返回错误
+func resolveSavePath(configManager *config.ConfigManager, dirName, image, defaultName string) (string, error) {
+	template := nameTmpl
+	if template == "" {
+		template = configManager.GetConfig().Settings.FilenameTemplate
+	}
+
+	name := defaultName
+	if template != "" {
+		registry, repo, tag := splitImageReference(image)
+		vars := puller.OutputNameVars{
+			Registry: registry,
+			Repo:     repo,
+			Tag:      tag,
+		}
+		// 只有模板用到时才查询镜像ID和架构
+		if strings.Contains(template, "{digest}") || strings.Contains(template, "{arch}") {
+			if info := inspectLocalImage(image); info != nil {
+				vars.Digest = info.ID
+				vars.Arch = info.Architecture
 			}
 		}
+		name = puller.RenderOutputName(template, vars, ".tar")
 	}
 
-	return images
+	return puller.ResolveOutputPath(dirName, name, force)
 }
 
 // runSearch 执行搜索命令
@@ -677,28 +795,3 @@ func runSearch(cmd *cobra.Command, args []string) {
 	fmt.Println("\n" + strings.Repeat("=", 80))
 	fmt.Printf("提示: 使用 'dockerops pull %s' 来拉取镜像\n", image)
 }
-
-// searchDockerImage 执行Docker搜索命令（保留作为备用）
-func searchDockerImage(image, arch string) ([]string, error) {
-	cmd := exec.Command("docker", "search", "--format", "{{.Name}}")
-	if arch != "" {
-		cmd.Args = append(cmd.Args, "--filter", "is-official=true", "--filter", "arch="+arch)
-	}
-	cmd.Args = append(cmd.Args, image)
-
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, err
-	}
-
-	var results []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			results = append(results, line)
-		}
-	}
-
-	return results, nil
-}
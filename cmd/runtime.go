@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"dockerops/internal/backend"
+	"dockerops/internal/config"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// localRuntime 延迟创建的本地容器运行时
+var localRuntime backend.Runtime
+
+// addRuntimeFlag 为命令添加容器运行时参数
+func addRuntimeFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&runtimeName, "runtime", "", "容器运行时：docker、podman、containerd（nerdctl/ctr，命名空间 k8s.io），默认自动检测")
+}
+
+// containerRuntime 返回本地容器运行时：命令行参数 > 配置文件 > 自动检测，首次调用时创建
+func containerRuntime() (backend.Runtime, error) {
+	if localRuntime != nil {
+		return localRuntime, nil
+	}
+	name := runtimeName
+	if name == "" {
+		name = config.NewConfigManager(configFile).GetConfig().Settings.Runtime
+	}
+	runtime, err := backend.New(name)
+	if err != nil {
+		return nil, err
+	}
+	if debug {
+		fmt.Printf("使用容器运行时: %s\n", runtime.Name())
+	}
+	localRuntime = runtime
+	return runtime, nil
+}
+
+// newByteProgress 在终端中创建字节进度条，非终端时返回 nil
+func newByteProgress(size int64, desc string) *progressbar.ProgressBar {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+	return progressbar.DefaultBytes(size, desc)
+}
+
+// withProgress 包装读取器，同时更新进度条
+func withProgress(r io.Reader, bar *progressbar.ProgressBar) io.Reader {
+	if bar == nil {
+		return r
+	}
+	return io.TeeReader(r, bar)
+}
+
+// getMatchingImages 获取本地镜像中满足匹配模式和过滤条件的镜像引用，悬空镜像返回镜像 ID
+func getMatchingImages(pattern string) ([]string, error) {
+	images, err := findMatchingImages(pattern)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, len(images))
+	for _, image := range images {
+		refs = append(refs, imageReference(image))
+	}
+	return refs, nil
+}
+
+// inspectLocalImage 查询本地镜像详情，镜像不存在或运行时不可用时返回 nil
+func inspectLocalImage(image string) *backend.ImageInfo {
+	runtime, err := containerRuntime()
+	if err != nil {
+		return nil
+	}
+	info, err := runtime.InspectImage(image)
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// saveImages 将本地镜像导出到 tar 文件，失败时删除不完整的文件
+func saveImages(path string, images ...string) error {
+	return writeImageArchive(path, images, true)
+}
+
+// writeImageArchive 将本地镜像导出到 tar 文件，progress 为 true 时在终端显示进度条；
+// 多个镜像写入同一归档时共享的层只写入一次
+func writeImageArchive(path string, images []string, progress bool) error {
+	runtime, err := containerRuntime()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("创建文件失败: %v", err)
+	}
+	var w io.Writer = file
+	if progress {
+		if bar := newByteProgress(-1, "导出 "+filepath.Base(path)); bar != nil {
+			w = io.MultiWriter(file, bar)
+		}
+	}
+	err = runtime.SaveImages(w, images)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("写入 %s 失败: %v", path, err)
+	}
+	return nil
+}
+
+// loadImageFile 将 tar 文件导入本地容器运行时
+func loadImageFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var size int64 = -1
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	bar := newByteProgress(size, "导入 "+filepath.Base(path))
+	return loadImageStream(withProgress(file, bar))
+}
+
+// loadImageStream 将 tar 数据流导入本地容器运行时并输出导入的镜像
+func loadImageStream(r io.Reader) error {
+	runtime, err := containerRuntime()
+	if err != nil {
+		return err
+	}
+	loaded, err := runtime.LoadImages(r, nil)
+	for _, image := range loaded {
+		fmt.Printf("已导入: %s\n", image)
+	}
+	return err
+}
+
+// pushLocalImage 推送本地镜像，未指定 -u/-p 时使用运行时保存的登录凭据
+func pushLocalImage(image string) error {
+	runtime, err := containerRuntime()
+	if err != nil {
+		return err
+	}
+	return runtime.PushImage(image, username, password, os.Stdout)
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+// singleArchiveName --single-archive 时输出的归档文件名
+const singleArchiveName = "images.tar"
+
+var (
+	saveSingleArchive bool // 是否把全部匹配的镜像保存到同一个归档
+	saveConcurrency   int  // 逐个保存时同时导出的镜像数量
+)
+
+func init() {
+	saveCmd.Flags().BoolVar(&saveSingleArchive, "single-archive", false, "把全部匹配的镜像保存到同一个归档（"+singleArchiveName+"），共享的层只写入一次")
+	saveCmd.Flags().IntVarP(&saveConcurrency, "concurrency", "j", 2, "逐个保存时同时导出的镜像数量")
+}
+
+// saveTask 单个镜像归档的导出任务
+type saveTask struct {
+	Images []string
+	Path   string
+}
+
+// runSave 执行保存命令
+func runSave(cmd *cobra.Command, args []string) {
+	pattern := args[0]
+	fmt.Printf("正在保存匹配%s 的镜像到本地tar文件...\n", describeMatch(pattern))
+
+	// 获取匹配的镜像
+	images, err := getMatchingImages(pattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取镜像列表失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(images) == 0 {
+		fmt.Printf("未找到匹配%s 的镜像\n", describeMatch(pattern))
+		return
+	}
+
+	partSize := parseSplitSize()
+	security := loadOutputSecurity()
+	configManager := config.NewConfigManager(configFile)
+
+	// 创建保存目录
+	dirName := resolveOutputDir(configManager, fmt.Sprintf("images_%s", sanitizePatternDir(pattern)))
+	if err := os.MkdirAll(dirName, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "创建目录失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	var tasks []saveTask
+	skipped := 0
+	if saveSingleArchive {
+		path, err := puller.ResolveOutputPath(dirName, singleArchiveName, force)
+		if err == nil {
+			err = checkSplitOutput(path, partSize)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+			os.Exit(1)
+		}
+		tasks = []saveTask{{Images: images, Path: path}}
+	} else {
+		tasks, skipped = resolveSaveTasks(configManager, dirName, images, partSize)
+	}
+
+	// 输出路径确定后再保存镜像列表，全部镜像被跳过时不改动已有的列表
+	if len(tasks) > 0 {
+		if err := writeSaveList(filepath.Join(dirName, "list.txt"), images); err != nil {
+			fmt.Fprintf(os.Stderr, "创建列表文件失败: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	savedFiles, failed := runSaveTasks(tasks, security, partSize)
+	failed += skipped
+	finalizeArchives(savedFiles, security, partSize)
+
+	fmt.Printf("保存操作完成！文件保存在目录: %s\n", dirName)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d 个镜像保存失败或被跳过\n", failed)
+		os.Exit(1)
+	}
+}
+
+// resolveSaveTasks 为每个镜像确定输出文件，跳过已存在或与其他镜像重名的文件，返回任务和跳过的镜像数量
+func resolveSaveTasks(configManager *config.ConfigManager, dirName string, images []string, partSize int64) ([]saveTask, int) {
+	var tasks []saveTask
+	skipped := 0
+	owners := make(map[string]string)
+	for _, image := range images {
+		path, err := resolveSavePath(configManager, dirName, image, saveFileName(image))
+		if err == nil {
+			err = checkSplitOutput(path, partSize)
+		}
+		if err == nil {
+			if owner, ok := owners[path]; ok {
+				err = fmt.Errorf("输出文件 %s 与镜像 %s 重名，请调整 --name-template", filepath.Base(path), owner)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 跳过镜像 %s: %v\n", image, err)
+			skipped++
+			continue
+		}
+		owners[path] = image
+		tasks = append(tasks, saveTask{Images: []string{image}, Path: path})
+	}
+	return tasks, skipped
+}
+
+// writeSaveList 写入镜像列表文件，每行一个镜像
+func writeSaveList(path string, images []string) error {
+	var content strings.Builder
+	for _, image := range images {
+		content.WriteString(image + "\n")
+	}
+	return os.WriteFile(path, []byte(content.String()), 0644)
+}
+
+// saveNameEscaper 将镜像引用转换为文件名：名称中原有的 _ 和 - 写为 __ 和 --，
+// 单个 _ 表示 /，单个 - 表示 :，因此不同的镜像引用不会得到相同的文件名
+var saveNameEscaper = strings.NewReplacer("_", "__", "-", "--", "/", "_", ":", "-")
+
+// saveFileName 默认的镜像归档文件名，包含仓库地址和命名空间以避免重名，
+// 例如 harbor.local/a/nginx:1.25 为 harbor.local_a_nginx-1.25.tar，
+// foo-1:2 为 docker.io_foo--1-2.tar；悬空镜像使用短镜像 ID
+func saveFileName(image string) string {
+	if id, ok := strings.CutPrefix(image, "sha256:"); ok {
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		return "dangling-" + id + ".tar"
+	}
+	registry, repo, tag := splitImageReference(image)
+	return saveNameEscaper.Replace(registry+"/"+repo+":"+tag) + ".tar"
+}
+
+// checkSplitOutput 需要切分分卷时检查分卷索引是否已存在
+func checkSplitOutput(path string, partSize int64) error {
+	if partSize > 0 && !force {
+		if _, err := os.Stat(puller.SplitIndexPath(path)); err == nil {
+			return fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", puller.SplitIndexPath(path))
+		}
+	}
+	return nil
+}
+
+// runSaveTasks 按 -j 并发导出归档，返回按任务顺序排列的成功文件和失败数量；
+// 只有一个任务同时执行时显示进度条
+func runSaveTasks(tasks []saveTask, security *outputSecurity, partSize int64) ([]string, int) {
+	workers := saveConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+
+	results := make([]string, len(tasks))
+	errs := make([]error, len(tasks))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task saveTask) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			label := task.Images[0]
+			if len(task.Images) > 1 {
+				label = fmt.Sprintf("%d 个镜像", len(task.Images))
+			}
+			fmt.Printf("正在保存镜像 %d/%d: %s\n", i+1, len(tasks), label)
+			results[i], errs[i] = saveTaskArchive(task, security, partSize, workers == 1)
+			if errs[i] != nil {
+				fmt.Fprintf(os.Stderr, "保存镜像 %s 失败: %v\n", label, errs[i])
+			} else {
+				fmt.Printf("✅ 成功保存镜像: %s -> %s\n", label, filepath.Base(results[i]))
+			}
+		}(i, task)
+	}
+	wg.Wait()
+
+	var savedFiles []string
+	failed := 0
+	for i, path := range results {
+		if errs[i] != nil {
+			failed++
+			continue
+		}
+		savedFiles = append(savedFiles, path)
+	}
+	return savedFiles, failed
+}
+
+// saveTaskArchive 导出归档，按需改写为增量归档并切分分卷，返回最终路径
+func saveTaskArchive(task saveTask, security *outputSecurity, partSize int64, progress bool) (string, error) {
+	if err := writeImageArchive(task.Path, task.Images, progress); err != nil {
+		return "", err
+	}
+
+	if baseline != "" {
+		omitted, err := puller.WriteDeltaArchive(task.Path, baseline)
+		if err != nil {
+			return "", fmt.Errorf("生成增量归档失败: %v", err)
+		}
+		fmt.Printf("%s: %d 个层已在基线中，未写入归档\n", filepath.Base(task.Path), omitted)
+	}
+
+	if splitPartSize := security.streamSplitSize(partSize); splitPartSize > 0 {
+		indexPath, err := puller.SplitFile(task.Path, splitPartSize)
+		if err != nil {
+			return "", fmt.Errorf("切分失败: %v", err)
+		}
+		return indexPath, nil
+	}
+	return task.Path, nil
+}
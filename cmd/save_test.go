@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dockerops/internal/config"
+)
+
+func TestSaveFileName(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"harbor.local/a/nginx:1.25", "harbor.local_a_nginx-1.25.tar"},
+		{"nginx", "docker.io_nginx-latest.tar"},
+		{"localhost:5000/app:v1", "localhost-5000_app-v1.tar"},
+		{"foo:1-2", "docker.io_foo-1--2.tar"},
+		{"foo-1:2", "docker.io_foo--1-2.tar"},
+		{"a_b/c", "docker.io_a__b_c-latest.tar"},
+		{"a/b_c", "docker.io_a_b__c-latest.tar"},
+		{"my-nginx-exporter:v1_rc", "docker.io_my--nginx--exporter-v1__rc.tar"},
+		{"sha256:0123456789abcdef0123", "dangling-0123456789ab.tar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := saveFileName(tt.image); got != tt.want {
+				t.Errorf("saveFileName(%q) = %s，期望 %s", tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveFileNameUnique(t *testing.T) {
+	images := []string{
+		"foo:1-2", "foo-1:2", "foo:1_2", "foo_1:2",
+		"a_b/c", "a/b_c", "a-b/c", "a/b-c", "a__b/c", "a/_b",
+		"host.local:5000/a:b", "host.local/5000/a:b", "host.local/5000:a/b",
+		"x/y:z", "x_y:z", "x-y:z", "x/y-z", "x/y_z",
+	}
+	owners := make(map[string]string)
+	for _, image := range images {
+		name := saveFileName(image)
+		if owner, ok := owners[name]; ok {
+			t.Errorf("%s 与 %s 的文件名同为 %s", image, owner, name)
+		}
+		owners[name] = image
+	}
+}
+
+func TestResolveSaveTasks(t *testing.T) {
+	dir := t.TempDir()
+	configManager := config.NewConfigManager(filepath.Join(dir, "config.json"))
+	if err := os.WriteFile(filepath.Join(dir, "docker.io_bar-1.tar"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	images := []string{"foo:1-2", "foo-1:2", "a_b/c", "a/b_c", "bar:1"}
+	tasks, skipped := resolveSaveTasks(configManager, dir, images, 0)
+	if skipped != 1 {
+		t.Errorf("跳过的镜像数量 = %d，期望 1（只跳过已存在的 bar:1）", skipped)
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("任务数量 = %d，期望 4", len(tasks))
+	}
+	for i, task := range tasks {
+		if want := filepath.Join(dir, saveFileName(images[i])); task.Path != want || task.Images[0] != images[i] {
+			t.Errorf("任务 %d = %+v，期望 %s -> %s", i, task, images[i], want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncFile  string
+	syncState string
+)
+
+// syncCmd 声明式同步命令
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "按配置文件将上游镜像同步到私有仓库",
+	Long: `读取同步配置文件，将其中列出的源镜像（支持标签通配模式）复制到目标仓库。
+每次运行只复制源清单 digest 发生变化的镜像，结果记录在状态文件中，适合由 cron 定期执行。
+源镜像通过配置的镜像源搜索，某个镜像源不可用时自动切换到下一个。`,
+	Example: `  DockerOps sync -f sync.yaml
+  DockerOps sync -f sync.yaml --state /var/lib/dockerops/sync.state.json --dest-username admin --dest-password secret`,
+	Args: cobra.NoArgs,
+	Run:  runSync,
+}
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncFile, "file", "f", "", "同步配置文件（YAML）")
+	syncCmd.Flags().StringVar(&syncState, "state", "", "状态文件路径，默认使用配置中的 state 或配置文件同目录的 <名称>.state.json")
+	syncCmd.Flags().StringVarP(&arch, "arch", "a", "", "单架构同步时的架构，默认：amd64")
+	syncCmd.Flags().StringVarP(&username, "username", "u", "", "源仓库用户名")
+	syncCmd.Flags().StringVarP(&password, "password", "p", "", "源仓库密码")
+	syncCmd.Flags().StringVar(&destUsername, "dest-username", "", "目标仓库用户名")
+	syncCmd.Flags().StringVar(&destPassword, "dest-password", "", "目标仓库密码")
+	syncCmd.Flags().StringVar(&pushChunkSize, "chunk-size", "", "分块上传的块大小（例如 16M），默认整体上传")
+	syncCmd.Flags().BoolVar(&plainHTTP, "plain-http", false, "使用 HTTP 访问目标仓库")
+	syncCmd.MarkFlagRequired("file")
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+// runSync 执行声明式同步命令
+func runSync(cmd *cobra.Command, args []string) {
+	syncConfig, err := puller.LoadSyncConfig(syncFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+		os.Exit(1)
+	}
+	if syncState != "" {
+		syncConfig.State = syncState
+	}
+
+	state, err := puller.LoadSyncState(syncConfig.State)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "错误：读取状态文件失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+
+	if arch == "" {
+		arch = configManager.GetConfig().Settings.DefaultArchitecture
+	}
+
+	start := time.Now()
+	jobs, results := imagePuller.ExpandSyncJobs(syncConfig, username, password)
+	fmt.Printf("共 %d 个同步任务，目标：%s\n", len(jobs), valueOrDash(syncConfig.Target))
+
+	results = append(results, imagePuller.Sync(jobs, state, syncConfig.State, puller.CopyOptions{
+		Arch:         arch,
+		Platforms:    syncConfig.Platforms,
+		AllPlatforms: syncConfig.AllPlatforms,
+		Username:     username,
+		Password:     password,
+		Dest: puller.PushOptions{
+			Username:  destUsername,
+			Password:  destPassword,
+			ChunkSize: parseChunkSize(),
+			PlainHTTP: plainHTTP,
+		},
+	})...)
+
+	if err := state.Save(syncConfig.State); err != nil {
+		fmt.Fprintf(os.Stderr, "保存状态文件失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := printSyncSummary(results)
+	fmt.Printf("总耗时: %s，状态文件：%s\n", time.Since(start).Round(time.Second), syncConfig.State)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printSyncSummary 打印同步结果表格和统计，返回失败数量
+func printSyncSummary(results []puller.SyncResult) int {
+	puller.SortSyncResults(results)
+	counts := make(map[string]int)
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "目标镜像\t状态\t源仓库\t传输\t清单digest")
+	for _, result := range results {
+		counts[result.Status]++
+
+		status := "❌ 失败"
+		switch result.Status {
+		case puller.SyncAdded:
+			status = "🆕 新增"
+		case puller.SyncUpdated:
+			status = "🔄 更新"
+		case puller.SyncUnchanged:
+			status = "⏭️ 未变化"
+		}
+
+		registry, transferred, digest := "-", "-", "-"
+		if result.Copy != nil && result.Err == nil {
+			registry = valueOrDash(result.Copy.Registry)
+			digest = valueOrDash(result.Copy.Digest)
+			if !result.Copy.Skipped {
+				transferred = formatSize(result.Copy.Size)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Job.Target, status, registry, transferred, digest)
+	}
+	w.Flush()
+
+	failed := counts[puller.SyncFailed]
+	if failed > 0 {
+		fmt.Println("\n失败原因:")
+		for _, result := range results {
+			if result.Err != nil {
+				fmt.Printf("  %s: %v\n", result.Job.Source, result.Err)
+			}
+		}
+	}
+
+	fmt.Printf("\n新增 %d 个，更新 %d 个，未变化 %d 个，失败 %d 个\n",
+		counts[puller.SyncAdded], counts[puller.SyncUpdated], counts[puller.SyncUnchanged], failed)
+	return failed
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dockerops/internal/config"
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagsFilter string
+	tagsSemver string
+	tagsSort   string
+	tagsLimit  int
+)
+
+// tagsCmd 标签列表命令
+var tagsCmd = &cobra.Command{
+	Use:   "tags <镜像>",
+	Short: "列出镜像的标签，支持正则、版本约束过滤和排序",
+	Long: `通过配置的镜像源查询镜像的标签列表（自动翻页），某个镜像源不可用时自动切换到下一个。
+版本约束支持 ~1.25（1.25.x）、^1.27（1.x）、>=1.2,<2、1.25.x 等写法；
+约束中带后缀时（例如 ~1.25-alpine）只匹配相同后缀的标签。
+镜像引用中的标签为版本约束时（例如 nginx:~1.25）等同于 --semver。
+pull 命令同样接受版本约束，会拉取满足约束的最高版本。`,
+	Example: `  DockerOps tags nginx
+  DockerOps tags registry.k8s.io/kube-apiserver --semver "~1.27" --limit 1
+  DockerOps tags nginx --filter "alpine$" --sort name`,
+	Args: cobra.ExactArgs(1),
+	Run:  runTags,
+}
+
+func init() {
+	tagsCmd.Flags().StringVar(&tagsFilter, "filter", "", "只显示匹配正则表达式的标签")
+	tagsCmd.Flags().StringVar(&tagsSemver, "semver", "", "只显示满足版本约束的标签，例如 ~1.25 或 >=1.2,<2")
+	tagsCmd.Flags().StringVar(&tagsSort, "sort", "semver", "排序方式：semver（版本从高到低）、name、none（仓库返回的顺序）")
+	tagsCmd.Flags().IntVarP(&tagsLimit, "limit", "n", 0, "最多显示的标签数量，0 表示不限制")
+	tagsCmd.Flags().StringVarP(&username, "username", "u", "", "仓库用户名")
+	tagsCmd.Flags().StringVarP(&password, "password", "p", "", "仓库密码")
+
+	rootCmd.AddCommand(tagsCmd)
+}
+
+// runTags 执行标签列表命令
+func runTags(cmd *cobra.Command, args []string) {
+	configManager := config.NewConfigManager(configFile)
+	imagePuller := puller.NewMultiRegistryImagePuller(configManager)
+
+	// 镜像引用带标签时去掉标签，标签为版本约束时用作 --semver
+	name := args[0]
+	if strings.Contains(name[strings.LastIndex(name, "/")+1:], ":") {
+		imageInfo := imagePuller.ParseImageInput(name)
+		name = strings.TrimSuffix(name, ":"+imageInfo.Tag)
+		if tagsSemver == "" && puller.IsTagConstraint(imageInfo.Tag) {
+			tagsSemver = imageInfo.Tag
+		}
+	}
+
+	var filter *regexp.Regexp
+	if tagsFilter != "" {
+		var err error
+		if filter, err = regexp.Compile(tagsFilter); err != nil {
+			fmt.Fprintf(os.Stderr, "错误：无效的正则表达式 %s: %v\n", tagsFilter, err)
+			os.Exit(1)
+		}
+	}
+
+	var constraint *puller.VersionConstraint
+	if tagsSemver != "" {
+		var err error
+		if constraint, err = puller.ParseVersionConstraint(tagsSemver); err != nil {
+			fmt.Fprintf(os.Stderr, "错误：%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch tagsSort {
+	case "semver", "name", "none":
+	default:
+		fmt.Fprintf(os.Stderr, "错误：不支持的排序方式 %s，可选：semver、name、none\n", tagsSort)
+		os.Exit(1)
+	}
+
+	registry, imageInfo, tags, err := imagePuller.ListTags(name, username, password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "获取标签失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	var matched []string
+	for _, tag := range tags {
+		if filter != nil && !filter.MatchString(tag) {
+			continue
+		}
+		if constraint != nil {
+			version, ok := puller.ParseVersion(tag)
+			if !ok || !constraint.Match(version) {
+				continue
+			}
+		}
+		matched = append(matched, tag)
+	}
+
+	switch tagsSort {
+	case "semver":
+		puller.SortTagsBySemver(matched)
+	case "name":
+		sort.Strings(matched)
+	}
+
+	total := len(matched)
+	if tagsLimit > 0 && len(matched) > tagsLimit {
+		matched = matched[:tagsLimit]
+	}
+
+	fmt.Fprintf(os.Stderr, "%s 在 %s 中共有 %d 个标签，匹配 %d 个\n", imageInfo.Repository, registry.Name, len(tags), total)
+	for _, tag := range matched {
+		fmt.Println(tag)
+	}
+	if total == 0 {
+		os.Exit(1)
+	}
+}
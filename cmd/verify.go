@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"dockerops/internal/puller"
+
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd 校验离线传输的镜像归档
+var verifyCmd = &cobra.Command{
+	Use:   "verify <目录|归档>...",
+	Short: "校验镜像归档的完整性",
+	Long: `重新计算镜像归档的 sha256 并与目录中的 ` + puller.BundleManifestName + ` 比对，
+同时根据归档内的 manifest.json 校验每个镜像的配置和各层 digest。
+传入目录时校验清单中列出的全部归档以及目录中未列入清单的归档，发现损坏时以非零状态退出。
+指定 --pubkey 时还会校验清单签名；对加密归档指定 --identity 或口令时会解密后校验内容，否则只校验文件 sha256。`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runVerify,
+}
+
+func init() {
+	addDecryptFlags(verifyCmd)
+
+	rootCmd.AddCommand(verifyCmd)
+}
+
+// runVerify 执行校验命令
+func runVerify(cmd *cobra.Command, args []string) {
+	trusted := loadTrustedKeys()
+
+	// 只有显式提供了私钥或口令时才解密校验，避免校验时交互询问口令
+	var keys *puller.Keyring
+	if len(identities) > 0 || passphraseFile != "" || os.Getenv(passphraseEnv) != "" {
+		keys = loadKeyring()
+	}
+
+	var results []puller.FileVerifyResult
+	for _, target := range args {
+		dir := target
+		if info, err := os.Stat(target); err == nil && !info.IsDir() {
+			dir = filepath.Dir(target)
+		}
+		if len(trusted) > 0 {
+			signer, err := puller.VerifyBundleSignature(dir, trusted)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "❌ %s: %v\n", target, err)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ %s 清单签名校验通过（签名者 %s）\n", dir, signer)
+		} else if puller.HasBundleSignature(dir) {
+			fmt.Fprintf(os.Stderr, "⚠️ %s 中的清单已签名，但未指定 --pubkey，未校验签名\n", dir)
+		}
+
+		targetResults, err := puller.VerifyPath(target, keys)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "校验 %s 失败: %v\n", target, err)
+			os.Exit(1)
+		}
+		// 已校验签名时，不在已签名清单中的归档视为校验失败
+		if len(trusted) > 0 {
+			for i := range targetResults {
+				if !targetResults[i].InManifest {
+					targetResults[i].Errors = append(targetResults[i].Errors, "不在已签名的清单中")
+				}
+			}
+		}
+		results = append(results, targetResults...)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("未找到需要校验的镜像归档")
+		return
+	}
+
+	if printVerifySummary(results) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printVerifySummary 打印校验结果表格，返回损坏的文件数量
+func printVerifySummary(results []puller.FileVerifyResult) int {
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "文件\t状态\t大小\t清单\t镜像")
+	for _, result := range results {
+		status := "✅ 完好"
+		if !result.OK() {
+			status = "❌ 损坏"
+			failed++
+		} else if result.Info != nil && result.Info.Encrypted {
+			status = "✅ 完好（🔒 加密）"
+		} else if result.Info != nil && result.Info.Delta {
+			status = "✅ 完好（增量）"
+		}
+
+		listed := "否"
+		if result.InManifest {
+			listed = "是"
+		}
+
+		var size int64
+		var images []string
+		if result.Info != nil {
+			size = result.Info.Size
+			for _, image := range result.Info.Images {
+				images = append(images, strings.Join(image.RepoTags, ","))
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			result.Name,
+			status,
+			formatSize(size),
+			listed,
+			valueOrDash(strings.Join(images, " ")),
+		)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fmt.Println("\n损坏详情:")
+		for _, result := range results {
+			if result.OK() {
+				continue
+			}
+			fmt.Printf("  %s:\n", result.Name)
+			for _, e := range result.Errors {
+				fmt.Printf("    - %s\n", e)
+			}
+			if result.Info == nil {
+				continue
+			}
+			for _, e := range result.Info.Errors {
+				fmt.Printf("    - %s\n", e)
+			}
+			for _, image := range result.Info.Images {
+				for _, e := range image.Errors {
+					fmt.Printf("    - [%s] %s\n", valueOrDash(strings.Join(image.RepoTags, ",")), e)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("\n共校验 %d 个文件，完好 %d 个，损坏 %d 个\n", len(results), len(results)-failed, failed)
+	return failed
+}
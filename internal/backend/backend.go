@@ -0,0 +1,236 @@
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrImageNotFound 本地运行时中没有指定镜像
+var ErrImageNotFound = errors.New("镜像不存在")
+
+// Names 支持的容器运行时名称
+var Names = []string{"docker", "podman", "containerd"}
+
+// ImageInfo 本地镜像信息
+type ImageInfo struct {
+	ID           string // 镜像配置的摘要（sha256:...），与 docker save 归档中的镜像 ID 一致
+	Architecture string
+}
+
+// Image 本地镜像列表中的一项，每个引用一项；悬空镜像没有引用，Ref 为空
+type Image struct {
+	Ref     string
+	ID      string    // nerdctl 为短 ID，运行时不提供时为空
+	Created time.Time // 运行时不提供创建时间时为零值
+	Size    int64
+}
+
+// Dangling 是否为没有引用的悬空镜像
+func (i Image) Dangling() bool {
+	return i.Ref == ""
+}
+
+// Runtime 本地容器运行时，match、save、load、push 等命令通过它访问本地镜像
+type Runtime interface {
+	// Name 返回运行时名称
+	Name() string
+	// ListImages 列出本地全部镜像，包括悬空镜像
+	ListImages() ([]Image, error)
+	// InspectImage 查询本地镜像，镜像不存在时返回 ErrImageNotFound
+	InspectImage(ref string) (*ImageInfo, error)
+	// SaveImages 将镜像导出为 docker save 格式的 tar 流写入 w
+	SaveImages(w io.Writer, refs []string) error
+	// LoadImages 从 tar 流导入镜像，进度写入 out，返回导入的镜像引用
+	LoadImages(r io.Reader, out io.Writer) ([]string, error)
+	// PushImage 推送本地镜像，未指定用户名时使用运行时保存的登录凭据
+	PushImage(ref, username, password string, out io.Writer) error
+	// TagImage 为本地镜像添加新的引用
+	TagImage(source, target string) error
+	// RemoveTag 移除本地镜像的一个引用，镜像内容仍被其他引用使用时保留
+	RemoveTag(ref string) error
+}
+
+// New 按名称创建容器运行时，名称为空或 auto 时自动检测
+func New(name string) (Runtime, error) {
+	switch strings.ToLower(name) {
+	case "", "auto":
+		return Detect()
+	case "docker":
+		return newDockerRuntime()
+	case "podman":
+		return newPodmanRuntime()
+	case "containerd", "nerdctl", "ctr":
+		return newContainerdRuntime(strings.ToLower(name))
+	default:
+		return nil, fmt.Errorf("不支持的容器运行时 %s，可选: %s", name, strings.Join(Names, ", "))
+	}
+}
+
+// Detect 依次尝试 docker、podman、containerd，返回第一个可用的运行时
+func Detect() (Runtime, error) {
+	var reasons []string
+	for _, create := range []func() (Runtime, error){
+		func() (Runtime, error) { return newDockerRuntime() },
+		func() (Runtime, error) { return newPodmanRuntime() },
+		func() (Runtime, error) { return newContainerdRuntime("containerd") },
+	} {
+		runtime, err := create()
+		if err == nil {
+			return runtime, nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return nil, fmt.Errorf("未找到可用的容器运行时:\n  %s", strings.Join(reasons, "\n  "))
+}
+
+// commandError 命令行工具执行失败的错误，包含标准错误输出
+type commandError struct {
+	Command string
+	Err     error
+	Stderr  string
+}
+
+func (e *commandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("执行 %s 失败: %v", e.Command, e.Err)
+	}
+	return fmt.Sprintf("执行 %s 失败: %v: %s", e.Command, e.Err, e.Stderr)
+}
+
+// runTool 执行命令行工具，stdin 和 stdout 可为 nil，失败时返回带标准错误输出的 commandError
+func runTool(stdin io.Reader, stdout io.Writer, name string, args ...string) error {
+	return runToolEnv(nil, stdin, stdout, name, args...)
+}
+
+// runToolEnv 与 runTool 相同，env 中的环境变量追加到当前进程的环境变量之后
+func runToolEnv(env []string, stdin io.Reader, stdout io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return &commandError{
+			Command: name + " " + strings.Join(args, " "),
+			Err:     err,
+			Stderr:  strings.TrimSpace(stderr.String()),
+		}
+	}
+	return nil
+}
+
+// tempAuthConfig 创建只包含目标仓库凭据的临时配置目录（config.json 与 docker、podman --authfile 和 nerdctl 的格式一致），
+// 用于推送时传递凭据而不把密码放进命令参数；调用方负责删除返回的目录
+func tempAuthConfig(ref, username, password string) (string, error) {
+	registry, _, _ := strings.Cut(normalizeReference(ref), "/")
+	auth := map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte(username + ":" + password))}
+	auths := map[string]any{registry: auth}
+	if registry == "docker.io" {
+		// docker 命令使用旧的 Docker Hub 地址作为键
+		auths["https://index.docker.io/v1/"] = auth
+	}
+	data, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "dockerops-auth-")
+	if err != nil {
+		return "", fmt.Errorf("创建临时凭据目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("写入临时凭据失败: %v", err)
+	}
+	return dir, nil
+}
+
+// toolOutput 执行命令行工具并返回标准输出
+func toolOutput(name string, args ...string) (string, error) {
+	var stdout strings.Builder
+	if err := runTool(nil, &stdout, name, args...); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// splitLines 按行拆分输出，去掉空行
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// loadedImages 从导入输出中提取镜像引用，兼容 docker/podman/nerdctl 的 "Loaded image: " 格式
+func loadedImages(output string) []string {
+	var images []string
+	for _, line := range splitLines(output) {
+		for _, prefix := range []string{"Loaded image: ", "Loaded image(s): ", "Loaded image ID: "} {
+			if strings.HasPrefix(line, prefix) {
+				for _, image := range strings.Split(strings.TrimPrefix(line, prefix), ",") {
+					images = append(images, strings.TrimSpace(image))
+				}
+			}
+		}
+	}
+	return images
+}
+
+// normalizeID 为缺少算法前缀的镜像 ID 补上 sha256:
+func normalizeID(id string) string {
+	id = strings.TrimSpace(id)
+	if id != "" && !strings.Contains(id, ":") {
+		return "sha256:" + id
+	}
+	return id
+}
+
+// parseHumanSize 解析 nerdctl、ctr 输出中的可读大小，例如 67.3 MiB、12.5MB、512B
+func parseHumanSize(value string) int64 {
+	s := strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(s)
+	}
+	number, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0
+	}
+
+	multiplier := float64(1)
+	switch strings.ToUpper(s[i:]) {
+	case "KB":
+		multiplier = 1e3
+	case "MB":
+		multiplier = 1e6
+	case "GB":
+		multiplier = 1e9
+	case "TB":
+		multiplier = 1e12
+	case "KIB":
+		multiplier = 1 << 10
+	case "MIB":
+		multiplier = 1 << 20
+	case "GIB":
+		multiplier = 1 << 30
+	case "TIB":
+		multiplier = 1 << 40
+	}
+	return int64(number * multiplier)
+}
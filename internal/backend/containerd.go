@@ -0,0 +1,287 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strings"
+	"time"
+)
+
+// defaultContainerdNamespace Kubernetes 节点上 kubelet 使用的 containerd 命名空间
+const defaultContainerdNamespace = "k8s.io"
+
+// containerdRuntime 通过 nerdctl 或 ctr 访问 containerd 中的镜像，优先使用 nerdctl
+type containerdRuntime struct {
+	tool      string // nerdctl 或 ctr
+	namespace string
+}
+
+// newContainerdRuntime 创建 containerd 运行时，name 为 nerdctl 或 ctr 时只使用对应命令，
+// 命名空间取自 CONTAINERD_NAMESPACE，默认为 k8s.io
+func newContainerdRuntime(name string) (Runtime, error) {
+	namespace := os.Getenv("CONTAINERD_NAMESPACE")
+	if namespace == "" {
+		namespace = defaultContainerdNamespace
+	}
+
+	tools := []string{"nerdctl", "ctr"}
+	if name == "nerdctl" || name == "ctr" {
+		tools = []string{name}
+	}
+	for _, tool := range tools {
+		if _, err := exec.LookPath(tool); err == nil {
+			return &containerdRuntime{tool: tool, namespace: namespace}, nil
+		}
+	}
+	return nil, fmt.Errorf("containerd: 未找到 %s 命令", strings.Join(tools, " 或 "))
+}
+
+func (c *containerdRuntime) Name() string {
+	return "containerd"
+}
+
+// run 在命名空间中执行 nerdctl 或 ctr 命令
+func (c *containerdRuntime) run(stdin io.Reader, stdout io.Writer, args ...string) error {
+	return runTool(stdin, stdout, c.tool, append([]string{"--namespace", c.namespace}, args...)...)
+}
+
+// output 在命名空间中执行命令并返回标准输出
+func (c *containerdRuntime) output(args ...string) (string, error) {
+	var stdout strings.Builder
+	if err := c.run(nil, &stdout, args...); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (c *containerdRuntime) ListImages() ([]Image, error) {
+	if c.tool == "nerdctl" {
+		return c.listWithNerdctl()
+	}
+
+	output, err := c.output("images", "ls")
+	if err != nil {
+		return nil, err
+	}
+	// 输出格式：REF TYPE DIGEST SIZE PLATFORMS LABELS，第一行为表头，大小包含单位（例如 67.3 MiB）；
+	// ctr 不提供镜像创建时间，DIGEST 是 manifest 摘要而不是镜像 ID
+	var images []Image
+	for i, line := range splitLines(output) {
+		fields := strings.Fields(line)
+		// 跳过表头和 kubelet 记录的纯摘要引用
+		if i == 0 || len(fields) < 5 || strings.Contains(fields[0], "@") || strings.HasPrefix(fields[0], "sha256:") {
+			continue
+		}
+		images = append(images, Image{Ref: fields[0], Size: parseHumanSize(fields[3] + fields[4])})
+	}
+	return images, nil
+}
+
+// listWithNerdctl 通过 nerdctl images 的 JSON 输出列出镜像
+func (c *containerdRuntime) listWithNerdctl() ([]Image, error) {
+	output, err := c.output("images", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	for _, line := range splitLines(output) {
+		var summary struct {
+			Repository string `json:"Repository"`
+			Tag        string `json:"Tag"`
+			ID         string `json:"ID"`
+			CreatedAt  string `json:"CreatedAt"`
+			Size       string `json:"Size"`
+		}
+		if err := json.Unmarshal([]byte(line), &summary); err != nil {
+			return nil, fmt.Errorf("解析 nerdctl 镜像列表失败: %v", err)
+		}
+		// 跳过 kubelet 记录的纯摘要引用
+		if strings.HasPrefix(summary.Repository, "sha256:") || (summary.Repository != "<none>" && summary.Tag == "<none>") {
+			continue
+		}
+
+		image := Image{ID: summary.ID, Size: parseHumanSize(summary.Size)}
+		if created, err := time.Parse("2006-01-02 15:04:05 -0700 MST", summary.CreatedAt); err == nil {
+			image.Created = created
+		}
+		if summary.Repository != "<none>" {
+			image.Ref = summary.Repository + ":" + summary.Tag
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+func (c *containerdRuntime) InspectImage(ref string) (*ImageInfo, error) {
+	if c.tool == "nerdctl" {
+		output, err := c.output("image", "inspect", "--format", "{{.ID}} {{.Architecture}}", ref)
+		if err != nil {
+			if strings.Contains(strings.ToLower(err.Error()), "no such") || strings.Contains(err.Error(), "not found") {
+				return nil, ErrImageNotFound
+			}
+			return nil, err
+		}
+		id, arch, _ := strings.Cut(strings.TrimSpace(output), " ")
+		return &ImageInfo{ID: normalizeID(id), Architecture: arch}, nil
+	}
+	return c.inspectWithCtr(normalizeReference(ref))
+}
+
+// inspectWithCtr 通过 ctr 读取镜像的 manifest 和配置，得到与 docker 一致的镜像 ID（配置摘要）
+func (c *containerdRuntime) inspectWithCtr(ref string) (*ImageInfo, error) {
+	output, err := c.output("images", "ls", "name=="+ref)
+	if err != nil {
+		return nil, err
+	}
+	// 输出格式：REF TYPE DIGEST SIZE PLATFORMS LABELS，第一行为表头
+	lines := splitLines(output)
+	if len(lines) < 2 {
+		return nil, ErrImageNotFound
+	}
+	fields := strings.Fields(lines[1])
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("无法解析 ctr 输出: %s", lines[1])
+	}
+
+	var manifest struct {
+		Manifests []struct {
+			Digest   string `json:"digest"`
+			Platform struct {
+				Architecture string `json:"architecture"`
+				OS           string `json:"os"`
+			} `json:"platform"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if err := c.readContent(fields[2], &manifest); err != nil {
+		return nil, err
+	}
+	// 多架构镜像选择当前平台的 manifest
+	if len(manifest.Manifests) > 0 {
+		digest := manifest.Manifests[0].Digest
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == goruntime.GOARCH {
+				digest = m.Digest
+				break
+			}
+		}
+		if err := c.readContent(digest, &manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	var imageConfig struct {
+		Architecture string `json:"architecture"`
+	}
+	if err := c.readContent(manifest.Config.Digest, &imageConfig); err != nil {
+		return nil, err
+	}
+	return &ImageInfo{ID: manifest.Config.Digest, Architecture: imageConfig.Architecture}, nil
+}
+
+// readContent 读取 containerd 内容存储中的 JSON 对象
+func (c *containerdRuntime) readContent(digest string, v any) error {
+	output, err := c.output("content", "get", digest)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(output), v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %v", digest, err)
+	}
+	return nil
+}
+
+func (c *containerdRuntime) SaveImages(w io.Writer, refs []string) error {
+	if c.tool == "nerdctl" {
+		return c.run(nil, w, append([]string{"save"}, refs...)...)
+	}
+	args := []string{"images", "export", "-"}
+	for _, ref := range refs {
+		args = append(args, normalizeReference(ref))
+	}
+	return c.run(nil, w, args...)
+}
+
+func (c *containerdRuntime) LoadImages(r io.Reader, out io.Writer) ([]string, error) {
+	var stdout strings.Builder
+	args := []string{"load"}
+	if c.tool == "ctr" {
+		args = []string{"images", "import", "-"}
+	}
+	if err := c.run(r, &stdout, args...); err != nil {
+		return nil, err
+	}
+	if out != nil {
+		io.WriteString(out, stdout.String())
+	}
+
+	images := loadedImages(stdout.String())
+	// ctr 输出格式：unpacking docker.io/library/nginx:1.25 (sha256:...)...done
+	for _, line := range splitLines(stdout.String()) {
+		if name, ok := strings.CutPrefix(line, "unpacking "); ok {
+			name, _, _ = strings.Cut(name, " ")
+			images = append(images, name)
+		}
+	}
+	return images, nil
+}
+
+// PushImage 指定用户名时通过临时配置目录（DOCKER_CONFIG）把凭据交给 nerdctl，密码不出现在命令参数中；
+// ctr 只能在命令参数中传递密码，因此带凭据推送时改用 nerdctl
+func (c *containerdRuntime) PushImage(ref, username, password string, out io.Writer) error {
+	if username == "" {
+		if c.tool == "nerdctl" {
+			return c.run(nil, out, "push", ref)
+		}
+		return c.run(nil, out, "images", "push", normalizeReference(ref))
+	}
+
+	if _, err := exec.LookPath("nerdctl"); err != nil {
+		return fmt.Errorf("ctr 只能在命令参数中传递密码，带 -u/-p 推送需要 nerdctl；也可以不指定 -u/-p，改用 nerdctl login 保存的凭据")
+	}
+	dir, err := tempAuthConfig(ref, username, password)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	return runToolEnv([]string{"DOCKER_CONFIG=" + dir}, nil, out, "nerdctl", "--namespace", c.namespace, "push", ref)
+}
+
+func (c *containerdRuntime) TagImage(source, target string) error {
+	if c.tool == "nerdctl" {
+		return c.run(nil, nil, "tag", source, target)
+	}
+	return c.run(nil, nil, "images", "tag", normalizeReference(source), normalizeReference(target))
+}
+
+// RemoveTag containerd 中每个镜像引用是独立的记录，删除记录不影响其他引用共享的内容
+func (c *containerdRuntime) RemoveTag(ref string) error {
+	if c.tool == "nerdctl" {
+		return c.run(nil, nil, "rmi", ref)
+	}
+	return c.run(nil, nil, "images", "rm", normalizeReference(ref))
+}
+
+// normalizeReference 将镜像引用补全为 containerd 使用的完整名称，
+// 例如 nginx 补全为 docker.io/library/nginx:latest
+func normalizeReference(ref string) string {
+	name := ref
+	first, _, found := strings.Cut(name, "/")
+	if !found || !(strings.ContainsAny(first, ".:") || first == "localhost") {
+		if !found {
+			name = "library/" + name
+		}
+		name = "docker.io/" + name
+	}
+	if !strings.Contains(name, "@") && strings.LastIndex(name, ":") <= strings.LastIndex(name, "/") {
+		name += ":latest"
+	}
+	return name
+}
@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"dockerops/internal/engine"
+)
+
+// dockerRuntime 通过 Docker Engine API 访问 dockerd
+type dockerRuntime struct {
+	client *engine.Client
+}
+
+// newDockerRuntime 创建 Docker 运行时；dockerd 只能通过 Windows 命名管道访问时改用 docker 命令
+func newDockerRuntime() (Runtime, error) {
+	client, err := engine.NewClient()
+	if errors.Is(err, engine.ErrNamedPipe) {
+		if _, lookErr := exec.LookPath("docker"); lookErr == nil {
+			return &dockerCLIRuntime{}, nil
+		}
+		return nil, fmt.Errorf("docker: %v，且未找到 docker 命令；可以设置 DOCKER_HOST=tcp://localhost:2375", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("docker: %v", err)
+	}
+	return &dockerRuntime{client: client}, nil
+}
+
+func (d *dockerRuntime) Name() string {
+	return "docker"
+}
+
+func (d *dockerRuntime) ListImages() ([]Image, error) {
+	summaries, err := d.client.ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	for _, summary := range summaries {
+		image := Image{ID: summary.ID, Created: time.Unix(summary.Created, 0), Size: summary.Size}
+		dangling := true
+		for _, tag := range summary.RepoTags {
+			if tag != "" && tag != "<none>:<none>" {
+				image.Ref = tag
+				images = append(images, image)
+				dangling = false
+			}
+		}
+		if dangling {
+			image.Ref = ""
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+func (d *dockerRuntime) InspectImage(ref string) (*ImageInfo, error) {
+	image, err := d.client.InspectImage(ref)
+	if engine.IsNotFound(err) {
+		return nil, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ImageInfo{ID: image.ID, Architecture: image.Architecture}, nil
+}
+
+func (d *dockerRuntime) SaveImages(w io.Writer, refs []string) error {
+	stream, err := d.client.SaveImages(refs)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+func (d *dockerRuntime) LoadImages(r io.Reader, out io.Writer) ([]string, error) {
+	return d.client.LoadImage(r, out)
+}
+
+func (d *dockerRuntime) PushImage(ref, username, password string, out io.Writer) error {
+	return d.client.PushImage(ref, username, password, out)
+}
+
+func (d *dockerRuntime) TagImage(source, target string) error {
+	return d.client.TagImage(source, target)
+}
+
+func (d *dockerRuntime) RemoveTag(ref string) error {
+	return d.client.RemoveImage(ref)
+}
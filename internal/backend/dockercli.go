@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// dockerCLIRuntime 通过 docker 命令访问本地镜像，用于内置客户端无法直接连接 dockerd 的情况，
+// 例如 Windows 上 Docker Desktop 默认使用的命名管道
+type dockerCLIRuntime struct{}
+
+func (d *dockerCLIRuntime) Name() string {
+	return "docker"
+}
+
+func (d *dockerCLIRuntime) ListImages() ([]Image, error) {
+	output, err := toolOutput("docker", "images", "--no-trunc", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	for _, line := range splitLines(output) {
+		var summary struct {
+			Repository string `json:"Repository"`
+			Tag        string `json:"Tag"`
+			ID         string `json:"ID"`
+			CreatedAt  string `json:"CreatedAt"`
+			Size       string `json:"Size"`
+		}
+		if err := json.Unmarshal([]byte(line), &summary); err != nil {
+			return nil, fmt.Errorf("解析 docker 镜像列表失败: %v", err)
+		}
+
+		image := Image{ID: normalizeID(summary.ID), Size: parseHumanSize(summary.Size)}
+		if created, err := time.Parse("2006-01-02 15:04:05 -0700 MST", summary.CreatedAt); err == nil {
+			image.Created = created
+		}
+		if summary.Repository != "<none>" && summary.Tag != "<none>" {
+			image.Ref = summary.Repository + ":" + summary.Tag
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+func (d *dockerCLIRuntime) InspectImage(ref string) (*ImageInfo, error) {
+	output, err := toolOutput("docker", "image", "inspect", "--format", "{{.Id}} {{.Architecture}}", ref)
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "no such image") {
+			return nil, ErrImageNotFound
+		}
+		return nil, err
+	}
+	id, arch, _ := strings.Cut(strings.TrimSpace(output), " ")
+	return &ImageInfo{ID: normalizeID(id), Architecture: arch}, nil
+}
+
+func (d *dockerCLIRuntime) SaveImages(w io.Writer, refs []string) error {
+	return runTool(nil, w, "docker", append([]string{"save"}, refs...)...)
+}
+
+func (d *dockerCLIRuntime) LoadImages(r io.Reader, out io.Writer) ([]string, error) {
+	var stdout strings.Builder
+	if err := runTool(r, &stdout, "docker", "load"); err != nil {
+		return nil, err
+	}
+	if out != nil {
+		io.WriteString(out, stdout.String())
+	}
+	return loadedImages(stdout.String()), nil
+}
+
+// PushImage 指定用户名时使用临时配置目录中的凭据推送，密码不出现在命令参数中
+func (d *dockerCLIRuntime) PushImage(ref, username, password string, out io.Writer) error {
+	if username == "" {
+		return runTool(nil, out, "docker", "push", ref)
+	}
+	dir, err := tempAuthConfig(ref, username, password)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	return runTool(nil, out, "docker", "--config", dir, "push", ref)
+}
+
+func (d *dockerCLIRuntime) TagImage(source, target string) error {
+	return runTool(nil, nil, "docker", "tag", source, target)
+}
+
+// RemoveTag docker rmi 在镜像还有其他引用时只移除该引用
+func (d *dockerCLIRuntime) RemoveTag(ref string) error {
+	return runTool(nil, nil, "docker", "rmi", ref)
+}
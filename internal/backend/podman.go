@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// podmanRuntime 通过 podman 命令访问本地镜像
+type podmanRuntime struct{}
+
+func newPodmanRuntime() (Runtime, error) {
+	if _, err := exec.LookPath("podman"); err != nil {
+		return nil, fmt.Errorf("podman: 未找到 podman 命令")
+	}
+	return &podmanRuntime{}, nil
+}
+
+func (p *podmanRuntime) Name() string {
+	return "podman"
+}
+
+func (p *podmanRuntime) ListImages() ([]Image, error) {
+	output, err := toolOutput("podman", "images", "--format", "json")
+	if err != nil {
+		return nil, err
+	}
+	var summaries []struct {
+		ID      string   `json:"Id"`
+		Names   []string `json:"Names"`
+		Created int64    `json:"Created"`
+		Size    int64    `json:"Size"`
+	}
+	if err := json.Unmarshal([]byte(output), &summaries); err != nil {
+		return nil, fmt.Errorf("解析 podman 镜像列表失败: %v", err)
+	}
+
+	var images []Image
+	for _, summary := range summaries {
+		image := Image{ID: normalizeID(summary.ID), Created: time.Unix(summary.Created, 0), Size: summary.Size}
+		if len(summary.Names) == 0 {
+			images = append(images, image)
+			continue
+		}
+		for _, name := range summary.Names {
+			image.Ref = name
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+func (p *podmanRuntime) InspectImage(ref string) (*ImageInfo, error) {
+	if err := runTool(nil, nil, "podman", "image", "exists", ref); err != nil {
+		return nil, ErrImageNotFound
+	}
+	output, err := toolOutput("podman", "image", "inspect", "--format", "{{.Id}} {{.Architecture}}", ref)
+	if err != nil {
+		return nil, err
+	}
+	id, arch, _ := strings.Cut(strings.TrimSpace(output), " ")
+	return &ImageInfo{ID: normalizeID(id), Architecture: arch}, nil
+}
+
+func (p *podmanRuntime) SaveImages(w io.Writer, refs []string) error {
+	// 多个镜像需要 -m 才能写入同一个 docker-archive
+	args := []string{"save", "--format", "docker-archive"}
+	if len(refs) > 1 {
+		args = append(args, "-m")
+	}
+	return runTool(nil, w, "podman", append(args, refs...)...)
+}
+
+func (p *podmanRuntime) LoadImages(r io.Reader, out io.Writer) ([]string, error) {
+	var stdout strings.Builder
+	if err := runTool(r, &stdout, "podman", "load"); err != nil {
+		return nil, err
+	}
+	if out != nil {
+		io.WriteString(out, stdout.String())
+	}
+	return loadedImages(stdout.String()), nil
+}
+
+// PushImage 指定用户名时通过临时 --authfile 传递凭据，密码不出现在命令参数中
+func (p *podmanRuntime) PushImage(ref, username, password string, out io.Writer) error {
+	if username == "" {
+		return runTool(nil, out, "podman", "push", ref)
+	}
+	dir, err := tempAuthConfig(ref, username, password)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	return runTool(nil, out, "podman", "push", "--authfile", filepath.Join(dir, "config.json"), ref)
+}
+
+func (p *podmanRuntime) TagImage(source, target string) error {
+	return runTool(nil, nil, "podman", "tag", source, target)
+}
+
+func (p *podmanRuntime) RemoveTag(ref string) error {
+	return runTool(nil, nil, "podman", "untag", ref, ref)
+}
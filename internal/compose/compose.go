@@ -0,0 +1,376 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFiles 未指定 Compose 文件时在工作目录中依次查找的文件名
+var DefaultFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// Options 加载 Compose 项目的参数
+type Options struct {
+	Files      []string // Compose 文件，后面的覆盖前面的；为空时使用 COMPOSE_FILE 或 DefaultFiles 及其 override 文件
+	EnvFiles   []string // env 文件，为空时使用项目目录下的 .env
+	Profiles   []string // 启用的 profile，为空时使用 COMPOSE_PROFILES，* 表示全部
+	WorkingDir string   // 查找默认文件的目录，为空时使用当前目录
+}
+
+// Service Compose 项目中的服务
+type Service struct {
+	Name     string
+	Image    string
+	Build    bool // 是否定义了 build
+	Profiles []string
+}
+
+// Project 合并、插值并展开 extends 后的 Compose 项目
+type Project struct {
+	Name     string
+	Files    []string
+	Services []Service // 按名称排序，只包含启用的服务
+	Disabled []string  // 因 profile 未启用而忽略的服务
+}
+
+// Images 返回全部服务使用的镜像，去重并保持服务顺序
+func (p *Project) Images() []string {
+	var images []string
+	seen := make(map[string]bool)
+	for _, service := range p.Services {
+		if service.Image != "" && !seen[service.Image] {
+			seen[service.Image] = true
+			images = append(images, service.Image)
+		}
+	}
+	return images
+}
+
+// BuildOnly 返回只定义了 build 而没有 image 的服务
+func (p *Project) BuildOnly() []Service {
+	var services []Service
+	for _, service := range p.Services {
+		if service.Build && service.Image == "" {
+			services = append(services, service)
+		}
+	}
+	return services
+}
+
+// BuiltImageName 返回 docker compose 为没有 image 的服务构建的默认镜像名
+func (p *Project) BuiltImageName(service Service) string {
+	return p.Name + "-" + service.Name
+}
+
+// Load 按 Compose 规范加载项目：读取 env 文件，对每个文件做变量插值，按顺序合并，展开 extends 并按 profile 过滤服务。
+// YAML 锚点、别名和 << 合并键在解析时展开，x- 开头的扩展字段被忽略
+func Load(options Options) (*Project, error) {
+	workDir := options.WorkingDir
+	if workDir == "" {
+		workDir = "."
+	}
+	files := options.Files
+	projectDir := workDir
+	if len(files) > 0 {
+		projectDir = filepath.Dir(files[0])
+	}
+
+	env, err := LoadEnvironment(projectDir, options.EnvFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		if files, err = defaultFiles(workDir, env); err != nil {
+			return nil, err
+		}
+		projectDir = filepath.Dir(files[0])
+	}
+
+	profiles := options.Profiles
+	if len(profiles) == 0 && env["COMPOSE_PROFILES"] != "" {
+		profiles = strings.Split(env["COMPOSE_PROFILES"], ",")
+	}
+
+	var merged map[string]any
+	// extendsDirs 记录定义服务 extends 的文件所在目录，extends.file 相对于该目录
+	extendsDirs := make(map[string]string)
+	for _, file := range files {
+		doc, err := loadFile(file, env)
+		if err != nil {
+			return nil, err
+		}
+		for name, service := range mapValue(doc["services"]) {
+			if _, ok := mapValue(service)["extends"]; ok {
+				extendsDirs[name] = filepath.Dir(file)
+			}
+		}
+		merged = mergeMaps(merged, doc)
+	}
+
+	project := &Project{Name: projectName(merged, env, projectDir), Files: files}
+	services := mapValue(merged["services"])
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	loader := &extendsLoader{env: env}
+	for _, name := range names {
+		dir := extendsDirs[name]
+		if dir == "" {
+			dir = projectDir
+		}
+		config, err := loader.resolve(services, dir, name, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		service, err := parseService(name, config)
+		if err != nil {
+			return nil, err
+		}
+		if !profileEnabled(service.Profiles, profiles) {
+			project.Disabled = append(project.Disabled, name)
+			continue
+		}
+		project.Services = append(project.Services, service)
+	}
+	return project, nil
+}
+
+// defaultFiles 返回 COMPOSE_FILE 指定的文件，未设置时在目录中查找默认 Compose 文件及其 override 文件
+func defaultFiles(dir string, env Environment) ([]string, error) {
+	if value := env["COMPOSE_FILE"]; value != "" {
+		separator := env["COMPOSE_PATH_SEPARATOR"]
+		if separator == "" {
+			separator = string(os.PathListSeparator)
+		}
+		var files []string
+		for _, file := range strings.Split(value, separator) {
+			if file != "" {
+				files = append(files, joinPath(dir, file))
+			}
+		}
+		return files, nil
+	}
+
+	for _, name := range DefaultFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		files := []string{path}
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		for _, ext := range []string{".yaml", ".yml"} {
+			override := filepath.Join(dir, base+".override"+ext)
+			if _, err := os.Stat(override); err == nil {
+				files = append(files, override)
+				break
+			}
+		}
+		return files, nil
+	}
+	return nil, fmt.Errorf("%s 中未找到 Compose 文件（%s），请使用 -f 指定", dir, strings.Join(DefaultFiles, "、"))
+}
+
+// loadFile 读取 Compose 文件并替换其中的变量
+func loadFile(path string, env Environment) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", path, err)
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", path, err)
+	}
+	for key := range doc {
+		if strings.HasPrefix(key, "x-") {
+			delete(doc, key)
+		}
+	}
+	if _, err := interpolateValue(doc, env.Lookup); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+	return doc, nil
+}
+
+// projectName 项目名称：COMPOSE_PROJECT_NAME > 顶层 name > 项目目录名，转换为 compose 使用的小写形式
+func projectName(doc map[string]any, env Environment, projectDir string) string {
+	name := env["COMPOSE_PROJECT_NAME"]
+	if name == "" {
+		name, _ = doc["name"].(string)
+	}
+	if name == "" {
+		if abs, err := filepath.Abs(projectDir); err == nil {
+			name = filepath.Base(abs)
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, name)
+}
+
+// extendsLoader 展开服务的 extends，缓存已读取的外部文件
+type extendsLoader struct {
+	env   Environment
+	files map[string]map[string]any
+}
+
+// resolve 返回展开 extends 后的服务配置，dir 为 extends.file 的相对路径基准
+func (l *extendsLoader) resolve(services map[string]any, dir, name string, chain []string) (map[string]any, error) {
+	config, ok := services[name]
+	if !ok {
+		return nil, fmt.Errorf("服务 %s 不存在", name)
+	}
+	service := mapValue(config)
+	extends, ok := service["extends"]
+	if !ok {
+		return service, nil
+	}
+
+	key := filepath.Join(dir, name)
+	for _, visited := range chain {
+		if visited == key {
+			return nil, fmt.Errorf("服务 %s 的 extends 存在循环引用", name)
+		}
+	}
+	chain = append(chain, key)
+
+	var baseName, baseFile string
+	switch v := extends.(type) {
+	case string:
+		baseName = v
+	case map[string]any:
+		baseName, _ = v["service"].(string)
+		baseFile, _ = v["file"].(string)
+	}
+	if baseName == "" {
+		return nil, fmt.Errorf("服务 %s 的 extends 缺少 service", name)
+	}
+
+	baseServices, baseDir := services, dir
+	if baseFile != "" {
+		path := joinPath(dir, baseFile)
+		doc, err := l.load(path)
+		if err != nil {
+			return nil, err
+		}
+		baseServices, baseDir = mapValue(doc["services"]), filepath.Dir(path)
+	}
+	base, err := l.resolve(baseServices, baseDir, baseName, chain)
+	if err != nil {
+		return nil, fmt.Errorf("服务 %s 继承 %s 失败: %v", name, baseName, err)
+	}
+
+	override := make(map[string]any, len(service))
+	for key, value := range service {
+		if key != "extends" {
+			override[key] = value
+		}
+	}
+	return mergeMaps(base, override), nil
+}
+
+// load 读取 extends 引用的文件，同一文件只读取一次
+func (l *extendsLoader) load(path string) (map[string]any, error) {
+	if doc, ok := l.files[path]; ok {
+		return doc, nil
+	}
+	doc, err := loadFile(path, l.env)
+	if err != nil {
+		return nil, err
+	}
+	if l.files == nil {
+		l.files = make(map[string]map[string]any)
+	}
+	l.files[path] = doc
+	return doc, nil
+}
+
+// parseService 读取服务中与镜像相关的字段
+func parseService(name string, config map[string]any) (Service, error) {
+	service := Service{Name: name}
+	if image, ok := config["image"]; ok && image != nil {
+		value, ok := image.(string)
+		if !ok {
+			return service, fmt.Errorf("服务 %s 的 image 必须是字符串", name)
+		}
+		service.Image = strings.TrimSpace(value)
+	}
+	if build, ok := config["build"]; ok && build != nil {
+		service.Build = true
+	}
+	if profiles, ok := config["profiles"].([]any); ok {
+		for _, profile := range profiles {
+			service.Profiles = append(service.Profiles, fmt.Sprint(profile))
+		}
+	}
+	return service, nil
+}
+
+// profileEnabled 没有 profile 的服务总是启用，否则需要启用其中之一
+func profileEnabled(serviceProfiles, active []string) bool {
+	if len(serviceProfiles) == 0 {
+		return true
+	}
+	for _, profile := range active {
+		profile = strings.TrimSpace(profile)
+		if profile == "*" {
+			return true
+		}
+		for _, p := range serviceProfiles {
+			if p == profile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeMaps 返回 override 覆盖 base 后的新映射，嵌套映射递归合并，其他值整体替换
+func mergeMaps(base, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(base)+len(override))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range override {
+		baseMap, baseOK := merged[key].(map[string]any)
+		overrideMap, overrideOK := value.(map[string]any)
+		if baseOK && overrideOK {
+			merged[key] = mergeMaps(baseMap, overrideMap)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// mapValue 将 YAML 值转换为映射，不是映射时返回 nil
+func mapValue(value any) map[string]any {
+	m, _ := value.(map[string]any)
+	return m
+}
+
+// joinPath 拼接相对路径，绝对路径保持不变
+func joinPath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,213 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string // 相对于临时目录的路径 -> 内容
+		args         []string          // -f 指定的文件，为空时在临时目录中查找默认文件
+		profiles     []string
+		env          map[string]string
+		wantName     string
+		wantImages   []string
+		wantDisabled []string
+		wantErr      string
+	}{
+		{
+			name: "多个 -f 文件合并",
+			files: map[string]string{
+				"compose.yaml": "name: Demo_App\nservices:\n  web:\n    image: nginx:1.24\n    ports: [\"80:80\"]\n  db:\n    image: postgres:15\n",
+				"prod.yaml":    "services:\n  web:\n    image: nginx:1.25\n  cache:\n    image: redis:7\n",
+			},
+			args:       []string{"compose.yaml", "prod.yaml"},
+			wantName:   "demo_app",
+			wantImages: []string{"redis:7", "postgres:15", "nginx:1.25"},
+		},
+		{
+			name: "默认文件与 override 文件",
+			files: map[string]string{
+				"compose.yaml":          "services:\n  web:\n    image: nginx:1.24\n",
+				"compose.override.yaml": "services:\n  web:\n    image: nginx:1.25\n",
+			},
+			wantImages: []string{"nginx:1.25"},
+		},
+		{
+			name: "extends 同一文件中的服务",
+			files: map[string]string{
+				"compose.yaml": "services:\n  base:\n    image: app:1\n  api:\n    extends: base\n  worker:\n    extends:\n      service: base\n    image: app:2\n",
+			},
+			args:       []string{"compose.yaml"},
+			wantImages: []string{"app:1", "app:2"},
+		},
+		{
+			name: "extends 的文件相对于定义它的文件",
+			files: map[string]string{
+				"compose.yaml":      "services:\n  api:\n    extends:\n      file: common/base.yaml\n      service: base\n",
+				"common/base.yaml":  "services:\n  base:\n    extends:\n      file: root.yaml\n      service: root\n",
+				"common/root.yaml":  "services:\n  root:\n    image: alpine:3.19\n",
+				"root.yaml":         "services:\n  root:\n    image: wrong:1\n",
+				"ops/override.yaml": "services:\n  job:\n    extends:\n      file: job.yaml\n      service: job\n",
+				"ops/job.yaml":      "services:\n  job:\n    image: job:1\n",
+			},
+			args:       []string{"compose.yaml", "ops/override.yaml"},
+			wantImages: []string{"alpine:3.19", "job:1"},
+		},
+		{
+			name: "-f 合并后的配置覆盖 extends 的基础服务",
+			files: map[string]string{
+				"compose.yaml":  "services:\n  api:\n    extends:\n      file: common.yaml\n      service: base\n",
+				"common.yaml":   "services:\n  base:\n    image: app:${APP_TAG:-dev}\n",
+				"override.yaml": "services:\n  api:\n    image: app:2\n",
+			},
+			args:       []string{"compose.yaml", "override.yaml"},
+			wantImages: []string{"app:2"},
+		},
+		{
+			name: "extends 中的变量插值",
+			files: map[string]string{
+				"compose.yaml": "services:\n  api:\n    extends:\n      file: common.yaml\n      service: base\n",
+				"common.yaml":  "services:\n  base:\n    image: app:${APP_TAG:-dev}\n",
+			},
+			args:       []string{"compose.yaml"},
+			env:        map[string]string{"APP_TAG": "1.2"},
+			wantImages: []string{"app:1.2"},
+		},
+		{
+			name: ".env 中的变量与嵌套默认值",
+			files: map[string]string{
+				".env":         "REGISTRY=\"registry.local:5000\"\nTAG='1.25'\n",
+				"compose.yaml": "services:\n  web:\n    image: ${REGISTRY}/nginx:${TAG:-latest}\n  db:\n    image: postgres:${PG_TAG:-${PG_MAJOR:-16}}\n",
+			},
+			args:       []string{"compose.yaml"},
+			wantImages: []string{"postgres:16", "registry.local:5000/nginx:1.25"},
+		},
+		{
+			name: "YAML 锚点与 x- 扩展字段",
+			files: map[string]string{
+				"compose.yaml": "x-base: &base\n  image: busybox:1.36\n  restart: always\nservices:\n  one:\n    <<: *base\n  two:\n    <<: *base\n    image: busybox:1.37\n",
+			},
+			args:       []string{"compose.yaml"},
+			wantImages: []string{"busybox:1.36", "busybox:1.37"},
+		},
+		{
+			name: "未启用的 profile",
+			files: map[string]string{
+				"compose.yaml": "services:\n  web:\n    image: nginx\n  debug:\n    image: busybox\n    profiles: [debug]\n",
+			},
+			args:         []string{"compose.yaml"},
+			wantImages:   []string{"nginx"},
+			wantDisabled: []string{"debug"},
+		},
+		{
+			name: "启用 profile",
+			files: map[string]string{
+				"compose.yaml": "services:\n  web:\n    image: nginx\n  debug:\n    image: busybox\n    profiles: [debug]\n",
+			},
+			args:       []string{"compose.yaml"},
+			profiles:   []string{"debug"},
+			wantImages: []string{"busybox", "nginx"},
+		},
+		{
+			name: "缺少必需变量",
+			files: map[string]string{
+				"compose.yaml": "services:\n  web:\n    image: nginx:${DOCKEROPS_TEST_MISSING:?请设置镜像版本}\n",
+			},
+			args:    []string{"compose.yaml"},
+			wantErr: "请设置镜像版本",
+		},
+		{
+			name: "extends 循环引用",
+			files: map[string]string{
+				"compose.yaml": "services:\n  a:\n    extends: b\n  b:\n    extends: a\n",
+			},
+			args:    []string{"compose.yaml"},
+			wantErr: "循环引用",
+		},
+		{
+			name: "extends 的服务不存在",
+			files: map[string]string{
+				"compose.yaml": "services:\n  a:\n    extends:\n      file: common.yaml\n      service: missing\n",
+				"common.yaml":  "services:\n  base:\n    image: app\n",
+			},
+			args:    []string{"compose.yaml"},
+			wantErr: "服务 missing 不存在",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"COMPOSE_FILE", "COMPOSE_PROFILES", "COMPOSE_PROJECT_NAME"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			options := Options{Profiles: tt.profiles, WorkingDir: dir}
+			for _, name := range tt.args {
+				options.Files = append(options.Files, filepath.Join(dir, name))
+			}
+
+			project, err := Load(options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if tt.wantName != "" && project.Name != tt.wantName {
+				t.Errorf("项目名称 = %q，期望 %q", project.Name, tt.wantName)
+			}
+			if got := project.Images(); !reflect.DeepEqual(got, tt.wantImages) {
+				t.Errorf("Images() = %v，期望 %v", got, tt.wantImages)
+			}
+			if !reflect.DeepEqual(project.Disabled, tt.wantDisabled) {
+				t.Errorf("Disabled = %v，期望 %v", project.Disabled, tt.wantDisabled)
+			}
+		})
+	}
+}
+
+func TestBuildOnly(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "compose.yaml")
+	content := "name: My.Project\nservices:\n  app:\n    build: .\n  tagged:\n    build: .\n    image: app:1\n  db:\n    image: postgres:16\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+
+	project, err := Load(Options{Files: []string{file}})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	services := project.BuildOnly()
+	if len(services) != 1 || services[0].Name != "app" {
+		t.Fatalf("BuildOnly() = %v，期望只有 app", services)
+	}
+	if got := project.BuiltImageName(services[0]); got != "myproject-app" {
+		t.Errorf("BuiltImageName = %q，期望 myproject-app", got)
+	}
+	if got := project.Images(); !reflect.DeepEqual(got, []string{"postgres:16", "app:1"}) {
+		t.Errorf("Images() = %v", got)
+	}
+}
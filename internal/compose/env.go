@@ -0,0 +1,141 @@
+package compose
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment Compose 文件插值使用的变量：环境变量优先于 env 文件中的值
+type Environment map[string]string
+
+// Lookup 查询变量值
+func (e Environment) Lookup(name string) (string, bool) {
+	value, ok := e[name]
+	return value, ok
+}
+
+// LoadEnvironment 读取 env 文件并合并当前进程的环境变量；envFiles 为空时读取 projectDir 下的 .env（不存在时忽略），
+// 多个 env 文件按顺序读取，后面的覆盖前面的
+func LoadEnvironment(projectDir string, envFiles []string) (Environment, error) {
+	env := make(Environment)
+	if len(envFiles) == 0 {
+		defaultFile := joinPath(projectDir, ".env")
+		if _, err := os.Stat(defaultFile); err == nil {
+			envFiles = []string{defaultFile}
+		}
+	}
+
+	for _, path := range envFiles {
+		values, err := readEnvFile(path, env)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			env[key] = value
+		}
+	}
+
+	for _, item := range os.Environ() {
+		if key, value, ok := strings.Cut(item, "="); ok {
+			env[key] = value
+		}
+	}
+	return env, nil
+}
+
+// readEnvFile 解析 env 文件：KEY=VALUE 每行一个，支持 # 注释、export 前缀、单双引号，
+// 未加单引号的值可以引用之前定义的变量
+func readEnvFile(path string, defined Environment) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 env 文件失败: %v", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		if value, ok := values[name]; ok {
+			return value, true
+		}
+		return defined.Lookup(name)
+	}
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s 第 %d 行格式错误: %s", path, lineNo, line)
+		}
+		raw = strings.TrimSpace(raw)
+
+		var value string
+		switch {
+		case strings.HasPrefix(raw, "'"):
+			end := strings.Index(raw[1:], "'")
+			if end == -1 {
+				return nil, fmt.Errorf("%s 第 %d 行缺少结束的单引号", path, lineNo)
+			}
+			values[key] = raw[1 : end+1]
+			continue
+		case strings.HasPrefix(raw, `"`):
+			unquoted, ok := unquoteDouble(raw[1:])
+			if !ok {
+				return nil, fmt.Errorf("%s 第 %d 行缺少结束的双引号", path, lineNo)
+			}
+			value = unquoted
+		default:
+			// 未加引号的值中，空白后的 # 开始注释
+			if i := strings.Index(raw, " #"); i != -1 {
+				raw = raw[:i]
+			}
+			value = strings.TrimSpace(raw)
+		}
+
+		if value, err = Interpolate(value, lookup); err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行: %v", path, lineNo, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 env 文件失败: %v", err)
+	}
+	return values, nil
+}
+
+// unquoteDouble 读取双引号中的内容（s 不含开头的引号），处理 \n、\t、\"、\\ 转义
+func unquoteDouble(s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), true
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(s[i])
+				}
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return "", false
+}
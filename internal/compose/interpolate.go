@@ -0,0 +1,159 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Interpolate 按 Compose 规范替换字符串中的变量：$VAR、${VAR}、${VAR:-默认值}、${VAR-默认值}、
+// ${VAR:?错误}、${VAR?错误}、${VAR:+替换值}、${VAR+替换值}，$$ 表示字面量 $；默认值中可以嵌套变量。
+// lookup 查询变量值，变量未设置时返回 false
+func Interpolate(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		next := s[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := closingBrace(s, i+2)
+			if end == -1 {
+				return "", fmt.Errorf("变量表达式缺少 }: %s", s[i:])
+			}
+			value, err := expandBraced(s[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			value, _ := lookup(s[i+1 : j])
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), nil
+}
+
+// expandBraced 展开 ${...} 中的表达式
+func expandBraced(expr string, lookup func(string) (string, bool)) (string, error) {
+	j := 0
+	for j < len(expr) && isNameChar(expr[j]) {
+		j++
+	}
+	name, op := expr[:j], expr[j:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", fmt.Errorf("无效的变量表达式: ${%s}", expr)
+	}
+
+	value, ok := lookup(name)
+	if op == "" {
+		return value, nil
+	}
+
+	// 带冒号的运算符把空字符串视为未设置
+	set := ok
+	if strings.HasPrefix(op, ":") {
+		set = ok && value != ""
+		op = op[1:]
+	}
+	if op == "" {
+		return "", fmt.Errorf("无效的变量表达式: ${%s}", expr)
+	}
+
+	arg, err := Interpolate(op[1:], lookup)
+	if err != nil {
+		return "", err
+	}
+	switch op[0] {
+	case '-':
+		if set {
+			return value, nil
+		}
+		return arg, nil
+	case '?':
+		if set {
+			return value, nil
+		}
+		if arg == "" {
+			arg = "未设置"
+		}
+		return "", fmt.Errorf("缺少必需的变量 %s: %s", name, arg)
+	case '+':
+		if set {
+			return arg, nil
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("无效的变量表达式: ${%s}", expr)
+	}
+}
+
+// closingBrace 返回与 start 之前的 { 匹配的 } 位置，没有时返回 -1
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
+
+// interpolateValue 递归替换 YAML 数据中全部字符串值里的变量
+func interpolateValue(value any, lookup func(string) (string, bool)) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return Interpolate(v, lookup)
+	case map[string]any:
+		for key, item := range v {
+			interpolated, err := interpolateValue(item, lookup)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			v[key] = interpolated
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			interpolated, err := interpolateValue(item, lookup)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = interpolated
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
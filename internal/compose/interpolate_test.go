@@ -0,0 +1,172 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string]string{
+		"TAG":   "1.25",
+		"EMPTY": "",
+		"NAME":  "web",
+		"INNER": "inner",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{"无变量", "nginx:1.25", "nginx:1.25", ""},
+		{"简单变量", "nginx:$TAG", "nginx:1.25", ""},
+		{"花括号变量", "nginx:${TAG}-alpine", "nginx:1.25-alpine", ""},
+		{"变量后接字符", "$NAME/app", "web/app", ""},
+		{"未设置的变量为空", "nginx:${MISSING}", "nginx:", ""},
+		{"$$ 表示字面量", "echo $$HOME", "echo $HOME", ""},
+		{"$$ 后接花括号", "$${TAG}", "${TAG}", ""},
+		{"$ 后不是变量名", "cost $5 and $", "cost $5 and $", ""},
+		{":- 未设置", "${MISSING:-1.24}", "1.24", ""},
+		{":- 为空", "${EMPTY:-1.24}", "1.24", ""},
+		{":- 已设置", "${TAG:-1.24}", "1.25", ""},
+		{"- 为空时保留空值", "${EMPTY-1.24}", "", ""},
+		{"- 未设置", "${MISSING-1.24}", "1.24", ""},
+		{"默认值中嵌套变量", "${MISSING:-${INNER}}", "inner", ""},
+		{"多层嵌套默认值", "${MISSING:-${ALSO_MISSING:-deep}}", "deep", ""},
+		{"嵌套默认值中的 $$", "${MISSING:-$$literal}", "$literal", ""},
+		{":? 已设置", "${TAG:?需要 TAG}", "1.25", ""},
+		{":? 未设置", "${MISSING:?需要 MISSING}", "", "需要 MISSING"},
+		{":? 为空", "${EMPTY:?不能为空}", "", "不能为空"},
+		{":? 没有错误信息", "${MISSING:?}", "", "缺少必需的变量 MISSING"},
+		{"? 为空时通过", "${EMPTY?需要}", "", ""},
+		{"? 未设置", "${MISSING?需要}", "", "需要"},
+		{":+ 已设置", "${TAG:+set}", "set", ""},
+		{":+ 为空", "${EMPTY:+set}", "", ""},
+		{"+ 为空", "${EMPTY+set}", "set", ""},
+		{"缺少右花括号", "${TAG", "", "缺少 }"},
+		{"无效变量名", "${1TAG}", "", "无效的变量表达式"},
+		{"无效运算符", "${TAG!x}", "", "无效的变量表达式"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Interpolate(tt.input, lookup)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Interpolate(%q) 期望错误包含 %q，实际 %v", tt.input, tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Interpolate(%q): %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Interpolate(%q) = %q，期望 %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "未加引号",
+			content: "# 注释\n\nDOCKEROPS_A=plain\nexport DOCKEROPS_B=exported\nDOCKEROPS_C = spaced \n",
+			want:    map[string]string{"DOCKEROPS_A": "plain", "DOCKEROPS_B": "exported", "DOCKEROPS_C": "spaced"},
+		},
+		{
+			name:    "行尾注释",
+			content: "DOCKEROPS_A=value # 注释\nDOCKEROPS_B=a#b\n",
+			want:    map[string]string{"DOCKEROPS_A": "value", "DOCKEROPS_B": "a#b"},
+		},
+		{
+			name:    "双引号与转义",
+			content: `DOCKEROPS_A="with # hash"` + "\n" + `DOCKEROPS_B="say \"hi\"\n\tnext"` + "\n" + `DOCKEROPS_C="back\\slash" # 注释` + "\n",
+			want:    map[string]string{"DOCKEROPS_A": "with # hash", "DOCKEROPS_B": "say \"hi\"\n\tnext", "DOCKEROPS_C": `back\slash`},
+		},
+		{
+			name:    "单引号不转义也不插值",
+			content: "DOCKEROPS_A=one\nDOCKEROPS_B='$DOCKEROPS_A \\n ${X:-y}'\n",
+			want:    map[string]string{"DOCKEROPS_A": "one", "DOCKEROPS_B": `$DOCKEROPS_A \n ${X:-y}`},
+		},
+		{
+			name:    "引用之前定义的变量",
+			content: "DOCKEROPS_A=1.25\nDOCKEROPS_B=nginx:${DOCKEROPS_A}\nDOCKEROPS_C=\"${DOCKEROPS_MISSING:-default}\"\n",
+			want:    map[string]string{"DOCKEROPS_A": "1.25", "DOCKEROPS_B": "nginx:1.25", "DOCKEROPS_C": "default"},
+		},
+		{
+			name:    "空值",
+			content: "DOCKEROPS_A=\nDOCKEROPS_B=\"\"\n",
+			want:    map[string]string{"DOCKEROPS_A": "", "DOCKEROPS_B": ""},
+		},
+		{name: "缺少结束的双引号", content: "DOCKEROPS_A=\"open\n", wantErr: "双引号"},
+		{name: "缺少结束的单引号", content: "DOCKEROPS_A='open\n", wantErr: "单引号"},
+		{name: "缺少等号", content: "DOCKEROPS_A\n", wantErr: "格式错误"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readEnvFile(path, Environment{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readEnvFile: %v", err)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("%s = %q，期望 %q", key, got[key], want)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("解析出 %d 个变量，期望 %d 个: %v", len(got), len(tt.want), got)
+			}
+		})
+	}
+}
+
+func TestLoadEnvironmentPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.env")
+	second := filepath.Join(dir, "second.env")
+	if err := os.WriteFile(first, []byte("DOCKEROPS_A=first\nDOCKEROPS_B=first\nDOCKEROPS_C=first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("DOCKEROPS_B=second\nDOCKEROPS_D=${DOCKEROPS_A}-second\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKEROPS_C", "process")
+
+	env, err := LoadEnvironment(dir, []string{first, second})
+	if err != nil {
+		t.Fatalf("LoadEnvironment: %v", err)
+	}
+	want := map[string]string{
+		"DOCKEROPS_A": "first",
+		"DOCKEROPS_B": "second",
+		"DOCKEROPS_C": "process",
+		"DOCKEROPS_D": "first-second",
+	}
+	for key, value := range want {
+		if env[key] != value {
+			t.Errorf("%s = %q，期望 %q", key, env[key], value)
+		}
+	}
+}
@@ -40,15 +40,21 @@ type TagTransform struct {
 
 // Settings 全局设置
 type Settings struct {
-	MaxConcurrentRegistries int    `json:"max_concurrent_registries"`
-	RetryCount              int    `json:"retry_count"`
-	RemoveRegistryPrefix    bool   `json:"remove_registry_prefix"`
-	DefaultArchitecture     string `json:"default_architecture"`
-	DownloadTimeout         int    `json:"download_timeout"`
-	EnableProgressBar       bool   `json:"enable_progress_bar"`
-	CleanupTempFiles        bool   `json:"cleanup_temp_files"`
-	EnableAdvancedAPI       bool   `json:"enable_advanced_api"`
-	AdvancedAPIURL          string `json:"advanced_api_url"`
+	MaxConcurrentRegistries int      `json:"max_concurrent_registries"`
+	RetryCount              int      `json:"retry_count"`
+	RemoveRegistryPrefix    bool     `json:"remove_registry_prefix"`
+	DefaultArchitecture     string   `json:"default_architecture"`
+	DownloadTimeout         int      `json:"download_timeout"`
+	EnableProgressBar       bool     `json:"enable_progress_bar"`
+	CleanupTempFiles        bool     `json:"cleanup_temp_files"`
+	EnableAdvancedAPI       bool     `json:"enable_advanced_api"`
+	AdvancedAPIURL          string   `json:"advanced_api_url"`
+	OutputDir               string   `json:"output_dir,omitempty"`
+	FilenameTemplate        string   `json:"filename_template,omitempty"`
+	TagMode                 string   `json:"tag_mode,omitempty"`
+	BlobCacheDir            string   `json:"blob_cache_dir,omitempty"`
+	Runtime                 string   `json:"runtime,omitempty"`
+	TrustedKeys             []string `json:"trusted_keys,omitempty"`
 }
 
 // Config 主配置结构
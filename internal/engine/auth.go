@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// dockerHubAuthKey docker login 保存 Docker Hub 凭据使用的键
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// authConfig X-Registry-Auth 头中的认证信息
+type authConfig struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+}
+
+// registryAuthHeader 生成 X-Registry-Auth 头，指定了用户名时直接使用，否则按 docker login 保存的凭据
+// 依次查找 credHelpers、credsStore 和 auths，没有凭据时返回空认证
+func registryAuthHeader(registry, username, password string) (string, error) {
+	server := registry
+	if registry == "docker.io" {
+		server = dockerHubAuthKey
+	}
+
+	auth := authConfig{ServerAddress: server}
+	cfg, err := loadDockerConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if username != "" {
+		auth.Username, auth.Password = username, password
+	} else if helper := cfg.CredHelpers[registry]; helper != "" {
+		if err := credentialHelperAuth(helper, server, &auth); err != nil {
+			return "", err
+		}
+	} else if cfg.CredsStore != "" {
+		if err := credentialHelperAuth(cfg.CredsStore, server, &auth); err != nil {
+			return "", err
+		}
+	} else if entry, ok := lookupAuthEntry(cfg.Auths, registry, server); ok {
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return "", fmt.Errorf("解析 %s 的登录信息失败: %v", registry, err)
+			}
+			auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		auth.IdentityToken = entry.IdentityToken
+	}
+
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// lookupAuthEntry 在 auths 中查找仓库凭据，兼容带 https:// 前缀的键
+func lookupAuthEntry(auths map[string]dockerAuthEntry, registry, server string) (dockerAuthEntry, bool) {
+	for _, key := range []string{server, registry, "https://" + registry, "http://" + registry} {
+		if entry, ok := auths[key]; ok {
+			return entry, true
+		}
+	}
+	return dockerAuthEntry{}, false
+}
+
+// credentialHelperAuth 调用 docker-credential-<helper> get 获取凭据，
+// 凭据不存在时保持空认证
+func credentialHelperAuth(helper, server string, auth *authConfig) error {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(message, "credentials not found") {
+			return nil
+		}
+		return fmt.Errorf("调用凭据助手 docker-credential-%s 失败: %v %s", helper, err, message)
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return fmt.Errorf("解析凭据助手 docker-credential-%s 的输出失败: %v", helper, err)
+	}
+	// 凭据助手用 <token> 作为用户名表示保存的是身份令牌
+	if creds.Username == "<token>" {
+		auth.IdentityToken = creds.Secret
+	} else {
+		auth.Username, auth.Password = creds.Username, creds.Secret
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxAPIVersion 客户端支持的最高 Engine API 版本，服务端更低时使用服务端版本
+const maxAPIVersion = "1.41"
+
+// Client Docker Engine API 客户端，通过 unix socket 或 TCP 直接访问 dockerd，不依赖 docker 命令
+type Client struct {
+	host       string // 连接地址，例如 unix:///var/run/docker.sock
+	baseURL    string
+	httpClient *http.Client
+	version    string // 协商后的 API 版本
+}
+
+// APIError Engine API 返回的错误
+type APIError struct {
+	Op         string // 操作，例如 推送镜像
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Docker 返回错误（%d）: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound 判断错误是否为对象不存在
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ErrNamedPipe 连接地址为 Windows 命名管道；内置客户端不支持，调用方可以改用 docker 命令
+var ErrNamedPipe = errors.New("内置 Engine API 客户端不支持 Windows 命名管道")
+
+// NewClient 根据 DOCKER_HOST、docker context 或默认 socket 创建客户端，并协商 API 版本
+func NewClient() (*Client, error) {
+	host, err := ResolveHost()
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithHost(host)
+}
+
+// NewClientWithHost 使用指定的连接地址创建客户端
+func NewClientWithHost(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 Docker 地址 %s: %v", host, err)
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:    10,
+		IdleConnTimeout: 30 * time.Second,
+	}
+	client := &Client{host: host, httpClient: &http.Client{Transport: transport}}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		client.baseURL = "http://docker"
+	case "tcp", "http", "https":
+		scheme := "http"
+		if u.Scheme == "https" || os.Getenv("DOCKER_TLS_VERIFY") != "" {
+			tlsConfig, err := loadTLSConfig()
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig = tlsConfig
+			scheme = "https"
+		}
+		client.baseURL = scheme + "://" + u.Host
+	case "npipe":
+		return nil, fmt.Errorf("%w: %s", ErrNamedPipe, host)
+	default:
+		return nil, fmt.Errorf("不支持的 Docker 地址 %s（支持 unix://、tcp://）", host)
+	}
+
+	if err := client.negotiate(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// loadTLSConfig 按 DOCKER_CERT_PATH 加载 TLS 证书，与 docker 命令的约定一致
+func loadTLSConfig() (*tls.Config, error) {
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		certPath = configDir()
+	}
+
+	tlsConfig := &tls.Config{}
+	if ca, err := os.ReadFile(filepath.Join(certPath, "ca.pem")); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		tlsConfig.RootCAs = pool
+	}
+	certFile, keyFile := filepath.Join(certPath, "cert.pem"), filepath.Join(certPath, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载 Docker TLS 证书失败: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Host 返回客户端的连接地址
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Version 返回协商后的 API 版本
+func (c *Client) Version() string {
+	return c.version
+}
+
+// negotiate 通过 /_ping 确认 dockerd 可访问，并选择双方都支持的 API 版本
+func (c *Client) negotiate() error {
+	resp, err := c.httpClient.Get(c.baseURL + "/_ping")
+	if err != nil {
+		return fmt.Errorf("无法连接 Docker (%s): %v", c.host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("无法连接 Docker (%s)，状态码: %d", c.host, resp.StatusCode)
+	}
+
+	c.version = maxAPIVersion
+	if version := os.Getenv("DOCKER_API_VERSION"); version != "" {
+		c.version = version
+	} else if server := resp.Header.Get("Api-Version"); server != "" && compareVersions(server, maxAPIVersion) < 0 {
+		c.version = server
+	}
+	return nil
+}
+
+// compareVersions 比较形如 1.41 的 API 版本
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// do 发送带版本前缀的 API 请求，非 2xx 响应转换为 APIError
+func (c *Client) do(op, method, path string, query url.Values, body io.Reader, headers map[string]string) (*http.Response, error) {
+	rawURL := fmt.Sprintf("%s/v%s%s", c.baseURL, c.version, path)
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Docker 失败: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(op, resp)
+	}
+	return resp, nil
+}
+
+// responseError 从错误响应的 {"message": "..."} 中提取错误信息
+func responseError(op string, resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Message string `json:"message"`
+	}
+	message := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		message = body.Message
+	}
+	return &APIError{Op: op, StatusCode: resp.StatusCode, Message: message}
+}
+
+// decodeJSON 发送请求并解析 JSON 响应
+func (c *Client) decodeJSON(op, method, path string, query url.Values, v any) error {
+	resp, err := c.do(op, method, path, query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s时解析响应失败: %v", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// 默认的 dockerd 地址
+const (
+	defaultUnixSocket = "/var/run/docker.sock"
+	defaultNamedPipe  = "npipe:////./pipe/docker_engine"
+)
+
+// configDir 返回 docker 客户端配置目录，优先使用 DOCKER_CONFIG
+func configDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".docker"
+	}
+	return filepath.Join(home, ".docker")
+}
+
+// dockerConfigFile ~/.docker/config.json 中用到的字段
+type dockerConfigFile struct {
+	CurrentContext string                     `json:"currentContext"`
+	Auths          map[string]dockerAuthEntry `json:"auths"`
+	CredsStore     string                     `json:"credsStore"`
+	CredHelpers    map[string]string          `json:"credHelpers"`
+}
+
+// dockerAuthEntry config.json 中单个仓库的登录信息
+type dockerAuthEntry struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// loadDockerConfig 读取 docker 客户端配置，文件不存在时返回空配置
+func loadDockerConfig() (*dockerConfigFile, error) {
+	var cfg dockerConfigFile
+	data, err := os.ReadFile(filepath.Join(configDir(), "config.json"))
+	if os.IsNotExist(err) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析 docker 配置文件失败: %v", err)
+	}
+	return &cfg, nil
+}
+
+// ResolveHost 按 docker 命令的规则确定 dockerd 地址：
+// DOCKER_HOST > DOCKER_CONTEXT > config.json 中的 currentContext > 默认 socket（含 rootless socket）
+func ResolveHost() (string, error) {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host, nil
+	}
+
+	name := os.Getenv("DOCKER_CONTEXT")
+	if name == "" {
+		if cfg, err := loadDockerConfig(); err == nil {
+			name = cfg.CurrentContext
+		}
+	}
+	if name != "" && name != "default" {
+		return contextHost(name)
+	}
+
+	if runtime.GOOS == "windows" {
+		return defaultNamedPipe, nil
+	}
+	if _, err := os.Stat(defaultUnixSocket); err == nil {
+		return "unix://" + defaultUnixSocket, nil
+	}
+	// rootless 模式的 dockerd 监听在 $XDG_RUNTIME_DIR/docker.sock
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		socket := filepath.Join(dir, "docker.sock")
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket, nil
+		}
+	}
+	return "unix://" + defaultUnixSocket, nil
+}
+
+// contextHost 读取 docker context 元数据中的 dockerd 地址，
+// 元数据保存在 contexts/meta/<sha256(名称)>/meta.json
+func contextHost(name string) (string, error) {
+	sum := sha256.Sum256([]byte(name))
+	metaPath := filepath.Join(configDir(), "contexts", "meta", hex.EncodeToString(sum[:]), "meta.json")
+
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return "", fmt.Errorf("读取 docker context %s 失败: %v", name, err)
+	}
+	var meta struct {
+		Endpoints map[string]struct {
+			Host string `json:"Host"`
+		} `json:"Endpoints"`
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", fmt.Errorf("解析 docker context %s 失败: %v", name, err)
+	}
+	host := meta.Endpoints["docker"].Host
+	if host == "" {
+		return "", fmt.Errorf("docker context %s 中没有 dockerd 地址", name)
+	}
+	return host, nil
+}
@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"io"
+	"net/url"
+	"strings"
+)
+
+// ImageSummary 本地镜像列表中的一项
+type ImageSummary struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	Size     int64    `json:"Size"`
+	Created  int64    `json:"Created"`
+}
+
+// ImageInspect 本地镜像详情中用到的字段
+type ImageInspect struct {
+	ID           string   `json:"Id"`
+	RepoTags     []string `json:"RepoTags"`
+	RepoDigests  []string `json:"RepoDigests"`
+	Architecture string   `json:"Architecture"`
+	Variant      string   `json:"Variant"`
+	Os           string   `json:"Os"`
+	Size         int64    `json:"Size"`
+}
+
+// ListImages 列出本地全部镜像
+func (c *Client) ListImages() ([]ImageSummary, error) {
+	var images []ImageSummary
+	if err := c.decodeJSON("列出镜像", "GET", "/images/json", nil, &images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// InspectImage 查询本地镜像详情，镜像不存在时返回的错误满足 IsNotFound
+func (c *Client) InspectImage(ref string) (*ImageInspect, error) {
+	var image ImageInspect
+	if err := c.decodeJSON("查询镜像", "GET", "/images/"+ref+"/json", nil, &image); err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// SaveImages 将一个或多个镜像导出为 tar 流，调用方负责关闭
+func (c *Client) SaveImages(refs []string) (io.ReadCloser, error) {
+	query := url.Values{}
+	for _, ref := range refs {
+		query.Add("names", ref)
+	}
+	resp, err := c.do("导出镜像", "GET", "/images/get", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// LoadImage 从 tar 流导入镜像，进度写入 out（可为 nil），返回导入的镜像引用
+func (c *Client) LoadImage(r io.Reader, out io.Writer) ([]string, error) {
+	query := url.Values{"quiet": {"0"}}
+	resp, err := c.do("导入镜像", "POST", "/images/load", query, r, map[string]string{"Content-Type": "application/x-tar"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var loaded []string
+	err = DisplayJSONMessages(resp.Body, out, func(msg JSONMessage) {
+		stream := strings.TrimSpace(msg.Stream)
+		for _, prefix := range []string{"Loaded image: ", "Loaded image ID: "} {
+			if strings.HasPrefix(stream, prefix) {
+				loaded = append(loaded, strings.TrimPrefix(stream, prefix))
+			}
+		}
+	})
+	return loaded, err
+}
+
+// TagImage 为本地镜像添加新的引用
+func (c *Client) TagImage(source, target string) error {
+	repo, tag := splitReference(target)
+	query := url.Values{"repo": {repo}}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	resp, err := c.do("标记镜像", "POST", "/images/"+source+"/tag", query, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// RemoveImage 删除本地镜像引用；镜像还有其他标签时只移除该标签，不清理未打标签的父镜像
+func (c *Client) RemoveImage(ref string) error {
+	resp, err := c.do("删除镜像", "DELETE", "/images/"+ref, url.Values{"noprune": {"1"}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PushImage 推送本地镜像，未指定用户名时使用 docker 客户端配置中的凭据，进度写入 out（可为 nil）
+func (c *Client) PushImage(ref, username, password string, out io.Writer) error {
+	repo, tag := splitReference(ref)
+	auth, err := registryAuthHeader(registryHost(repo), username, password)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	resp, err := c.do("推送镜像", "POST", "/images/"+repo+"/push", query, nil, map[string]string{"X-Registry-Auth": auth})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return DisplayJSONMessages(resp.Body, out, nil)
+}
+
+// splitReference 将镜像引用拆分为仓库和标签，摘要引用的标签部分保留 @sha256:...
+func splitReference(ref string) (string, string) {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i], ref[i:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// registryHost 返回镜像仓库地址，没有仓库前缀的镜像属于 Docker Hub
+func registryHost(repo string) string {
+	first, _, found := strings.Cut(repo, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+	return "docker.io"
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONMessage 推送、导入等接口返回的流式进度消息
+type JSONMessage struct {
+	Stream         string `json:"stream,omitempty"`
+	Status         string `json:"status,omitempty"`
+	ID             string `json:"id,omitempty"`
+	Progress       string `json:"progress,omitempty"`
+	ProgressDetail struct {
+		Current int64 `json:"current,omitempty"`
+		Total   int64 `json:"total,omitempty"`
+	} `json:"progressDetail"`
+	ErrorDetail *struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message"`
+	} `json:"errorDetail,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// StreamError 进度流中返回的错误
+type StreamError struct {
+	Code    int
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return e.Message
+}
+
+// DisplayJSONMessages 读取进度流并写入 out（可为 nil），每个层只在状态变化时输出一行，
+// 避免大量进度刷屏；onMessage 用于提取导入结果等信息。流中出现错误时返回 StreamError
+func DisplayJSONMessages(r io.Reader, out io.Writer, onMessage func(JSONMessage)) error {
+	if out == nil {
+		out = io.Discard
+	}
+
+	decoder := json.NewDecoder(r)
+	lastStatus := make(map[string]string)
+	for {
+		var msg JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("解析进度消息失败: %v", err)
+		}
+
+		if msg.ErrorDetail != nil {
+			return &StreamError{Code: msg.ErrorDetail.Code, Message: msg.ErrorDetail.Message}
+		}
+		if msg.Error != "" {
+			return &StreamError{Message: msg.Error}
+		}
+		if onMessage != nil {
+			onMessage(msg)
+		}
+
+		switch {
+		case msg.Stream != "":
+			fmt.Fprint(out, msg.Stream)
+			if !strings.HasSuffix(msg.Stream, "\n") {
+				fmt.Fprintln(out)
+			}
+		case msg.Status != "" && msg.ID != "":
+			if lastStatus[msg.ID] == msg.Status {
+				continue
+			}
+			lastStatus[msg.ID] = msg.Status
+			fmt.Fprintf(out, "%s: %s\n", msg.ID, msg.Status)
+		case msg.Status != "":
+			fmt.Fprintln(out, msg.Status)
+		}
+	}
+}
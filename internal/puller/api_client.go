@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strings"
@@ -64,7 +65,7 @@ func (c *AdvancedAPIClient) SearchImage(imageName, site, platform string) ([]API
 
 	// 构建完整URL
 	searchURL := fmt.Sprintf("%s/image?%s", c.baseURL, params.Encode())
-	fmt.Printf("🔍 API请求URL: %s\n", searchURL)
+	log.Printf("🔍 API请求URL: %s", searchURL)
 
 	// 发送请求
 	resp, err := c.httpClient.Get(searchURL)
@@ -79,8 +80,8 @@ func (c *AdvancedAPIClient) SearchImage(imageName, site, platform string) ([]API
 		return nil, fmt.Errorf("读取响应失败: %v", err)
 	}
 
-	fmt.Printf("📡 API响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("📄 API响应内容: %s\n", string(body))
+	log.Printf("📡 API响应状态码: %d", resp.StatusCode)
+	log.Printf("📄 API响应内容: %s", string(body))
 
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("API返回错误状态码: %d, 响应: %s", resp.StatusCode, string(body))
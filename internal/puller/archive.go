@@ -0,0 +1,346 @@
+package puller
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageConfig 镜像配置文件中与打包相关的字段
+type ImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Created      string `json:"created,omitempty"`
+	RootFS       struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// ArchiveManifestEntry docker-archive 中 manifest.json 的条目
+type ArchiveManifestEntry struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ImageID 返回条目的镜像 ID（配置文件的 sha256），兼容 <hex>.json 和 blobs/sha256/<hex> 两种写法
+func (e ArchiveManifestEntry) ImageID() string {
+	return "sha256:" + strings.TrimSuffix(path.Base(e.Config), ".json")
+}
+
+// v1Layer docker save 兼容格式中的一层
+type v1Layer struct {
+	ID     string // v1 层ID（不含sha256:前缀），同时作为层目录名
+	DiffID string // 未压缩层的digest
+	JSON   []byte // 层目录下的 json 文件内容
+}
+
+// readImageConfig 读取并解析镜像配置文件
+func readImageConfig(path string) ([]byte, *ImageConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取镜像配置失败: %v", err)
+	}
+
+	var imageConfig ImageConfig
+	if err := json.Unmarshal(data, &imageConfig); err != nil {
+		return nil, nil, fmt.Errorf("解析镜像配置失败: %v", err)
+	}
+
+	return data, &imageConfig, nil
+}
+
+// sha256Digest 计算数据的 sha256 digest（带 sha256: 前缀）
+func sha256Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// chainIDs 根据 diff_ids 计算每一层的 ChainID
+// ChainID(L0) = DiffID(L0)，ChainID(Ln) = sha256(ChainID(Ln-1) + " " + DiffID(Ln))
+func chainIDs(diffIDs []string) []string {
+	ids := make([]string, len(diffIDs))
+	for i, diffID := range diffIDs {
+		if i == 0 {
+			ids[i] = diffID
+			continue
+		}
+		ids[i] = sha256Digest([]byte(ids[i-1] + " " + diffID))
+	}
+	return ids
+}
+
+// buildV1Layers 生成与 docker save 一致的 v1 兼容层信息
+// 层ID的计算方式与 moby 的 v1.CreateID 相同：对带有 layer_id(ChainID) 和 parent 的 v1 json 取 sha256，
+// 最顶层的 json 包含完整的镜像配置（去掉 rootfs 和 history），因此顶层ID即 v1 镜像ID
+func buildV1Layers(rawConfig []byte, imageConfig *ImageConfig) ([]v1Layer, error) {
+	var configFields map[string]json.RawMessage
+	if err := json.Unmarshal(rawConfig, &configFields); err != nil {
+		return nil, fmt.Errorf("解析镜像配置失败: %v", err)
+	}
+
+	diffIDs := imageConfig.RootFS.DiffIDs
+	chains := chainIDs(diffIDs)
+	layers := make([]v1Layer, 0, len(diffIDs))
+
+	var parent string
+	for i, diffID := range diffIDs {
+		v1JSON := make(map[string]interface{})
+		if i == len(diffIDs)-1 {
+			for key, value := range configFields {
+				if key == "rootfs" || key == "history" {
+					continue
+				}
+				v1JSON[key] = value
+			}
+		} else if imageConfig.Created != "" {
+			v1JSON["created"] = imageConfig.Created
+		}
+		if imageConfig.OS != "" {
+			v1JSON["os"] = imageConfig.OS
+		}
+
+		// 计算层ID
+		idSource := make(map[string]interface{}, len(v1JSON)+2)
+		for key, value := range v1JSON {
+			idSource[key] = value
+		}
+		idSource["layer_id"] = chains[i]
+		if parent != "" {
+			idSource["parent"] = "sha256:" + parent
+		}
+		idData, err := json.Marshal(idSource)
+		if err != nil {
+			return nil, fmt.Errorf("生成层ID失败: %v", err)
+		}
+		id := strings.TrimPrefix(sha256Digest(idData), "sha256:")
+
+		v1JSON["id"] = id
+		if parent != "" {
+			v1JSON["parent"] = parent
+		}
+		data, err := json.Marshal(v1JSON)
+		if err != nil {
+			return nil, fmt.Errorf("生成层JSON失败: %v", err)
+		}
+
+		layers = append(layers, v1Layer{ID: id, DiffID: diffID, JSON: data})
+		parent = id
+	}
+
+	return layers, nil
+}
+
+// splitRepoTag 将 RepoTag 拆分为仓库名和标签
+func splitRepoTag(repoTag string) (string, string) {
+	i := strings.LastIndex(repoTag, ":")
+	if i == -1 || strings.Contains(repoTag[i+1:], "/") {
+		return repoTag, "latest"
+	}
+	return repoTag[:i], repoTag[i+1:]
+}
+
+// buildRepositories 生成 repositories 文件内容，标签指向顶层的 v1 镜像ID
+func buildRepositories(repoTags []string, topLayerID string) map[string]map[string]string {
+	repositories := make(map[string]map[string]string)
+	for _, repoTag := range repoTags {
+		repo, tag := splitRepoTag(repoTag)
+		if repositories[repo] == nil {
+			repositories[repo] = make(map[string]string)
+		}
+		repositories[repo][tag] = topLayerID
+	}
+	return repositories
+}
+
+// layerStore 记录工作目录中已保存的层，用于同一归档内的层去重
+type layerStore struct {
+	mu    sync.Mutex
+	paths map[string]string // diff_id -> 归档内的层路径
+	links map[string]string // 归档内的层路径 -> 实际保存数据的层路径
+
+	// 生成增量归档时基线中已有的层，这些层不下载也不写入归档
+	baseline       map[string]DeltaLayer // diff_id -> 基线中的来源
+	baselineDigest string
+	missing        []DeltaLayer
+}
+
+// newLayerStore 创建层存储
+func newLayerStore() *layerStore {
+	return &layerStore{
+		paths: make(map[string]string),
+		links: make(map[string]string),
+	}
+}
+
+// lookup 查找已保存的层
+func (s *layerStore) lookup(diffID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path, ok := s.paths[diffID]
+	return path, ok
+}
+
+// add 登记新保存的层，若该层已被其他下载登记则返回已有路径和 false
+func (s *layerStore) add(diffID, path string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.paths[diffID]; ok {
+		return existing, false
+	}
+	s.paths[diffID] = path
+	return path, true
+}
+
+// link 记录一个引用已有层数据的层路径
+func (s *layerStore) link(path, target string) {
+	if path == target {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links[path] = target
+}
+
+// symlinks 返回归档内需要写入的符号链接，值为相对链接路径
+func (s *layerStore) symlinks() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links := make(map[string]string, len(s.links))
+	for path, target := range s.links {
+		links[path] = "../" + target
+	}
+	return links
+}
+
+// writeArchiveMetadata 在工作目录中写入 manifest.json 和 repositories 文件
+func writeArchiveMetadata(workDir string, entries []ArchiveManifestEntry, repositories map[string]map[string]string) error {
+	manifestData, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("生成manifest.json失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "manifest.json"), manifestData, 0644); err != nil {
+		return fmt.Errorf("写入manifest.json失败: %v", err)
+	}
+
+	repositoriesData, err := json.Marshal(repositories)
+	if err != nil {
+		return fmt.Errorf("生成repositories失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "repositories"), repositoriesData, 0644); err != nil {
+		return fmt.Errorf("写入repositories失败: %v", err)
+	}
+
+	return nil
+}
+
+// writeSymlinks 将层的符号链接写入tar，按路径排序保证输出稳定
+func writeSymlinks(tarWriter *tar.Writer, links map[string]string) error {
+	paths := make([]string, 0, len(links))
+	for path := range links {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	now := time.Now()
+	for _, path := range paths {
+		header := &tar.Header{
+			Typeflag: tar.TypeSymlink,
+			Name:     path,
+			Linkname: links[path],
+			Mode:     0777,
+			ModTime:  now,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadArchiveManifest 读取镜像归档（支持 gzip/zstd 压缩）中的 manifest.json
+func ReadArchiveManifest(path string) ([]ArchiveManifestEntry, error) {
+	data, err := ReadArchiveFile(path, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []ArchiveManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析 manifest.json 失败: %v", err)
+	}
+	return entries, nil
+}
+
+// ReadArchiveFile 读取镜像归档（支持 gzip/zstd 压缩及分卷索引）中指定的文件
+func ReadArchiveFile(path, name string) ([]byte, error) {
+	compression := CompressionGzip
+	if !IsSplitIndex(path) {
+		detected, err := DetectFileCompression(path)
+		if err != nil {
+			return nil, err
+		}
+		compression = detected
+	}
+
+	file, err := openArchiveStream(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 未压缩的归档直接使用文件读取，tar 可以通过 Seek 跳过层数据；
+	// 分卷和压缩归档统一经过解压探测（未压缩时原样透传）
+	var reader io.Reader = file
+	if compression != CompressionNone {
+		decompressReader, err := newDecompressReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("解压归档失败: %v", err)
+		}
+		defer decompressReader.Close()
+		reader = decompressReader
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("归档中未找到 %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取归档失败: %v", err)
+		}
+
+		if strings.TrimPrefix(header.Name, "./") != name {
+			continue
+		}
+
+		return io.ReadAll(tarReader)
+	}
+}
+
+// archiveImageTags 检查归档中是否已包含指定镜像ID的镜像，并且归档完整、该镜像的配置和各层校验通过，
+// 返回归档中该镜像的 RepoTags；只比对 manifest.json 不够，中断写入的归档同样会先写入 manifest.json
+func archiveImageTags(path, configDigest string) ([]string, bool) {
+	info, err := InspectArchive(path)
+	if err != nil || len(info.Errors) > 0 {
+		return nil, false
+	}
+	for _, image := range info.Images {
+		if image.ImageID == configDigest && len(image.Errors) == 0 {
+			return image.RepoTags, true
+		}
+	}
+	return nil, false
+}
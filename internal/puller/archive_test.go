@@ -0,0 +1,189 @@
+package puller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dockerops/internal/config"
+)
+
+// 以下期望值按 moby 的 ChainID 和 v1.CreateID 算法独立计算
+const (
+	testDiffID1 = "sha256:f191e625176e7514249870c60bc24622131b04927a418001d1969c9cecd260f1" // sha256("layer one")
+	testDiffID2 = "sha256:77c7ed05b2e49df2e55d70cb007508cc66b2f5419a940bfd6f8749ba7db77c45" // sha256("layer two")
+	testDiffID3 = "sha256:6483085b79dcef99d4d90b43a910666cdce078bf772da453728f8c385fbfdf2f" // sha256("layer three")
+)
+
+func TestChainIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		diffIDs []string
+		want    []string
+	}{
+		{"没有层", nil, []string{}},
+		{"单层的 ChainID 等于 diff_id", []string{testDiffID1}, []string{testDiffID1}},
+		{"两层", []string{testDiffID1, testDiffID2}, []string{
+			testDiffID1,
+			"sha256:d440fc2ac5bfa225471b58c5d6b4283504f5dfbcd6251023a635cdcd0c058e3e",
+		}},
+		{"三层", []string{testDiffID1, testDiffID2, testDiffID3}, []string{
+			testDiffID1,
+			"sha256:d440fc2ac5bfa225471b58c5d6b4283504f5dfbcd6251023a635cdcd0c058e3e",
+			"sha256:952689aceeac7e377b602804de533a4ad47ba634e3917ca7cb071e5ff2bff6cd",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chainIDs(tt.diffIDs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chainIDs = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testImageConfig 生成包含 rootfs 和 history 的镜像配置
+func testImageConfig(t *testing.T, diffIDs ...string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{
+		"architecture": "amd64",
+		"os":           "linux",
+		"created":      "2024-01-01T00:00:00Z",
+		"config":       map[string]any{"Env": []string{"PATH=/bin"}},
+		"rootfs":       map[string]any{"type": "layers", "diff_ids": diffIDs},
+		"history":      []map[string]any{{"created_by": "test"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBuildV1Layers(t *testing.T) {
+	tests := []struct {
+		name      string
+		diffIDs   []string
+		wantIDs   []string
+		wantJSONs []string
+	}{
+		{
+			name:    "单层",
+			diffIDs: []string{testDiffID1},
+			wantIDs: []string{"7c1fde46c51b0b92649f585945ee28f53085d886b2c7e2994f5ef2cae3e95236"},
+		},
+		{
+			name:    "两层",
+			diffIDs: []string{testDiffID1, testDiffID2},
+			wantIDs: []string{
+				"cb4b9b1e8831633f2fddc6310e605848eb2b61456809353157883ea6ee4db68a",
+				"221b0e29c040981751dc1030c7ff5cae218c659236d5b103616b65a600df07a2",
+			},
+			wantJSONs: []string{
+				`{"created":"2024-01-01T00:00:00Z","id":"cb4b9b1e8831633f2fddc6310e605848eb2b61456809353157883ea6ee4db68a","os":"linux"}`,
+				`{"architecture":"amd64","config":{"Env":["PATH=/bin"]},"created":"2024-01-01T00:00:00Z","id":"221b0e29c040981751dc1030c7ff5cae218c659236d5b103616b65a600df07a2","os":"linux","parent":"cb4b9b1e8831633f2fddc6310e605848eb2b61456809353157883ea6ee4db68a"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := testImageConfig(t, tt.diffIDs...)
+			var imageConfig ImageConfig
+			if err := json.Unmarshal(raw, &imageConfig); err != nil {
+				t.Fatal(err)
+			}
+			layers, err := buildV1Layers(raw, &imageConfig)
+			if err != nil {
+				t.Fatalf("buildV1Layers: %v", err)
+			}
+			if len(layers) != len(tt.wantIDs) {
+				t.Fatalf("层数 = %d，期望 %d", len(layers), len(tt.wantIDs))
+			}
+			for i, layer := range layers {
+				if layer.ID != tt.wantIDs[i] {
+					t.Errorf("第 %d 层 ID = %s，期望 %s", i, layer.ID, tt.wantIDs[i])
+				}
+				if layer.DiffID != tt.diffIDs[i] {
+					t.Errorf("第 %d 层 DiffID = %s，期望 %s", i, layer.DiffID, tt.diffIDs[i])
+				}
+				if tt.wantJSONs != nil && string(layer.JSON) != tt.wantJSONs[i] {
+					t.Errorf("第 %d 层 json = %s，期望 %s", i, layer.JSON, tt.wantJSONs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchImageVerifiesDiffIDs(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte("layer one"))
+	gz.Close()
+	layerBlob := gzipped.Bytes()
+	layerDigest := sha256Digest(layerBlob)
+
+	tests := []struct {
+		name    string
+		diffID  string
+		wantErr string
+	}{
+		{"diff_id 一致", testDiffID1, ""},
+		{"diff_id 不一致", testDiffID2, "diff_id 期望 " + testDiffID2 + "，实际 " + testDiffID1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawConfig := testImageConfig(t, tt.diffID)
+			configDigest := sha256Digest(rawConfig)
+			blobs := map[string][]byte{configDigest: rawConfig, layerDigest: layerBlob}
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, ok := blobs[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write(data)
+			}))
+			defer server.Close()
+
+			dir := t.TempDir()
+			p := &MultiRegistryImagePuller{
+				configManager:   config.NewConfigManager(filepath.Join(dir, "config.json")),
+				httpClient:      server.Client(),
+				disableProgress: true,
+			}
+			manifest := &ManifestResponse{
+				Config: ConfigDescriptor{Digest: configDigest, Size: int64(len(rawConfig))},
+				Layers: []LayerDescriptor{{Digest: layerDigest, Size: int64(len(layerBlob))}},
+			}
+			registry := &config.RegistryConfig{URL: strings.TrimPrefix(server.URL, "https://")}
+			workDir := filepath.Join(dir, "work")
+
+			entry, topID, err := p.fetchImage(registry, ImageInfo{Repository: "library/test", Tag: "1"}, manifest, []string{"test:1"}, "", workDir, newLayerStore())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchImage: %v", err)
+			}
+			if topID != "7c1fde46c51b0b92649f585945ee28f53085d886b2c7e2994f5ef2cae3e95236" {
+				t.Errorf("顶层 ID = %s", topID)
+			}
+			if want := []string{topID + "/layer.tar"}; !reflect.DeepEqual(entry.Layers, want) {
+				t.Errorf("Layers = %v，期望 %v", entry.Layers, want)
+			}
+			data, err := os.ReadFile(filepath.Join(workDir, topID, "layer.tar"))
+			if err != nil || string(data) != "layer one" {
+				t.Errorf("解压后的层内容 = %q, %v", data, err)
+			}
+		})
+	}
+}
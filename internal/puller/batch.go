@@ -0,0 +1,168 @@
+package puller
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ImageListEntry 镜像列表中的一项
+type ImageListEntry struct {
+	Image    string   `json:"image" yaml:"image"`
+	Tag      string   `json:"tag,omitempty" yaml:"tag,omitempty"`           // 覆盖镜像引用中的标签
+	Platform string   `json:"platform,omitempty" yaml:"platform,omitempty"` // 例如 linux/arm64 或 arm64
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty"`         // 导入后额外的 RepoTag
+}
+
+// UnmarshalYAML 支持直接使用字符串表示镜像
+func (e *ImageListEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Image = strings.TrimSpace(value.Value)
+		return nil
+	}
+
+	type plain ImageListEntry
+	return value.Decode((*plain)(e))
+}
+
+// Reference 返回应用标签覆盖后的镜像引用
+func (e ImageListEntry) Reference() string {
+	if e.Tag == "" {
+		return e.Image
+	}
+
+	name := e.Image
+	if i := strings.Index(name, "@"); i != -1 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i != -1 && !strings.Contains(name[i:], "/") {
+		name = name[:i]
+	}
+	return name + ":" + e.Tag
+}
+
+// Arch 返回平台中的架构部分，未指定时返回 defaultArch
+func (e ImageListEntry) Arch(defaultArch string) string {
+	if e.Platform == "" {
+		return defaultArch
+	}
+	parts := strings.Split(e.Platform, "/")
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return parts[0]
+}
+
+// imageListFile YAML/JSON 格式的镜像列表文件
+type imageListFile struct {
+	Platform string           `yaml:"platform"` // 所有镜像的默认平台
+	Images   []ImageListEntry `yaml:"images"`
+}
+
+// LoadImageList 加载镜像列表文件
+// .yaml/.yml/.json 文件支持对象形式（images 字段）或直接的列表，
+// 其他文件按文本处理：每行一个镜像，可在镜像后跟平台，# 开头为注释
+func LoadImageList(path string) ([]ImageListEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取镜像列表失败: %v", err)
+	}
+
+	var entries []ImageListEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		// JSON 是 YAML 的子集，统一使用 YAML 解析
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("解析镜像列表失败: %v", err)
+		}
+		if len(node.Content) > 0 && node.Content[0].Kind == yaml.SequenceNode {
+			if err := node.Decode(&entries); err != nil {
+				return nil, fmt.Errorf("解析镜像列表失败: %v", err)
+			}
+		} else {
+			var listFile imageListFile
+			if err := node.Decode(&listFile); err != nil {
+				return nil, fmt.Errorf("解析镜像列表失败: %v", err)
+			}
+			for _, entry := range listFile.Images {
+				if entry.Platform == "" {
+					entry.Platform = listFile.Platform
+				}
+				entries = append(entries, entry)
+			}
+		}
+	default:
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if i := strings.Index(line, "#"); i != -1 {
+				line = strings.TrimSpace(line[:i])
+			}
+			if line == "" {
+				continue
+			}
+			fields := strings.Fields(line)
+			entry := ImageListEntry{Image: fields[0]}
+			if len(fields) > 1 {
+				entry.Platform = fields[1]
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.Image == "" {
+			return nil, fmt.Errorf("镜像列表第 %d 项缺少镜像名称", i+1)
+		}
+	}
+
+	return entries, nil
+}
+
+// BatchPullResult 批量拉取中单个镜像的结果
+type BatchPullResult struct {
+	Entry ImageListEntry
+	Arch  string
+	*PullResult
+	Err error
+}
+
+// PullBatch 并发拉取镜像列表，返回结果的顺序与输入一致
+func (p *MultiRegistryImagePuller) PullBatch(entries []ImageListEntry, defaultArch, username, password string, concurrency int, options PullOptions) []BatchPullResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > 1 {
+		p.DisableProgressBar()
+	}
+
+	results := make([]BatchPullResult, len(entries))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+
+	for i, entry := range entries {
+		wg.Add(1)
+		go func(i int, entry ImageListEntry) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			entryOptions := options
+			entryOptions.Output = ""
+			entryOptions.Tags = entry.Tags
+
+			arch := entry.Arch(defaultArch)
+			result, err := p.Pull(entry.Reference(), arch, username, password, entryOptions)
+			results[i] = BatchPullResult{Entry: entry, Arch: arch, PullResult: result, Err: err}
+		}(i, entry)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,111 @@
+package puller
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobCache 按 digest 保存从仓库下载的原始压缩层，推送时可以直接上传原始 blob，
+// 保持与源仓库相同的层 digest，无需重新压缩
+type BlobCache struct {
+	dir string
+}
+
+// cachedLayer 缓存中记录的 diff_id 到原始 blob 的映射
+type cachedLayer struct {
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Digest    string `json:"digest"`
+	DiffID    string `json:"diff_id"`
+}
+
+// NewBlobCache 创建 blob 缓存，dir 为空时返回 nil（不使用缓存）
+func NewBlobCache(dir string) *BlobCache {
+	if dir == "" {
+		return nil
+	}
+	return &BlobCache{dir: dir}
+}
+
+// blobPath 返回 blob 在缓存中的路径
+func (c *BlobCache) blobPath(digest string) string {
+	return filepath.Join(c.dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+// indexPath 返回 diff_id 索引文件的路径
+func (c *BlobCache) indexPath(diffID string) string {
+	return filepath.Join(c.dir, "diffids", "sha256", strings.TrimPrefix(diffID, "sha256:")+".json")
+}
+
+// Store 将已校验的压缩层移入缓存，并记录其 diff_id；缓存中已存在时删除 src
+func (c *BlobCache) Store(src string, layer LayerDescriptor, diffID string) error {
+	if !strings.HasPrefix(layer.Digest, "sha256:") || !strings.HasPrefix(diffID, "sha256:") {
+		os.Remove(src)
+		return nil
+	}
+
+	blobPath := c.blobPath(layer.Digest)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(blobPath); err == nil {
+		os.Remove(src)
+	} else if err := os.Rename(src, blobPath); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cachedLayer{
+		MediaType: layer.MediaType,
+		Size:      layer.Size,
+		Digest:    layer.Digest,
+		DiffID:    diffID,
+	})
+	if err != nil {
+		return err
+	}
+	indexPath := c.indexPath(diffID)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免并发拉取时读到不完整的索引
+	tmpPath := fmt.Sprintf("%s.%d", indexPath, os.Getpid())
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, indexPath)
+}
+
+// Has 查找缓存中的原始 blob，返回其路径
+func (c *BlobCache) Has(digest string) (string, bool) {
+	if c == nil || !strings.HasPrefix(digest, "sha256:") {
+		return "", false
+	}
+	path := c.blobPath(digest)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// Lookup 根据未压缩层的 diff_id 查找缓存的原始 blob
+func (c *BlobCache) Lookup(diffID string) (*cachedLayer, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.indexPath(diffID))
+	if err != nil {
+		return nil, false
+	}
+	var layer cachedLayer
+	if err := json.Unmarshal(data, &layer); err != nil || layer.DiffID != diffID {
+		return nil, false
+	}
+	info, err := os.Stat(c.blobPath(layer.Digest))
+	if err != nil || info.Size() != layer.Size {
+		return nil, false
+	}
+	return &layer, true
+}
@@ -0,0 +1,253 @@
+package puller
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultBundleNameTemplate 多镜像归档的默认文件名模板
+const DefaultBundleNameTemplate = "bundle_{date}"
+
+// BundleResult 多镜像打包结果
+type BundleResult struct {
+	OutputFile     string
+	Images         []BatchPullResult
+	Size           int64 // 输出文件大小
+	Layers         int   // 去重后实际保存的层数量
+	SharedLayers   int   // 复用已有层的次数
+	BaselineLayers int   // 因基线已有而省略的层数量
+}
+
+// bundleImage 归档中的一个镜像（按镜像ID去重）
+type bundleImage struct {
+	entry      *ArchiveManifestEntry
+	topLayerID string
+}
+
+// PullBundle 拉取多个镜像并打包为一个归档，相同的层只保存一次，
+// manifest.json 中包含所有镜像的 RepoTags，一次 docker load 即可全部导入
+func (p *MultiRegistryImagePuller) PullBundle(entries []ImageListEntry, defaultArch, username, password string, concurrency int, options PullOptions) (*BundleResult, error) {
+	compression, err := NormalizeCompression(options.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	outputFile, err := p.resolveBundleOutput(options, compression)
+	if err != nil {
+		return nil, err
+	}
+	archivePath := archiveOutputPath(outputFile, options)
+	if !options.Force && archivePath != outputFile {
+		if _, err := os.Stat(archivePath); err == nil {
+			return nil, fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", archivePath)
+		}
+	}
+	result := &BundleResult{OutputFile: archivePath}
+
+	workDir, err := newWorkDir("bundle-")
+	if err != nil {
+		return nil, err
+	}
+	if p.configManager.GetConfig().Settings.CleanupTempFiles {
+		defer os.RemoveAll(workDir)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > 1 {
+		p.DisableProgressBar()
+	}
+
+	store := newLayerStore()
+	if err := store.setBaseline(options.Baseline); err != nil {
+		return nil, err
+	}
+	images := make(map[string]*bundleImage)
+	var mu sync.Mutex
+
+	results := make([]BatchPullResult, len(entries))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+
+	for i, entry := range entries {
+		wg.Add(1)
+		go func(i int, entry ImageListEntry) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			entryOptions := options
+			entryOptions.Tags = entry.Tags
+
+			arch := entry.Arch(defaultArch)
+			pullResult, err := p.fetchBundleImage(entry.Reference(), arch, username, password, entryOptions, workDir, store, images, &mu)
+			results[i] = BatchPullResult{Entry: entry, Arch: arch, PullResult: pullResult, Err: err}
+		}(i, entry)
+	}
+	wg.Wait()
+	result.Images = results
+
+	for _, r := range results {
+		if r.Err != nil {
+			return result, fmt.Errorf("部分镜像拉取失败，未生成归档")
+		}
+	}
+
+	// 按输入顺序生成包含全部镜像的 manifest.json 和 repositories
+	var manifestEntries []ArchiveManifestEntry
+	repositories := make(map[string]map[string]string)
+	added := make(map[string]bool)
+	// 同一个 RepoTag 指向不同镜像时 docker load 只保留最后导入的一个，例如同一引用按多个架构打包
+	owners := make(map[string]string)
+	for _, r := range results {
+		if added[r.Digest] {
+			continue
+		}
+		added[r.Digest] = true
+
+		image := images[r.Digest]
+		for _, repoTag := range image.entry.RepoTags {
+			if owner, ok := owners[repoTag]; ok {
+				return result, fmt.Errorf("标签 %s 同时指向镜像 %s 和 %s，请在镜像列表中为不同架构的镜像指定不同的标签", repoTag, owner[:19], r.Digest[:19])
+			}
+			owners[repoTag] = r.Digest
+		}
+		manifestEntries = append(manifestEntries, *image.entry)
+		for repo, tags := range buildRepositories(image.entry.RepoTags, image.topLayerID) {
+			if repositories[repo] == nil {
+				repositories[repo] = make(map[string]string)
+			}
+			for tag, id := range tags {
+				repositories[repo][tag] = id
+			}
+		}
+	}
+
+	if err := writeArchiveMetadata(workDir, manifestEntries, repositories); err != nil {
+		return result, err
+	}
+	if err := store.writeDeltaMetadata(workDir); err != nil {
+		return result, err
+	}
+
+	links := store.symlinks()
+	result.Layers = len(store.paths)
+	result.SharedLayers = len(links)
+	result.BaselineLayers = store.baselineLayers()
+
+	log.Printf("正在打包 %d 个镜像（%d 个层，复用 %d 次）...", len(manifestEntries), result.Layers, result.SharedLayers)
+	if err := p.createImageTar(workDir, outputFile, links, options); err != nil {
+		return result, fmt.Errorf("打包镜像失败: %v", err)
+	}
+
+	if outputFile != "-" {
+		result.Size = archiveSize(archivePath)
+	}
+	return result, nil
+}
+
+// fetchBundleImage 下载单个镜像到共享的工作目录
+func (p *MultiRegistryImagePuller) fetchBundleImage(imageInput, arch, username, password string, options PullOptions, workDir string, store *layerStore, images map[string]*bundleImage, mu *sync.Mutex) (*PullResult, error) {
+	start := time.Now()
+	result := &PullResult{Image: imageInput}
+
+	imageInput, err := p.ResolveTagConstraint(imageInput, username, password)
+	if err != nil {
+		return result, err
+	}
+
+	registry, manifest, imageInfo, err := p.SearchImageInRegistries(imageInput, arch, username, password)
+	if err != nil {
+		return result, err
+	}
+	result.Registry = registry.URL
+	result.Digest = manifest.Config.Digest
+	result.RepoTags = p.resolveRepoTags(imageInput, registry, imageInfo, options)
+
+	if len(manifest.Layers) == 0 {
+		return result, fmt.Errorf("清单中没有层")
+	}
+	// 多个镜像写入同一个归档，无法得到单个镜像的文件大小，记录各层压缩后的大小之和
+	for _, layer := range manifest.Layers {
+		result.Size += layer.Size
+	}
+
+	// 相同镜像ID只下载一次，只合并标签
+	mu.Lock()
+	if image, ok := images[manifest.Config.Digest]; ok {
+		image.entry.RepoTags = appendUnique(image.entry.RepoTags, result.RepoTags...)
+		mu.Unlock()
+		result.Skipped = true
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+	image := &bundleImage{entry: &ArchiveManifestEntry{RepoTags: result.RepoTags}}
+	images[manifest.Config.Digest] = image
+	mu.Unlock()
+
+	token, err := p.GetAuthToken(registry, imageInfo.Repository, username, password)
+	if err != nil {
+		return result, fmt.Errorf("获取认证失败: %v", err)
+	}
+
+	entry, topLayerID, err := p.fetchImage(registry, imageInfo, manifest, result.RepoTags, token, workDir, store)
+	if err != nil {
+		return result, err
+	}
+
+	mu.Lock()
+	entry.RepoTags = image.entry.RepoTags
+	image.entry = entry
+	image.topLayerID = topLayerID
+	mu.Unlock()
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// resolveBundleOutput 确定多镜像归档的输出路径
+func (p *MultiRegistryImagePuller) resolveBundleOutput(options PullOptions, compression string) (string, error) {
+	if options.Output == "-" {
+		return "-", nil
+	}
+
+	outputDir := options.OutputDir
+	if outputDir == "" {
+		outputDir = p.configManager.GetConfig().Settings.OutputDir
+	}
+
+	name := options.Output
+	if name == "" {
+		template := options.NameTemplate
+		if template == "" {
+			template = DefaultBundleNameTemplate
+		}
+		name = RenderOutputName(template, OutputNameVars{}, CompressionExt(compression))
+	} else if filepath.IsAbs(name) {
+		outputDir = ""
+	}
+
+	return ResolveOutputPath(outputDir, name, options.Force)
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
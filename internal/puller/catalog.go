@@ -0,0 +1,153 @@
+package puller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dockerops/internal/config"
+)
+
+// catalogPageSize 每次请求仓库目录的数量
+const catalogPageSize = 1000
+
+// CatalogOptions 仓库目录查询选项
+type CatalogOptions struct {
+	Username  string
+	Password  string
+	Filter    string // 只保留匹配正则表达式的镜像路径
+	Tags      bool   // 同时查询每个镜像路径的标签
+	PlainHTTP bool   // 使用 HTTP 访问仓库
+}
+
+// CatalogRepository 仓库目录中的一个镜像路径
+type CatalogRepository struct {
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags,omitempty"`
+	Error string   `json:"error,omitempty"` // 查询标签失败的原因
+}
+
+// CatalogResult 仓库目录查询结果
+type CatalogResult struct {
+	Registry     string              `json:"registry"`
+	URL          string              `json:"url"`
+	Total        int                 `json:"total"` // 过滤前的镜像路径数量
+	Repositories []CatalogRepository `json:"repositories"`
+}
+
+// FindRegistry 按名称或地址查找配置中的镜像仓库
+func (p *MultiRegistryImagePuller) FindRegistry(name string) (*config.RegistryConfig, error) {
+	for _, registry := range p.registries {
+		if strings.EqualFold(registry.Name, name) || registry.URL == name {
+			registry := registry
+			return &registry, nil
+		}
+	}
+
+	var names []string
+	for _, registry := range p.registries {
+		names = append(names, registry.Name)
+	}
+	return nil, fmt.Errorf("配置中没有名为 %s 的镜像仓库，可用的仓库: %s", name, strings.Join(names, ", "))
+}
+
+// Catalog 按 Link 头分页获取仓库的 /v2/_catalog，可选查询每个镜像路径的标签
+func (p *MultiRegistryImagePuller) Catalog(registryName string, options CatalogOptions) (*CatalogResult, error) {
+	registry, err := p.FindRegistry(registryName)
+	if err != nil {
+		return nil, err
+	}
+	if registry.AuthRequired && options.Username == "" {
+		return nil, fmt.Errorf("仓库 %s 需要认证，请使用 -u 和 -p 提供用户名和密码", registry.Name)
+	}
+
+	var filter *regexp.Regexp
+	if options.Filter != "" {
+		if filter, err = regexp.Compile(options.Filter); err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %s: %v", options.Filter, err)
+		}
+	}
+
+	client := p.newRegistryClient(registry.URL, PushOptions{
+		Username:  options.Username,
+		Password:  options.Password,
+		PlainHTTP: options.PlainHTTP,
+	})
+	if err := client.authorizeScopes([]string{"registry:catalog:*"}); err != nil {
+		return nil, fmt.Errorf("仓库 %s 认证失败: %v", registry.Name, err)
+	}
+
+	names, err := client.listPaged(fmt.Sprintf("/v2/_catalog?n=%d", catalogPageSize), "repositories")
+	if err != nil {
+		return nil, fmt.Errorf("获取仓库目录失败: %v", err)
+	}
+	sort.Strings(names)
+
+	result := &CatalogResult{Registry: registry.Name, URL: registry.URL, Total: len(names)}
+	for _, name := range names {
+		if filter != nil && !filter.MatchString(name) {
+			continue
+		}
+		result.Repositories = append(result.Repositories, CatalogRepository{Name: name})
+	}
+	log.Printf("%s 中共有 %d 个镜像路径，匹配 %d 个", registry.Name, len(names), len(result.Repositories))
+
+	if options.Tags {
+		for i := range result.Repositories {
+			repository := &result.Repositories[i]
+			tags, err := client.repositoryTags(repository.Name)
+			if err != nil {
+				repository.Error = err.Error()
+				continue
+			}
+			SortTagsBySemver(tags)
+			repository.Tags = tags
+		}
+	}
+	return result, nil
+}
+
+// repositoryTags 申请镜像路径的拉取权限后分页获取其全部标签
+func (c *registryClient) repositoryTags(repository string) ([]string, error) {
+	if err := c.authorizeScopes([]string{fmt.Sprintf("repository:%s:pull", repository)}); err != nil {
+		return nil, err
+	}
+	return c.listPaged(fmt.Sprintf("/v2/%s/tags/list?n=%d", repository, tagListPageSize), "tags")
+}
+
+// listPaged 按 Link 头翻页获取列表接口中 key 字段的全部内容
+func (c *registryClient) listPaged(path, key string) ([]string, error) {
+	var items []string
+	next := c.baseURL + path
+	for next != "" {
+		resp, err := c.do("GET", next, nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page map[string]json.RawMessage
+		var values []string
+		if resp.StatusCode != http.StatusOK {
+			err = registryError("请求", resp)
+		} else if decodeErr := json.NewDecoder(resp.Body).Decode(&page); decodeErr != nil {
+			err = fmt.Errorf("解析响应失败: %v", decodeErr)
+		} else if raw, ok := page[key]; ok {
+			if decodeErr := json.Unmarshal(raw, &values); decodeErr != nil {
+				err = fmt.Errorf("解析响应失败: %v", decodeErr)
+			}
+		}
+		link := resp.Header.Get("Link")
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, values...)
+		next = nextPageURL(c.baseURL, link)
+	}
+	return items, nil
+}
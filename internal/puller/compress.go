@@ -0,0 +1,116 @@
+package puller
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 支持的输出压缩格式
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// NormalizeCompression 规范化压缩格式名称
+func NormalizeCompression(compression string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(compression)) {
+	case "", "none", "tar":
+		return CompressionNone, nil
+	case "gzip", "gz":
+		return CompressionGzip, nil
+	case "zstd", "zst":
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf("不支持的压缩格式: %s（可选: none, gzip, zstd）", compression)
+	}
+}
+
+// CompressionExt 返回压缩格式对应的文件扩展名
+func CompressionExt(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return ".tar.gz"
+	case CompressionZstd:
+		return ".tar.zst"
+	default:
+		return ".tar"
+	}
+}
+
+// nopWriteCloser 不做任何关闭操作的WriteCloser
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressWriter 根据压缩格式包装输出流
+func newCompressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	case CompressionNone, "":
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("不支持的压缩格式: %s", compression)
+	}
+}
+
+// compressionFromMagic 根据数据头部的魔数识别压缩格式
+func compressionFromMagic(magic []byte) string {
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return CompressionGzip
+	case len(magic) >= 4 && magic[0] == 0x28 && magic[1] == 0xb5 && magic[2] == 0x2f && magic[3] == 0xfd:
+		return CompressionZstd
+	default:
+		return CompressionNone
+	}
+}
+
+// DetectFileCompression 识别文件的压缩格式
+func DetectFileCompression(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(file, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return compressionFromMagic(magic[:n]), nil
+}
+
+// newDecompressReader 根据数据头部的魔数自动识别 gzip/zstd 压缩，未压缩数据原样返回
+func newDecompressReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch compressionFromMagic(magic) {
+	case CompressionGzip:
+		return gzip.NewReader(br)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return io.NopCloser(br), nil
+	}
+}
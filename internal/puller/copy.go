@@ -0,0 +1,373 @@
+package puller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"dockerops/internal/config"
+)
+
+// 多架构清单的媒体类型
+const (
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// manifestAccept 复制时请求的清单类型，保留源仓库的原始清单
+var manifestAccept = strings.Join([]string{
+	mediaTypeDockerManifest,
+	mediaTypeDockerManifestList,
+	mediaTypeOCIManifest,
+	mediaTypeOCIIndex,
+}, ", ")
+
+// CopyOptions 仓库间复制选项
+type CopyOptions struct {
+	Arch         string   // 单架构复制时使用的架构
+	Platforms    []string // 多架构复制时要复制的平台，例如 linux/amd64、linux/arm64/v8
+	AllPlatforms bool     // 复制源镜像的全部平台
+	Username     string   // 源仓库用户名
+	Password     string   // 源仓库密码
+	Dest         PushOptions
+	// PreviousDigest 上次复制时源清单的digest，与 PreviousTargetDigest 一起使用：
+	// 源清单未变化且目标标签仍指向上次写入的清单时跳过复制
+	PreviousDigest string
+	// PreviousTargetDigest 上次复制后目标仓库中的清单digest，目标标签被覆盖或删除时重新复制
+	PreviousTargetDigest string
+}
+
+// CopyResult 复制结果
+type CopyResult struct {
+	Source       string        // 源镜像引用
+	Target       string        // 目标镜像引用
+	Registry     string        // 实际使用的源仓库
+	SourceDigest string        // 源仓库中的清单digest
+	Digest       string        // 目标仓库中的清单digest
+	Skipped      bool          // 源清单未变化，未复制
+	Platforms    []string      // 已复制的平台
+	Blobs        int           // 涉及的 blob 数量（含配置）
+	Copied       int           // 实际传输的 blob 数量
+	Mounted      int           // 跨仓库挂载的 blob 数量
+	Existing     int           // 目标仓库中已存在的 blob 数量
+	Size         int64         // 实际传输的字节数
+	Duration     time.Duration // 耗时
+}
+
+// copySession 一次复制涉及的源仓库和目标仓库
+type copySession struct {
+	registry   *config.RegistryConfig
+	repository string
+	token      string
+	client     *registryClient
+	target     PushTarget
+	mountFrom  []string
+	chunkSize  int64
+	result     *CopyResult
+}
+
+// IsManifestIndex 判断清单是否为多架构清单
+func IsManifestIndex(mediaType string, manifest *ManifestResponse) bool {
+	return mediaType == mediaTypeDockerManifestList || mediaType == mediaTypeOCIIndex || len(manifest.Manifests) > 0
+}
+
+// CopyImage 将源镜像从搜索到的最佳镜像仓库直接复制到目标仓库，blob 以流的方式边下载边上传，
+// 不依赖 docker 命令，也不在本地落盘；目标仓库中已存在的 blob 会被跳过
+func (p *MultiRegistryImagePuller) CopyImage(source, target string, options CopyOptions) (*CopyResult, error) {
+	start := time.Now()
+	result := &CopyResult{Source: source, Target: target}
+
+	ref, err := ParsePushTarget(target)
+	if err != nil {
+		return result, err
+	}
+	result.Target = ref.String()
+
+	registry, _, imageInfo, err := p.SearchImageInRegistries(source, options.Arch, options.Username, options.Password)
+	if err != nil {
+		return result, err
+	}
+	result.Registry = registry.URL
+	log.Printf("选择的仓库：%s (%s)", registry.Name, registry.URL)
+
+	token, err := p.GetAuthToken(registry, imageInfo.Repository, options.Username, options.Password)
+	if err != nil {
+		return result, fmt.Errorf("获取认证失败: %v", err)
+	}
+
+	client := p.newRegistryClient(ref.Registry, options.Dest)
+	mountFrom := append(p.pushedRepositories(ref.Registry), options.Dest.MountFrom...)
+	if err := client.authorize(ref.Repository, mountFrom); err != nil {
+		return result, fmt.Errorf("目标仓库认证失败: %v", err)
+	}
+
+	session := &copySession{
+		registry:   registry,
+		repository: imageInfo.Repository,
+		token:      token,
+		client:     client,
+		target:     ref,
+		mountFrom:  mountFrom,
+		chunkSize:  options.Dest.ChunkSize,
+		result:     result,
+	}
+
+	data, mediaType, err := p.fetchRawManifest(registry, imageInfo.Repository, imageInfo.Tag, token)
+	if err != nil {
+		return result, err
+	}
+	var manifest ManifestResponse
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return result, fmt.Errorf("解析清单失败: %v", err)
+	}
+
+	result.SourceDigest = sha256Digest(data)
+	if options.PreviousDigest != "" && options.PreviousDigest == result.SourceDigest {
+		digest, err := client.manifestDigest(ref.Repository, ref.Tag)
+		if err != nil {
+			return result, err
+		}
+		if digest != "" && digest == options.PreviousTargetDigest {
+			log.Printf("⏭️ %s 的清单未变化，跳过复制", source)
+			result.Digest = digest
+			result.Skipped = true
+			result.Duration = time.Since(start)
+			return result, nil
+		}
+		log.Printf("⚠️ %s 的清单未变化，但目标标签 %s 已被修改或删除，重新复制", source, result.Target)
+	}
+
+	multiArch := options.AllPlatforms || len(options.Platforms) > 0
+	switch {
+	case IsManifestIndex(mediaType, &manifest) && multiArch:
+		data, err = p.copyIndex(session, data, &manifest, options)
+	case IsManifestIndex(mediaType, &manifest):
+		digest := p.selectManifest(manifest.Manifests, options.Arch)
+		if digest == "" {
+			return result, fmt.Errorf("源镜像没有 linux/%s 平台", options.Arch)
+		}
+		data, mediaType, err = p.copyManifest(session, digest)
+		result.Platforms = append(result.Platforms, "linux/"+options.Arch)
+	default:
+		if multiArch {
+			log.Printf("⚠️ 源镜像不是多架构镜像，只复制单个平台")
+		}
+		err = p.copyBlobs(session, &manifest, "")
+	}
+	if err != nil {
+		return result, err
+	}
+
+	digest, err := client.putManifest(ref.Repository, ref.Tag, mediaType, data)
+	if err != nil {
+		return result, fmt.Errorf("推送清单失败: %v", err)
+	}
+	result.Digest = digest
+	p.recordPushedRepository(ref.Registry, ref.Repository)
+
+	log.Printf("✅ 已复制 %s 到 %s@%s", source, result.Target, digest)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// copyIndex 复制多架构清单中选中的平台，返回要写入目标标签的清单内容；
+// 复制全部平台时保留原始清单，digest 与源镜像一致
+func (p *MultiRegistryImagePuller) copyIndex(session *copySession, data []byte, index *ManifestResponse, options CopyOptions) ([]byte, error) {
+	var selected []PlatformManifest
+	for _, m := range index.Manifests {
+		if options.AllPlatforms || matchPlatform(m.Platform, options.Platforms) {
+			selected = append(selected, m)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("源镜像中没有匹配 %s 的平台", strings.Join(options.Platforms, ", "))
+	}
+
+	for _, m := range selected {
+		if _, _, err := p.copyManifest(session, m.Digest); err != nil {
+			return nil, err
+		}
+		if m.Platform.OS != "" && m.Platform.OS != "unknown" {
+			session.result.Platforms = append(session.result.Platforms, formatPlatform(m.Platform))
+		}
+	}
+
+	if len(selected) == len(index.Manifests) {
+		return data, nil
+	}
+
+	// 只复制部分平台时重新生成清单，保留其余字段
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析清单失败: %v", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw["manifests"], &entries); err != nil {
+		return nil, fmt.Errorf("解析清单失败: %v", err)
+	}
+	var kept []json.RawMessage
+	for i, entry := range entries {
+		if i < len(index.Manifests) && (options.AllPlatforms || matchPlatform(index.Manifests[i].Platform, options.Platforms)) {
+			kept = append(kept, entry)
+		}
+	}
+	manifests, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	raw["manifests"] = manifests
+	return json.Marshal(raw)
+}
+
+// copyManifest 复制单个平台的清单及其 blob，并以 digest 写入目标仓库，返回清单内容和媒体类型
+func (p *MultiRegistryImagePuller) copyManifest(session *copySession, digest string) ([]byte, string, error) {
+	data, mediaType, err := p.fetchRawManifest(session.registry, session.repository, digest, session.token)
+	if err != nil {
+		return nil, "", err
+	}
+	var manifest ManifestResponse
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("解析清单失败: %v", err)
+	}
+
+	if err := p.copyBlobs(session, &manifest, digest[:19]+" "); err != nil {
+		return nil, "", err
+	}
+	if _, err := session.client.putManifest(session.target.Repository, digest, mediaType, data); err != nil {
+		return nil, "", fmt.Errorf("推送清单 %s 失败: %v", digest[:19], err)
+	}
+	return data, mediaType, nil
+}
+
+// copyBlobs 复制清单引用的配置和层
+func (p *MultiRegistryImagePuller) copyBlobs(session *copySession, manifest *ManifestResponse, prefix string) error {
+	blobs := []LayerDescriptor{{
+		MediaType: manifest.Config.MediaType,
+		Size:      manifest.Config.Size,
+		Digest:    manifest.Config.Digest,
+	}}
+	blobs = append(blobs, manifest.Layers...)
+
+	for i, blob := range blobs {
+		desc := prefix + "Config"
+		if i > 0 {
+			desc = fmt.Sprintf("%sLayer %d/%d", prefix, i, len(manifest.Layers))
+		}
+		blobURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", session.registry.URL, session.repository, blob.Digest)
+		transfer, err := p.transferBlob(session.client, session.target.Repository, blob.Digest, blob.Size, desc, session.mountFrom, session.chunkSize, func() (io.ReadCloser, error) {
+			return p.openBlob(blobURL, session.token)
+		})
+		if err != nil {
+			return fmt.Errorf("复制 blob %s 失败: %v", blob.Digest[:19], err)
+		}
+
+		session.result.Blobs++
+		switch transfer {
+		case blobExisting:
+			session.result.Existing++
+		case blobMounted:
+			session.result.Mounted++
+		default:
+			session.result.Copied++
+			session.result.Size += blob.Size
+		}
+	}
+	return nil
+}
+
+// openBlob 打开源仓库中的 blob 数据流
+func (p *MultiRegistryImagePuller) openBlob(url, token string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("下载失败，状态码: %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// fetchRawManifest 获取原始清单内容和媒体类型，支持 Docker 和 OCI 的单架构及多架构清单
+func (p *MultiRegistryImagePuller) fetchRawManifest(registry *config.RegistryConfig, repository, reference, token string) ([]byte, string, error) {
+	url := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registry.URL, repository, reference)
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Accept", manifestAccept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("获取清单失败，状态码: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, "", fmt.Errorf("读取清单失败: %v", err)
+	}
+	if strings.HasPrefix(reference, "sha256:") && sha256Digest(data) != reference {
+		return nil, "", fmt.Errorf("清单 %s 校验失败", reference)
+	}
+
+	mediaType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	var probe struct {
+		MediaType string `json:"mediaType"`
+	}
+	if json.Unmarshal(data, &probe) == nil && probe.MediaType != "" {
+		mediaType = probe.MediaType
+	}
+	if mediaType == "" {
+		mediaType = mediaTypeDockerManifest
+	}
+	return data, mediaType, nil
+}
+
+// formatPlatform 将平台格式化为 os/arch[/variant]
+func formatPlatform(platform Platform) string {
+	s := platform.OS + "/" + platform.Architecture
+	if platform.Variant != "" {
+		s += "/" + platform.Variant
+	}
+	return s
+}
+
+// matchPlatform 判断平台是否匹配 os/arch[/variant] 列表中的任一项，省略 os 时视为 linux
+func matchPlatform(platform Platform, patterns []string) bool {
+	for _, pattern := range patterns {
+		parts := strings.Split(strings.TrimSpace(pattern), "/")
+		if len(parts) == 1 {
+			parts = []string{"linux", parts[0]}
+		}
+		if parts[0] != platform.OS || parts[1] != platform.Architecture {
+			continue
+		}
+		if len(parts) > 2 && parts[2] != platform.Variant {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,699 @@
+package puller
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EncryptedSuffix 加密归档的文件后缀
+const EncryptedSuffix = ".enc"
+
+const (
+	encryptionMagic     = "DOPSENC1"
+	encryptionChunkSize = 64 << 10
+	encryptionNoncePre  = 7
+	pbkdf2Iterations    = 600000
+	// 解密时接受的迭代次数范围：文件头不可信，过小削弱口令强度，过大可被用来耗尽 CPU
+	minPBKDF2Iterations = 100000
+	maxPBKDF2Iterations = 4 * pbkdf2Iterations
+	x25519KeyInfo       = "dockerops-x25519-v1"
+)
+
+// EncryptOptions 加密选项，口令和接收方公钥可以同时使用，任一方式均可解密
+type EncryptOptions struct {
+	Passphrase []byte
+	Recipients []*ecdh.PublicKey
+}
+
+// Keyring 解密使用的口令和私钥
+type Keyring struct {
+	Passphrase []byte
+	Identities []*ecdh.PrivateKey
+	// PromptPassphrase 需要口令而未提供时调用，用于交互式输入
+	PromptPassphrase func() ([]byte, error)
+}
+
+// encryptionHeader 加密文件头，整体作为每个数据块的附加认证数据
+type encryptionHeader struct {
+	Version     int         `json:"version"`
+	Cipher      string      `json:"cipher"`
+	ChunkSize   int         `json:"chunk_size"`
+	NoncePrefix []byte      `json:"nonce_prefix"`
+	Stanzas     []keyStanza `json:"stanzas"`
+}
+
+// keyStanza 使用口令或接收方公钥包装的文件密钥
+type keyStanza struct {
+	Type       string `json:"type"` // passphrase 或 x25519
+	Salt       []byte `json:"salt,omitempty"`
+	Iterations int    `json:"iterations,omitempty"`
+	KeyID      string `json:"key_id,omitempty"`
+	Ephemeral  []byte `json:"ephemeral,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// KeyFingerprint 返回公钥的指纹（PKIX DER 的 sha256 前 16 位）
+func KeyFingerprint(publicKey any) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// newGCM 创建 AES-256-GCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrapKey 使用密钥加密密钥（KEK）包装文件密钥
+func wrapKey(stanza *keyStanza, kek, fileKey []byte) error {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return err
+	}
+	stanza.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(stanza.Nonce); err != nil {
+		return err
+	}
+	stanza.WrappedKey = aead.Seal(nil, stanza.Nonce, fileKey, []byte(stanza.Type))
+	return nil
+}
+
+// unwrapKey 解开文件密钥
+func unwrapKey(stanza keyStanza, kek []byte) ([]byte, error) {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, stanza.Nonce, stanza.WrappedKey, []byte(stanza.Type))
+}
+
+// x25519KEK 通过 X25519 共享密钥派生密钥加密密钥
+func x25519KEK(shared, ephemeral, recipient []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	return hkdf.Key(sha256.New, shared, salt, x25519KeyInfo, 32)
+}
+
+// newEncryptionHeader 生成文件密钥并为每个口令或接收方包装
+func newEncryptionHeader(options *EncryptOptions) (*encryptionHeader, []byte, error) {
+	if len(options.Passphrase) == 0 && len(options.Recipients) == 0 {
+		return nil, nil, fmt.Errorf("加密需要口令或接收方公钥")
+	}
+
+	fileKey := make([]byte, 32)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, nil, err
+	}
+
+	header := &encryptionHeader{
+		Version:     1,
+		Cipher:      "aes-256-gcm",
+		ChunkSize:   encryptionChunkSize,
+		NoncePrefix: make([]byte, encryptionNoncePre),
+	}
+	if _, err := rand.Read(header.NoncePrefix); err != nil {
+		return nil, nil, err
+	}
+
+	if len(options.Passphrase) > 0 {
+		stanza := keyStanza{Type: "passphrase", Salt: make([]byte, 16), Iterations: pbkdf2Iterations}
+		if _, err := rand.Read(stanza.Salt); err != nil {
+			return nil, nil, err
+		}
+		kek, err := pbkdf2.Key(sha256.New, string(options.Passphrase), stanza.Salt, stanza.Iterations, 32)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := wrapKey(&stanza, kek, fileKey); err != nil {
+			return nil, nil, err
+		}
+		header.Stanzas = append(header.Stanzas, stanza)
+	}
+
+	for _, recipient := range options.Recipients {
+		ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		shared, err := ephemeral.ECDH(recipient)
+		if err != nil {
+			return nil, nil, err
+		}
+		kek, err := x25519KEK(shared, ephemeral.PublicKey().Bytes(), recipient.Bytes())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stanza := keyStanza{Type: "x25519", KeyID: KeyFingerprint(recipient), Ephemeral: ephemeral.PublicKey().Bytes()}
+		if err := wrapKey(&stanza, kek, fileKey); err != nil {
+			return nil, nil, err
+		}
+		header.Stanzas = append(header.Stanzas, stanza)
+	}
+
+	return header, fileKey, nil
+}
+
+// unlock 使用口令或私钥解开文件密钥
+func (h *encryptionHeader) unlock(keys *Keyring) ([]byte, error) {
+	if keys == nil {
+		keys = &Keyring{}
+	}
+
+	hasPassphrase := false
+	for _, stanza := range h.Stanzas {
+		switch stanza.Type {
+		case "x25519":
+			for _, identity := range keys.Identities {
+				if stanza.KeyID != KeyFingerprint(identity.PublicKey()) {
+					continue
+				}
+				ephemeral, err := ecdh.X25519().NewPublicKey(stanza.Ephemeral)
+				if err != nil {
+					return nil, fmt.Errorf("加密文件头损坏: %v", err)
+				}
+				shared, err := identity.ECDH(ephemeral)
+				if err != nil {
+					return nil, err
+				}
+				kek, err := x25519KEK(shared, stanza.Ephemeral, identity.PublicKey().Bytes())
+				if err != nil {
+					return nil, err
+				}
+				if fileKey, err := unwrapKey(stanza, kek); err == nil {
+					return fileKey, nil
+				}
+			}
+		case "passphrase":
+			hasPassphrase = true
+		}
+	}
+
+	if !hasPassphrase {
+		return nil, fmt.Errorf("解密失败：没有与接收方匹配的私钥")
+	}
+	if len(keys.Passphrase) == 0 && keys.PromptPassphrase != nil {
+		passphrase, err := keys.PromptPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		keys.Passphrase = passphrase
+	}
+	if len(keys.Passphrase) == 0 {
+		return nil, fmt.Errorf("解密失败：需要口令或匹配的私钥")
+	}
+
+	for _, stanza := range h.Stanzas {
+		if stanza.Type != "passphrase" {
+			continue
+		}
+		if stanza.Iterations < minPBKDF2Iterations || stanza.Iterations > maxPBKDF2Iterations {
+			return nil, fmt.Errorf("解密失败：口令迭代次数 %d 超出允许范围（%d-%d）", stanza.Iterations, minPBKDF2Iterations, maxPBKDF2Iterations)
+		}
+		kek, err := pbkdf2.Key(sha256.New, string(keys.Passphrase), stanza.Salt, stanza.Iterations, 32)
+		if err != nil {
+			return nil, err
+		}
+		if fileKey, err := unwrapKey(stanza, kek); err == nil {
+			return fileKey, nil
+		}
+	}
+	return nil, fmt.Errorf("解密失败：口令错误")
+}
+
+// chunkNonce 数据块的 nonce：随机前缀 + 块序号 + 是否为最后一块
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptWriter 按固定大小分块加密，最后一块带结束标记以检测截断
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	aad     []byte
+	counter uint32
+	buf     []byte
+}
+
+// newEncryptWriter 写入加密文件头并返回加密写入器，Close 时写入最后一块
+func newEncryptWriter(w io.Writer, options *EncryptOptions) (io.WriteCloser, error) {
+	header, fileKey, err := newEncryptionHeader(options)
+	if err != nil {
+		return nil, err
+	}
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var prelude bytes.Buffer
+	prelude.WriteString(encryptionMagic)
+	binary.Write(&prelude, binary.BigEndian, uint32(len(headerData)))
+	prelude.Write(headerData)
+	if _, err := w.Write(prelude.Bytes()); err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	aad := sha256.Sum256(prelude.Bytes())
+	return &encryptWriter{w: w, aead: aead, prefix: header.NoncePrefix, aad: aad[:]}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	// 保留至少一个字节，保证最后一块在 Close 时写出
+	for len(e.buf) > encryptionChunkSize {
+		if err := e.seal(e.buf[:encryptionChunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[encryptionChunkSize:]
+	}
+	return len(p), nil
+}
+
+func (e *encryptWriter) seal(chunk []byte, last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter, last), chunk, e.aad)
+	e.counter++
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(e.buf, true)
+}
+
+// decryptReader 逐块解密并校验
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	aad     []byte
+	counter uint32
+	chunk   []byte
+	buf     []byte
+	done    bool
+}
+
+// IsEncrypted 检查数据是否以加密文件头开始
+func IsEncrypted(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte(encryptionMagic))
+}
+
+// IsEncryptedFile 检查文件（包括分卷索引）是否为加密归档
+func IsEncryptedFile(path string) bool {
+	stream, err := openArchiveStream(path)
+	if err != nil {
+		return false
+	}
+	defer stream.Close()
+
+	magic := make([]byte, len(encryptionMagic))
+	n, _ := io.ReadFull(stream, magic)
+	return IsEncrypted(magic[:n])
+}
+
+// newDecryptReader 读取加密文件头并返回解密后的数据流
+func newDecryptReader(r io.Reader, keys *Keyring) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, encryptionChunkSize+64)
+
+	prelude := make([]byte, len(encryptionMagic)+4)
+	if _, err := io.ReadFull(br, prelude); err != nil {
+		return nil, fmt.Errorf("读取加密文件头失败: %v", err)
+	}
+	if !IsEncrypted(prelude) {
+		return nil, fmt.Errorf("不是加密归档")
+	}
+	headerLen := binary.BigEndian.Uint32(prelude[len(encryptionMagic):])
+	if headerLen > maxMetadataSize {
+		return nil, fmt.Errorf("加密文件头损坏")
+	}
+	headerData := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, headerData); err != nil {
+		return nil, fmt.Errorf("读取加密文件头失败: %v", err)
+	}
+
+	var header encryptionHeader
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("解析加密文件头失败: %v", err)
+	}
+	if header.Version != 1 || header.Cipher != "aes-256-gcm" || header.ChunkSize != encryptionChunkSize || len(header.NoncePrefix) != encryptionNoncePre {
+		return nil, fmt.Errorf("不支持的加密格式")
+	}
+
+	fileKey, err := header.unlock(keys)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aad := sha256.New()
+	aad.Write(prelude)
+	aad.Write(headerData)
+	return &decryptReader{
+		r:      br,
+		aead:   aead,
+		prefix: header.NoncePrefix,
+		aad:    aad.Sum(nil),
+		chunk:  make([]byte, encryptionChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next 解密下一块；之后没有数据时该块必须带结束标记
+func (d *decryptReader) next() error {
+	n, err := io.ReadFull(d.r, d.chunk)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("加密归档被截断")
+	}
+
+	_, peekErr := d.r.Peek(1)
+	last := errors.Is(peekErr, io.EOF)
+	if !last && n < len(d.chunk) {
+		return fmt.Errorf("加密归档损坏")
+	}
+
+	plain, err := d.aead.Open(nil, chunkNonce(d.prefix, d.counter, last), d.chunk[:n], d.aad)
+	if err != nil {
+		if last {
+			return fmt.Errorf("加密归档损坏或被截断")
+		}
+		return fmt.Errorf("加密归档第 %d 块校验失败，文件已损坏或被篡改", d.counter+1)
+	}
+	d.counter++
+	d.buf = plain
+	d.done = last
+	return nil
+}
+
+// EncryptFile 加密文件为 <path>.enc 并删除原文件，返回加密后的路径
+func EncryptFile(path string, options *EncryptOptions) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	encPath := path + EncryptedSuffix
+	out, err := os.Create(encPath)
+	if err != nil {
+		return "", fmt.Errorf("创建加密文件失败: %v", err)
+	}
+
+	err = func() error {
+		defer out.Close()
+		writer, err := newEncryptWriter(out, options)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(writer, src); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		return out.Close()
+	}()
+	if err != nil {
+		os.Remove(encPath)
+		return "", fmt.Errorf("加密 %s 失败: %v", path, err)
+	}
+
+	src.Close()
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("删除未加密文件失败: %v", err)
+	}
+	return encPath, nil
+}
+
+// OpenArchive 打开归档并返回明文数据流：分卷会按顺序合并并校验，加密归档使用 keys 解密
+func OpenArchive(path string, keys *Keyring) (io.ReadCloser, error) {
+	stream, err := openArchiveStream(path)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(stream)
+	magic, _ := br.Peek(len(encryptionMagic))
+	if !IsEncrypted(magic) {
+		return readCloser{br, stream}, nil
+	}
+
+	plain, err := newDecryptReader(br, keys)
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	return readCloser{plain, stream}, nil
+}
+
+// OpenImageTar 打开归档并返回可直接导入的 tar 数据流：在 OpenArchive 的基础上自动解压 gzip/zstd
+func OpenImageTar(path string, keys *Keyring) (io.ReadCloser, error) {
+	archive, err := OpenArchive(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newDecompressReader(archive)
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("解压 %s 失败: %v", path, err)
+	}
+	return readCloser{reader, closers{reader, archive}}, nil
+}
+
+// readCloser 组合读取器和需要关闭的底层流
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// closers 依次关闭多个流，返回第一个错误
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var first error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// DecryptFile 解密归档到 output，返回写入的字节数
+func DecryptFile(path, output string, keys *Keyring, force bool) (int64, error) {
+	if !force {
+		if _, err := os.Stat(output); err == nil {
+			return 0, fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", output)
+		}
+	}
+
+	reader, err := OpenArchive(path, keys)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	tmpFile := output + ".decrypting"
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, reader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		return 0, err
+	}
+	if err := os.Rename(tmpFile, output); err != nil {
+		os.Remove(tmpFile)
+		return 0, err
+	}
+	return n, nil
+}
+
+// DecryptedName 返回加密归档解密后的默认文件名
+func DecryptedName(path string) string {
+	path = strings.TrimSuffix(path, SplitIndexSuffix)
+	return strings.TrimSuffix(path, EncryptedSuffix)
+}
+
+// GenerateKeyPair 生成密钥对并写入 <base>.key（私钥）和 <base>.pub（公钥），
+// kind 为 x25519（加密接收方）或 ed25519（签名）
+func GenerateKeyPair(kind, base string) (string, error) {
+	var privateKey, publicKey any
+	switch kind {
+	case "x25519":
+		key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		privateKey, publicKey = key, key.PublicKey()
+	case "ed25519":
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		privateKey, publicKey = key, pub
+	default:
+		return "", fmt.Errorf("不支持的密钥类型: %s（可选: x25519, ed25519）", kind)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	for _, path := range []string{base + ".key", base + ".pub"} {
+		if _, err := os.Stat(path); err == nil {
+			return "", fmt.Errorf("密钥文件 %s 已存在", path)
+		}
+	}
+	if err := os.WriteFile(base+".key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		return "", err
+	}
+	return KeyFingerprint(publicKey), nil
+}
+
+// readPEM 读取 PEM 文件中的第一个块
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s 不是 %s PEM 文件", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// LoadRecipient 加载接收方的 X25519 公钥
+func LoadRecipient(path string) (*ecdh.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析公钥 %s 失败: %v", path, err)
+	}
+	recipient, ok := key.(*ecdh.PublicKey)
+	if !ok || recipient.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("%s 不是 X25519 公钥", path)
+	}
+	return recipient, nil
+}
+
+// LoadIdentity 加载用于解密的 X25519 私钥
+func LoadIdentity(path string) (*ecdh.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥 %s 失败: %v", path, err)
+	}
+	identity, ok := key.(*ecdh.PrivateKey)
+	if !ok || identity.Curve() != ecdh.X25519() {
+		return nil, fmt.Errorf("%s 不是 X25519 私钥", path)
+	}
+	return identity, nil
+}
+
+// LoadSigningKey 加载 Ed25519 签名私钥
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥 %s 失败: %v", path, err)
+	}
+	signingKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是 Ed25519 私钥", path)
+	}
+	return signingKey, nil
+}
+
+// LoadVerifyKey 加载 Ed25519 签名公钥
+func LoadVerifyKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("解析公钥 %s 失败: %v", path, err)
+	}
+	verifyKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s 不是 Ed25519 公钥", path)
+	}
+	return verifyKey, nil
+}
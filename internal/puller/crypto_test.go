@@ -0,0 +1,320 @@
+package puller
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encryptTestFile 写入明文并加密，返回加密文件路径
+func encryptTestFile(t *testing.T, plain []byte, options *EncryptOptions) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(path, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	encPath, err := EncryptFile(path, options)
+	if err != nil {
+		t.Fatalf("EncryptFile: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("加密后应删除明文文件")
+	}
+	return encPath
+}
+
+// readArchive 通过 OpenArchive 读取全部明文
+func readArchive(path string, keys *Keyring) ([]byte, error) {
+	reader, err := OpenArchive(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func newIdentity(t *testing.T) *ecdh.PrivateKey {
+	t.Helper()
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEncryptRoundTripChunkBoundaries(t *testing.T) {
+	identity := newIdentity(t)
+	options := &EncryptOptions{Recipients: []*ecdh.PublicKey{identity.PublicKey()}}
+	keys := &Keyring{Identities: []*ecdh.PrivateKey{identity}}
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"空文件", 0},
+		{"单字节", 1},
+		{"不足一块", encryptionChunkSize - 1},
+		{"正好一块", encryptionChunkSize},
+		{"超过一块", encryptionChunkSize + 1},
+		{"多块", 3*encryptionChunkSize + 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := randomBytes(t, tt.size)
+			encPath := encryptTestFile(t, plain, options)
+
+			got, err := readArchive(encPath, keys)
+			if err != nil {
+				t.Fatalf("OpenArchive: %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("解密结果不一致：长度 %d，期望 %d", len(got), len(plain))
+			}
+		})
+	}
+}
+
+func TestEncryptKeyWrapping(t *testing.T) {
+	identity := newIdentity(t)
+	other := newIdentity(t)
+	plain := randomBytes(t, 1000)
+	encPath := encryptTestFile(t, plain, &EncryptOptions{
+		Passphrase: []byte("correct horse"),
+		Recipients: []*ecdh.PublicKey{identity.PublicKey()},
+	})
+
+	tests := []struct {
+		name    string
+		keys    *Keyring
+		wantErr string
+	}{
+		{"接收方私钥", &Keyring{Identities: []*ecdh.PrivateKey{identity}}, ""},
+		{"口令", &Keyring{Passphrase: []byte("correct horse")}, ""},
+		{"其他私钥回退到口令", &Keyring{Identities: []*ecdh.PrivateKey{other}, Passphrase: []byte("correct horse")}, ""},
+		{"错误口令", &Keyring{Passphrase: []byte("wrong")}, "口令错误"},
+		{"不匹配的私钥", &Keyring{Identities: []*ecdh.PrivateKey{other}}, "需要口令或匹配的私钥"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readArchive(encPath, tt.keys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenArchive: %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("解密结果不一致")
+			}
+		})
+	}
+}
+
+// splitEncrypted 拆分加密文件为前导（魔数和长度）、文件头和数据块部分
+func splitEncrypted(t *testing.T, data []byte) (prelude, header, body []byte) {
+	t.Helper()
+	n := len(encryptionMagic) + 4
+	headerLen := int(binary.BigEndian.Uint32(data[len(encryptionMagic):n]))
+	return data[:n], data[n : n+headerLen], data[n+headerLen:]
+}
+
+func TestDecryptDetectsTampering(t *testing.T) {
+	identity := newIdentity(t)
+	keys := &Keyring{Identities: []*ecdh.PrivateKey{identity}}
+	plain := randomBytes(t, 2*encryptionChunkSize+100)
+	encPath := encryptTestFile(t, plain, &EncryptOptions{Recipients: []*ecdh.PublicKey{identity.PublicKey()}})
+	original, err := os.ReadFile(encPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prelude, header, body := splitEncrypted(t, original)
+	sealedChunk := encryptionChunkSize + 16
+
+	tests := []struct {
+		name   string
+		mutate func([]byte) []byte
+	}{
+		{"修改第一块", func(data []byte) []byte {
+			data[len(prelude)+len(header)+10] ^= 0x01
+			return data
+		}},
+		{"修改最后一块", func(data []byte) []byte {
+			data[len(data)-1] ^= 0x01
+			return data
+		}},
+		{"截断最后一块", func(data []byte) []byte {
+			return data[:len(prelude)+len(header)+2*sealedChunk]
+		}},
+		{"截断在块中间", func(data []byte) []byte {
+			return data[:len(data)-50]
+		}},
+		{"追加数据", func(data []byte) []byte {
+			return append(data, 0)
+		}},
+		{"交换数据块顺序", func(data []byte) []byte {
+			start := len(prelude) + len(header)
+			swapped := append([]byte{}, data[:start]...)
+			swapped = append(swapped, body[sealedChunk:2*sealedChunk]...)
+			swapped = append(swapped, body[:sealedChunk]...)
+			return append(swapped, body[2*sealedChunk:]...)
+		}},
+		{"修改文件头", func(data []byte) []byte {
+			// 文件头作为附加认证数据，修改块大小以外的字段也会导致校验失败
+			modified := bytes.Replace(header, []byte(`"version":1`), []byte(`"version":1 `), 1)
+			out := binary.BigEndian.AppendUint32([]byte(encryptionMagic), uint32(len(modified)))
+			out = append(out, modified...)
+			return append(out, body...)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tampered.tar.enc")
+			if err := os.WriteFile(path, tt.mutate(append([]byte{}, original...)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readArchive(path, keys); err == nil {
+				t.Fatalf("篡改后的归档不应解密成功")
+			}
+		})
+	}
+}
+
+func TestDecryptRejectsIterationCount(t *testing.T) {
+	encPath := encryptTestFile(t, []byte("data"), &EncryptOptions{Passphrase: []byte("secret")})
+	original, err := os.ReadFile(encPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, headerData, body := splitEncrypted(t, original)
+
+	for _, iterations := range []int{1, minPBKDF2Iterations - 1, maxPBKDF2Iterations + 1} {
+		var header encryptionHeader
+		if err := json.Unmarshal(headerData, &header); err != nil {
+			t.Fatal(err)
+		}
+		header.Stanzas[0].Iterations = iterations
+		modified, err := json.Marshal(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := binary.BigEndian.AppendUint32([]byte(encryptionMagic), uint32(len(modified)))
+		data = append(append(data, modified...), body...)
+
+		path := filepath.Join(t.TempDir(), "iterations.tar.enc")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err = readArchive(path, &Keyring{Passphrase: []byte("secret")})
+		if err == nil || !strings.Contains(err.Error(), "迭代次数") {
+			t.Fatalf("迭代次数 %d: 期望拒绝，实际 %v", iterations, err)
+		}
+	}
+}
+
+func TestBundleSignature(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		trusted []ed25519.PublicKey
+		mutate  func(t *testing.T, dir string)
+		wantErr string
+	}{
+		{"校验通过", []ed25519.PublicKey{public}, nil, ""},
+		{"多个公钥之一匹配", []ed25519.PublicKey{otherPublic, public}, nil, ""},
+		{"签名者不受信任", []ed25519.PublicKey{otherPublic}, nil, "签名校验失败"},
+		{"清单被篡改", []ed25519.PublicKey{public}, func(t *testing.T, dir string) {
+			path := filepath.Join(dir, BundleManifestName)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = bytes.Replace(data, []byte("nginx"), []byte("evil!"), 1)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, "签名校验失败"},
+		{"签名被篡改", []ed25519.PublicKey{public}, func(t *testing.T, dir string) {
+			path := filepath.Join(dir, BundleSignatureName)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var signature BundleSignature
+			if err := json.Unmarshal(data, &signature); err != nil {
+				t.Fatal(err)
+			}
+			signature.Signature[0] ^= 0x01
+			if data, err = json.Marshal(signature); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, "签名校验失败"},
+		{"缺少签名", []ed25519.PublicKey{public}, func(t *testing.T, dir string) {
+			if err := os.Remove(filepath.Join(dir, BundleSignatureName)); err != nil {
+				t.Fatal(err)
+			}
+		}, "清单未签名"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			manifest := `{"files":[{"name":"nginx.tar","sha256":"sha256:00"}]}`
+			if err := os.WriteFile(filepath.Join(dir, BundleManifestName), []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := SignBundleManifest(dir, private); err != nil {
+				t.Fatalf("SignBundleManifest: %v", err)
+			}
+			if !HasBundleSignature(dir) {
+				t.Fatalf("签名后应存在签名文件")
+			}
+			if tt.mutate != nil {
+				tt.mutate(t, dir)
+			}
+
+			signer, err := VerifyBundleSignature(dir, tt.trusted)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyBundleSignature: %v", err)
+			}
+			if signer != KeyFingerprint(public) {
+				t.Fatalf("签名者指纹 %s，期望 %s", signer, KeyFingerprint(public))
+			}
+		})
+	}
+}
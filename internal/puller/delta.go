@@ -0,0 +1,644 @@
+package puller
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DeltaMetadataName 增量归档中记录缺失层的元数据文件名
+const DeltaMetadataName = "dockerops-delta.json"
+
+// DeltaInfo 增量归档的元数据，记录归档中省略的、需要从基线补全的层
+type DeltaInfo struct {
+	Version  int          `json:"version"`
+	Baseline string       `json:"baseline"` // 基线清单文件的 sha256
+	Layers   []DeltaLayer `json:"layers"`
+}
+
+// DeltaLayer 增量归档中省略的一层
+type DeltaLayer struct {
+	Path     string   `json:"path"` // 归档内的层路径
+	DiffID   string   `json:"diff_id"`
+	File     string   `json:"file,omitempty"`      // 基线中包含该层的归档
+	RepoTags []string `json:"repo_tags,omitempty"` // 基线中包含该层的镜像
+}
+
+// MergeResult 合并增量归档的结果
+type MergeResult struct {
+	OutputFile string
+	Restored   int // 从基线补全的层数量
+	Size       int64
+}
+
+// parseDeltaInfo 解析增量元数据
+func parseDeltaInfo(data []byte) (*DeltaInfo, error) {
+	var delta DeltaInfo
+	if err := json.Unmarshal(data, &delta); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", DeltaMetadataName, err)
+	}
+	return &delta, nil
+}
+
+// ReadDeltaInfo 读取增量归档的元数据
+func ReadDeltaInfo(archivePath string) (*DeltaInfo, error) {
+	data, err := ReadArchiveFile(archivePath, DeltaMetadataName)
+	if err != nil {
+		return nil, fmt.Errorf("%s 不是增量归档: %v", archivePath, err)
+	}
+	return parseDeltaInfo(data)
+}
+
+// LoadBaseline 从离线传输清单加载基线中已有的层，path 可以是清单文件或其所在目录
+func LoadBaseline(baselinePath string) (map[string]DeltaLayer, string, error) {
+	if stat, err := os.Stat(baselinePath); err == nil && stat.IsDir() {
+		baselinePath = filepath.Join(baselinePath, BundleManifestName)
+	}
+
+	data, err := os.ReadFile(baselinePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取基线清单失败: %v", err)
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("解析基线清单 %s 失败: %v", baselinePath, err)
+	}
+
+	layers := make(map[string]DeltaLayer)
+	for _, file := range manifest.Files {
+		for _, image := range file.Images {
+			if len(image.DiffIDs) != image.Layers {
+				return nil, "", fmt.Errorf("基线清单中 %s 缺少层信息（diff_ids），请重新生成清单", file.Name)
+			}
+			for _, diffID := range image.DiffIDs {
+				if _, ok := layers[diffID]; !ok {
+					layers[diffID] = DeltaLayer{DiffID: diffID, File: file.Name, RepoTags: image.RepoTags}
+				}
+			}
+		}
+	}
+
+	return layers, sha256Digest(data), nil
+}
+
+// setBaseline 为层存储设置基线，之后基线中已有的层不再下载
+func (s *layerStore) setBaseline(baselinePath string) error {
+	if baselinePath == "" {
+		return nil
+	}
+	layers, digest, err := LoadBaseline(baselinePath)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseline = layers
+	s.baselineDigest = digest
+	return nil
+}
+
+// fromBaseline 若该层已在基线中，则登记为缺失层并返回 true
+func (s *layerStore) fromBaseline(diffID, layerPath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	source, ok := s.baseline[diffID]
+	if !ok {
+		return false
+	}
+	source.Path = layerPath
+	s.missing = append(s.missing, source)
+	return true
+}
+
+// writeDeltaMetadata 设置了基线时在工作目录写入增量元数据
+func (s *layerStore) writeDeltaMetadata(workDir string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.baseline == nil {
+		return nil
+	}
+
+	layers := append([]DeltaLayer{}, s.missing...)
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].Path < layers[j].Path
+	})
+
+	data, err := json.MarshalIndent(DeltaInfo{Version: 1, Baseline: s.baselineDigest, Layers: layers}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化增量元数据失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, DeltaMetadataName), data, 0644); err != nil {
+		return fmt.Errorf("写入增量元数据失败: %v", err)
+	}
+	return nil
+}
+
+// baselineLayers 返回因基线已有而省略的层数量
+func (s *layerStore) baselineLayers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.missing)
+}
+
+// WriteDeltaArchive 将已有归档（例如 docker save 的输出）改写为相对于基线的增量归档，
+// 返回省略的层数量
+func WriteDeltaArchive(archivePath, baselinePath string) (int, error) {
+	baseline, baselineDigest, err := LoadBaseline(baselinePath)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := InspectArchive(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	if !info.OK() {
+		return 0, fmt.Errorf("归档 %s 校验失败，无法生成增量归档", archivePath)
+	}
+	if info.Delta {
+		return 0, fmt.Errorf("%s 已经是增量归档", archivePath)
+	}
+
+	entries, err := ReadArchiveManifest(archivePath)
+	if err != nil {
+		return 0, err
+	}
+
+	delta := DeltaInfo{Version: 1, Baseline: baselineDigest}
+	drop := make(map[string]bool)
+	for i, entry := range entries {
+		for j, layerPath := range entry.Layers {
+			diffID := info.Images[i].DiffIDs[j]
+			source, ok := baseline[diffID]
+			if !ok {
+				continue
+			}
+			layerPath = path.Clean(layerPath)
+			if drop[layerPath] {
+				continue
+			}
+			drop[layerPath] = true
+			source.Path = layerPath
+			delta.Layers = append(delta.Layers, source)
+		}
+	}
+
+	if err := writeDeltaCopy(archivePath, &delta, drop); err != nil {
+		return 0, err
+	}
+	return len(delta.Layers), nil
+}
+
+// writeDeltaCopy 复制归档并省略 drop 中的层，写入增量元数据后替换原文件
+func writeDeltaCopy(archivePath string, delta *DeltaInfo, drop map[string]bool) error {
+	compression, err := DetectFileCompression(archivePath)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	reader, err := newDecompressReader(src)
+	if err != nil {
+		return fmt.Errorf("解压归档失败: %v", err)
+	}
+	defer reader.Close()
+
+	tmpFile := archivePath + ".delta"
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return fmt.Errorf("创建增量归档失败: %v", err)
+	}
+
+	err = func() error {
+		defer out.Close()
+
+		compressWriter, err := newCompressWriter(out, compression)
+		if err != nil {
+			return err
+		}
+		tarWriter := tar.NewWriter(compressWriter)
+
+		data, err := json.MarshalIndent(delta, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeTarBytes(tarWriter, DeltaMetadataName, data); err != nil {
+			return err
+		}
+
+		tarReader := tar.NewReader(reader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("读取归档失败: %v", err)
+			}
+
+			name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+			if drop[name] {
+				continue
+			}
+			if header.Typeflag == tar.TypeSymlink && drop[path.Clean(path.Join(path.Dir(name), header.Linkname))] {
+				continue
+			}
+
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+			if _, err := io.Copy(tarWriter, tarReader); err != nil {
+				return err
+			}
+		}
+
+		if err := tarWriter.Close(); err != nil {
+			return err
+		}
+		return compressWriter.Close()
+	}()
+	if err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("写入增量归档失败: %v", err)
+	}
+
+	src.Close()
+	if err := os.Rename(tmpFile, archivePath); err != nil {
+		os.Remove(tmpFile)
+		return fmt.Errorf("替换归档失败: %v", err)
+	}
+	return nil
+}
+
+// writeTarBytes 向 tar 写入一个普通文件
+func writeTarBytes(tarWriter *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  time.Now(),
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(data)
+	return err
+}
+
+// CollectArchives 将目录展开为其中的镜像归档，文件原样返回
+func CollectArchives(paths []string) ([]string, error) {
+	var archives []string
+	for _, p := range paths {
+		stat, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			archives = append(archives, p)
+			continue
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && IsArchiveFile(entry.Name()) {
+				archives = append(archives, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	return archives, nil
+}
+
+// extractBaselineLayers 从基线归档中提取所需的层，found 记录 diff_id 到提取文件的映射。
+// 先读取 manifest.json 和镜像配置中的 rootfs.diff_ids 确定需要的层文件，再只提取这些层
+func extractBaselineLayers(archivePath string, needed map[string]bool, found map[string]string, workDir string) error {
+	// 第一遍只读取元数据（manifest.json、镜像配置）和符号链接，跳过层数据
+	contents := make(map[string][]byte)
+	links := make(map[string]string)
+	err := scanArchive(archivePath, func(name string, header *tar.Header, reader io.Reader) (bool, error) {
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			links[name] = path.Clean(path.Join(path.Dir(name), header.Linkname))
+		case tar.TypeLink:
+			links[name] = path.Clean(header.Linkname)
+		case tar.TypeReg:
+			if header.Size > maxMetadataSize || (name != "manifest.json" && !strings.HasSuffix(name, ".json") && path.Dir(name) != "blobs/sha256") {
+				return true, nil
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return false, fmt.Errorf("读取 %s 失败: %v", name, err)
+			}
+			contents[name] = data
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	resolve := func(name string) string {
+		name = path.Clean(strings.TrimPrefix(name, "./"))
+		for i := 0; i < 16; i++ {
+			target, ok := links[name]
+			if !ok {
+				break
+			}
+			name = target
+		}
+		return name
+	}
+
+	manifestData, ok := contents["manifest.json"]
+	if !ok {
+		return fmt.Errorf("归档中未找到 manifest.json")
+	}
+	var entries []ArchiveManifestEntry
+	if err := json.Unmarshal(manifestData, &entries); err != nil {
+		return fmt.Errorf("解析 manifest.json 失败: %v", err)
+	}
+
+	// wanted 记录需要提取的层文件及其 diff_id
+	wanted := make(map[string]string)
+	for _, entry := range entries {
+		var imageConfig ImageConfig
+		if err := json.Unmarshal(contents[resolve(entry.Config)], &imageConfig); err != nil {
+			return fmt.Errorf("解析镜像配置 %s 失败: %v", entry.Config, err)
+		}
+		if len(imageConfig.RootFS.DiffIDs) != len(entry.Layers) {
+			return fmt.Errorf("镜像配置 %s 中的 diff_ids 与层数量不一致", entry.Config)
+		}
+		for i, layerPath := range entry.Layers {
+			diffID := imageConfig.RootFS.DiffIDs[i]
+			if needed[diffID] && found[diffID] == "" {
+				wanted[resolve(layerPath)] = diffID
+			}
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	// 第二遍只提取需要的层，并校验内容与 diff_id 一致
+	remaining := len(wanted)
+	return scanArchive(archivePath, func(name string, header *tar.Header, reader io.Reader) (bool, error) {
+		diffID, ok := wanted[name]
+		if !ok || header.Typeflag != tar.TypeReg || found[diffID] != "" {
+			return true, nil
+		}
+
+		tmpPath := filepath.Join(workDir, "layer.extracting")
+		out, err := os.Create(tmpPath)
+		if err != nil {
+			return false, err
+		}
+		hasher := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, hasher), reader)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(tmpPath)
+			return false, fmt.Errorf("读取 %s 失败: %v", name, err)
+		}
+
+		remaining--
+		if digest := "sha256:" + hex.EncodeToString(hasher.Sum(nil)); digest != diffID {
+			os.Remove(tmpPath)
+			log.Printf("⚠️ 基线层 %s 已损坏（期望 %s，实际 %s）", name, diffID, digest)
+			return remaining > 0, nil
+		}
+		layerFile := filepath.Join(workDir, diffID[7:]+".tar")
+		if err := os.Rename(tmpPath, layerFile); err != nil {
+			return false, err
+		}
+		found[diffID] = layerFile
+		return remaining > 0, nil
+	})
+}
+
+// scanArchive 按顺序遍历归档（支持 gzip/zstd 压缩及分卷索引）中的条目，name 为规范化后的路径；
+// fn 返回 false 时停止遍历。未压缩的归档直接读取文件，tar 可以通过 Seek 跳过未读取的数据
+func scanArchive(archivePath string, fn func(name string, header *tar.Header, reader io.Reader) (bool, error)) error {
+	compression := CompressionGzip
+	if !IsSplitIndex(archivePath) {
+		detected, err := DetectFileCompression(archivePath)
+		if err != nil {
+			return err
+		}
+		compression = detected
+	}
+
+	file, err := openArchiveStream(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if compression != CompressionNone {
+		decompressReader, err := newDecompressReader(file)
+		if err != nil {
+			return fmt.Errorf("解压归档失败: %v", err)
+		}
+		defer decompressReader.Close()
+		reader = decompressReader
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取归档失败: %v", err)
+		}
+		next, err := fn(path.Clean(strings.TrimPrefix(header.Name, "./")), header, tarReader)
+		if err != nil || !next {
+			return err
+		}
+	}
+}
+
+// MergeDelta 从基线归档中补全增量归档缺少的层，生成可以直接 docker load 的完整归档。
+// compression 为空时沿用增量归档的压缩格式
+func MergeDelta(deltaPath string, bases []string, output, compression string, force bool) (*MergeResult, error) {
+	delta, err := ReadDeltaInfo(deltaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if compression == "" {
+		if compression, err = detectArchiveCompression(deltaPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if !force {
+		if _, err := os.Stat(output); err == nil {
+			return nil, fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", output)
+		}
+	}
+
+	workDir, err := newWorkDir("merge-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	// 在基线归档中查找缺少的层
+	needed := make(map[string]bool)
+	for _, layer := range delta.Layers {
+		needed[layer.DiffID] = true
+	}
+	found := make(map[string]string)
+
+	archives, err := CollectArchives(bases)
+	if err != nil {
+		return nil, err
+	}
+	for _, archive := range archives {
+		if len(found) == len(needed) {
+			break
+		}
+		log.Printf("正在从 %s 查找基线层...", archive)
+		if err := extractBaselineLayers(archive, needed, found, workDir); err != nil {
+			log.Printf("⚠️ 读取基线归档 %s 失败: %v", archive, err)
+		}
+	}
+
+	var absent []string
+	reported := make(map[string]bool)
+	for _, layer := range delta.Layers {
+		if found[layer.DiffID] != "" || reported[layer.DiffID] {
+			continue
+		}
+		reported[layer.DiffID] = true
+		hint := layer.File
+		if len(layer.RepoTags) > 0 {
+			hint = fmt.Sprintf("%s (%s)", layer.File, strings.Join(layer.RepoTags, ","))
+		}
+		absent = append(absent, fmt.Sprintf("%s，基线来源 %s", layer.DiffID, hint))
+	}
+	if len(absent) > 0 {
+		return nil, fmt.Errorf("基线中缺少以下层:\n  %s", strings.Join(absent, "\n  "))
+	}
+
+	tmpFile := output + ".merging"
+	if err := writeMergedArchive(deltaPath, tmpFile, compression, delta, found); err != nil {
+		os.Remove(tmpFile)
+		return nil, err
+	}
+
+	// 合并结果必须通过完整校验
+	info, err := InspectArchive(tmpFile)
+	if err != nil || !info.OK() || info.Delta {
+		os.Remove(tmpFile)
+		return nil, fmt.Errorf("合并后的归档校验失败")
+	}
+
+	if err := os.Rename(tmpFile, output); err != nil {
+		os.Remove(tmpFile)
+		return nil, fmt.Errorf("重命名输出文件失败: %v", err)
+	}
+
+	return &MergeResult{OutputFile: output, Restored: len(delta.Layers), Size: info.Size}, nil
+}
+
+// writeMergedArchive 复制增量归档并写入从基线提取的层
+func writeMergedArchive(deltaPath, output, compression string, delta *DeltaInfo, found map[string]string) error {
+	src, err := openArchiveStream(deltaPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	reader, err := newDecompressReader(src)
+	if err != nil {
+		return fmt.Errorf("解压归档失败: %v", err)
+	}
+	defer reader.Close()
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("创建输出文件失败: %v", err)
+	}
+	defer out.Close()
+
+	compressWriter, err := newCompressWriter(out, compression)
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(compressWriter)
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取增量归档失败: %v", err)
+		}
+		if strings.TrimPrefix(header.Name, "./") == DeltaMetadataName {
+			continue
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tarWriter, tarReader); err != nil {
+			return err
+		}
+	}
+
+	// 同一层只写入一份数据，其余路径使用符号链接
+	written := make(map[string]string)
+	for _, layer := range delta.Layers {
+		if first, ok := written[layer.DiffID]; ok {
+			if first == layer.Path {
+				continue
+			}
+			linkname, err := filepath.Rel(path.Dir(layer.Path), first)
+			if err != nil {
+				return err
+			}
+			if err := writeSymlinks(tarWriter, map[string]string{layer.Path: filepath.ToSlash(linkname)}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := addFileToTar(tarWriter, found[layer.DiffID], layer.Path); err != nil {
+			return fmt.Errorf("写入层 %s 失败: %v", layer.Path, err)
+		}
+		written[layer.DiffID] = layer.Path
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("写入tar失败: %v", err)
+	}
+	if err := compressWriter.Close(); err != nil {
+		return fmt.Errorf("压缩输出失败: %v", err)
+	}
+	return nil
+}
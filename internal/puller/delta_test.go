@@ -0,0 +1,272 @@
+package puller
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testArchiveImage 测试归档中的一个镜像，layers 为各层的内容
+type testArchiveImage struct {
+	repoTag string
+	layers  []string
+}
+
+// writeTestArchive 生成 docker save 格式的归档，不同镜像中相同的层以符号链接引用，返回 manifest.json 的内容
+func writeTestArchive(t *testing.T, archivePath, compression string, images []testArchiveImage) []ArchiveManifestEntry {
+	t.Helper()
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	compressWriter, err := newCompressWriter(file, compression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarWriter := tar.NewWriter(compressWriter)
+
+	var entries []ArchiveManifestEntry
+	written := make(map[string]string) // diff_id -> 层路径
+	for i, image := range images {
+		var diffIDs, layerPaths []string
+		for j, content := range image.layers {
+			diffID := sha256Digest([]byte(content))
+			layerPath := fmt.Sprintf("image%d-layer%d/layer.tar", i, j)
+			if first, ok := written[diffID]; ok {
+				if err := tarWriter.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: layerPath, Linkname: "../" + first}); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				if err := writeTarBytes(tarWriter, layerPath, []byte(content)); err != nil {
+					t.Fatal(err)
+				}
+				written[diffID] = layerPath
+			}
+			diffIDs = append(diffIDs, diffID)
+			layerPaths = append(layerPaths, layerPath)
+		}
+
+		config, err := json.Marshal(map[string]any{
+			"architecture": "amd64",
+			"os":           "linux",
+			"rootfs":       map[string]any{"type": "layers", "diff_ids": diffIDs},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		configName := strings.TrimPrefix(sha256Digest(config), "sha256:") + ".json"
+		if err := writeTarBytes(tarWriter, configName, config); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, ArchiveManifestEntry{Config: configName, RepoTags: []string{image.repoTag}, Layers: layerPaths})
+	}
+
+	manifest, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTarBytes(tarWriter, "manifest.json", manifest); err != nil {
+		t.Fatal(err)
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// writeTestBaseline 在 dir 中生成基线归档和离线传输清单
+func writeTestBaseline(t *testing.T, dir string, images []testArchiveImage) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "base.tar")
+	writeTestArchive(t, archive, CompressionNone, images)
+	if err := UpdateBundleManifest([]string{archive}); err != nil {
+		t.Fatalf("UpdateBundleManifest: %v", err)
+	}
+}
+
+func TestDeltaMerge(t *testing.T) {
+	baselineImages := []testArchiveImage{
+		{"base:1", []string{"os layer", "runtime layer"}},
+		{"tools:1", []string{"os layer", "tools layer"}},
+	}
+	appImages := []testArchiveImage{
+		{"app:1", []string{"os layer", "runtime layer", "app layer"}},
+		{"worker:1", []string{"os layer", "tools layer", "worker layer"}},
+	}
+	tests := []struct {
+		name        string
+		compression string
+	}{
+		{"未压缩", CompressionNone},
+		{"gzip", CompressionGzip},
+		{"zstd", CompressionZstd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			baselineDir := "baseline"
+			writeTestBaseline(t, baselineDir, baselineImages)
+
+			deltaPath := "app.tar"
+			entries := writeTestArchive(t, deltaPath, tt.compression, appImages)
+			omitted, err := WriteDeltaArchive(deltaPath, baselineDir)
+			if err != nil {
+				t.Fatalf("WriteDeltaArchive: %v", err)
+			}
+			// os layer 在两个镜像中各出现一次，runtime layer 和 tools layer 各一次
+			if omitted != 4 {
+				t.Errorf("省略的层数量 = %d，期望 4", omitted)
+			}
+
+			delta, err := ReadDeltaInfo(deltaPath)
+			if err != nil {
+				t.Fatalf("ReadDeltaInfo: %v", err)
+			}
+			var deltaPaths []string
+			for _, layer := range delta.Layers {
+				deltaPaths = append(deltaPaths, layer.Path)
+				if layer.File != "base.tar" {
+					t.Errorf("层 %s 的基线来源 = %s，期望 base.tar", layer.Path, layer.File)
+				}
+			}
+			sort.Strings(deltaPaths)
+			wantPaths := []string{"image0-layer0/layer.tar", "image0-layer1/layer.tar", "image1-layer0/layer.tar", "image1-layer1/layer.tar"}
+			if !reflect.DeepEqual(deltaPaths, wantPaths) {
+				t.Errorf("增量归档中省略的层 = %v，期望 %v", deltaPaths, wantPaths)
+			}
+			info, err := InspectArchive(deltaPath)
+			if err != nil || !info.OK() || !info.Delta {
+				t.Fatalf("增量归档应通过校验并标记为增量: %+v, %v", info, err)
+			}
+			if _, err := ReadArchiveFile(deltaPath, "image0-layer1/layer.tar"); err == nil {
+				t.Errorf("增量归档中不应包含基线已有的层")
+			}
+
+			output := "merged.tar"
+			result, err := MergeDelta(deltaPath, []string{baselineDir}, output, "", false)
+			if err != nil {
+				t.Fatalf("MergeDelta: %v", err)
+			}
+			if result.Restored != 4 {
+				t.Errorf("补全的层数量 = %d，期望 4", result.Restored)
+			}
+			if compression, _ := DetectFileCompression(output); compression != tt.compression {
+				t.Errorf("合并后的压缩格式 = %s，期望 %s", compression, tt.compression)
+			}
+
+			merged, err := ReadArchiveManifest(output)
+			if err != nil {
+				t.Fatalf("ReadArchiveManifest: %v", err)
+			}
+			if !reflect.DeepEqual(merged, entries) {
+				t.Errorf("合并后的 manifest.json = %+v，期望 %+v", merged, entries)
+			}
+			info, err = InspectArchive(output)
+			if err != nil || !info.OK() || info.Delta {
+				t.Fatalf("合并后的归档应通过校验且不是增量归档: %+v, %v", info, err)
+			}
+			if _, err := ReadArchiveFile(output, DeltaMetadataName); err == nil {
+				t.Errorf("合并后的归档中不应包含 %s", DeltaMetadataName)
+			}
+			// 重复的层以符号链接写入，InspectArchive 已按 diff_id 校验，这里只读取每层的第一份数据
+			checked := make(map[string]bool)
+			for i, image := range appImages {
+				for j, content := range image.layers {
+					if checked[content] {
+						continue
+					}
+					checked[content] = true
+					data, err := ReadArchiveFile(output, entries[i].Layers[j])
+					if err != nil || string(data) != content {
+						t.Errorf("层 %s = %q, %v，期望 %q", entries[i].Layers[j], data, err, content)
+					}
+				}
+			}
+
+			if _, err := MergeDelta(deltaPath, []string{baselineDir}, output, "", false); err == nil || !strings.Contains(err.Error(), "--force") {
+				t.Errorf("输出文件已存在时应返回错误，实际 %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeDeltaMissingBaselineLayer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeTestBaseline(t, "baseline", []testArchiveImage{{"base:1", []string{"os layer", "runtime layer"}}})
+	deltaPath := "app.tar"
+	writeTestArchive(t, deltaPath, CompressionNone, []testArchiveImage{{"app:1", []string{"os layer", "runtime layer", "app layer"}}})
+	if _, err := WriteDeltaArchive(deltaPath, "baseline"); err != nil {
+		t.Fatalf("WriteDeltaArchive: %v", err)
+	}
+
+	// 目标环境中的基线只有 os layer，另有一个不需要的层
+	writeTestBaseline(t, "partial", []testArchiveImage{{"base:0", []string{"os layer", "other layer"}}})
+	_, err := MergeDelta(deltaPath, []string{"partial"}, "merged.tar", "", false)
+	runtimeDiffID := sha256Digest([]byte("runtime layer"))
+	if err == nil || !strings.Contains(err.Error(), "基线中缺少以下层") || !strings.Contains(err.Error(), runtimeDiffID) {
+		t.Fatalf("期望报告缺少 %s，实际 %v", runtimeDiffID, err)
+	}
+	if strings.Contains(err.Error(), sha256Digest([]byte("os layer"))) {
+		t.Errorf("已找到的层不应报告为缺失: %v", err)
+	}
+	if !strings.Contains(err.Error(), "base.tar (base:1)") {
+		t.Errorf("错误中应包含生成增量归档时的基线来源: %v", err)
+	}
+	for _, name := range []string{"merged.tar", "merged.tar.merging"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("合并失败后不应留下 %s", name)
+		}
+	}
+}
+
+func TestMergeDeltaSkipsCorruptBaselineLayer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	images := []testArchiveImage{{"base:1", []string{"os layer"}}}
+	writeTestBaseline(t, "baseline", images)
+	deltaPath := "app.tar"
+	writeTestArchive(t, deltaPath, CompressionNone, []testArchiveImage{{"app:1", []string{"os layer", "app layer"}}})
+	if _, err := WriteDeltaArchive(deltaPath, "baseline"); err != nil {
+		t.Fatalf("WriteDeltaArchive: %v", err)
+	}
+
+	// 第一个基线归档中的层内容与 diff_id 不一致，应继续从第二个基线归档中查找
+	corrupt := filepath.Join("corrupt", "a.tar")
+	if err := os.MkdirAll("corrupt", 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestArchive(t, corrupt, CompressionNone, images)
+	data, err := os.ReadFile(corrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = []byte(strings.Replace(string(data), "os layer", "os lay3r", 1))
+	if err := os.WriteFile(corrupt, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := MergeDelta(deltaPath, []string{corrupt, "baseline"}, "merged.tar", "", false)
+	if err != nil {
+		t.Fatalf("MergeDelta: %v", err)
+	}
+	if result.Restored != 1 {
+		t.Errorf("补全的层数量 = %d，期望 1", result.Restored)
+	}
+
+	_, err = MergeDelta(deltaPath, []string{corrupt}, "merged2.tar", "", false)
+	if err == nil || !strings.Contains(err.Error(), "基线中缺少以下层") {
+		t.Errorf("只有损坏的基线时期望报告缺少层，实际 %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package puller
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// diskUsage 返回路径所在文件系统的可用空间和设备标识，设备标识相同的路径共享可用空间
+func diskUsage(path string) (uint64, string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, "", err
+	}
+
+	device := path
+	if info, err := os.Stat(path); err == nil {
+		if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+			device = fmt.Sprintf("%d", sys.Dev)
+		}
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), device, nil
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package puller
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// diskUsage 返回路径所在卷的可用空间和卷标识，卷标识相同的路径共享可用空间
+func diskUsage(path string) (uint64, string, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var freeBytes uint64
+	ret, _, callErr := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&freeBytes)), 0, 0)
+	if ret == 0 {
+		return 0, "", callErr
+	}
+
+	device := path
+	if abs, err := filepath.Abs(path); err == nil {
+		device = strings.ToUpper(filepath.VolumeName(abs))
+	}
+	return freeBytes, device, nil
+}
@@ -0,0 +1,147 @@
+package puller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// remoteImageConfig 镜像配置文件中用于查看的字段
+type remoteImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+	Created      string `json:"created,omitempty"`
+	Author       string `json:"author,omitempty"`
+	Config       struct {
+		Env          []string            `json:"Env"`
+		Entrypoint   []string            `json:"Entrypoint"`
+		Cmd          []string            `json:"Cmd"`
+		WorkingDir   string              `json:"WorkingDir"`
+		User         string              `json:"User"`
+		ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+		Labels       map[string]string   `json:"Labels"`
+	} `json:"config"`
+}
+
+// InspectResult 远程镜像的元数据，不包含层内容
+type InspectResult struct {
+	Image        string            `json:"image"`
+	Registry     string            `json:"registry"`      // 提供镜像的仓库地址
+	RegistryName string            `json:"registry_name"` // 提供镜像的仓库名称
+	Repository   string            `json:"repository"`
+	Tag          string            `json:"tag"`
+	MediaType    string            `json:"media_type"`
+	Digest       string            `json:"digest"`                 // 所选平台的清单digest
+	IndexDigest  string            `json:"index_digest,omitempty"` // 多架构清单digest
+	Platforms    []string          `json:"platforms,omitempty"`    // 多架构清单中的全部平台
+	Platform     string            `json:"platform"`
+	Created      string            `json:"created,omitempty"`
+	Author       string            `json:"author,omitempty"`
+	ConfigDigest string            `json:"config_digest"`
+	Entrypoint   []string          `json:"entrypoint,omitempty"`
+	Cmd          []string          `json:"cmd,omitempty"`
+	Env          []string          `json:"env,omitempty"`
+	WorkingDir   string            `json:"working_dir,omitempty"`
+	User         string            `json:"user,omitempty"`
+	ExposedPorts []string          `json:"exposed_ports,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Layers       []LayerDescriptor `json:"layers"`
+	Size         int64             `json:"size"` // 全部层压缩后的大小
+}
+
+// Inspect 通过镜像源搜索获取远程镜像的清单和配置，不下载层
+func (p *MultiRegistryImagePuller) Inspect(imageInput, arch, username, password string) (*InspectResult, error) {
+	imageInput, err := p.ResolveTagConstraint(imageInput, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, manifest, imageInfo, err := p.SearchImageInRegistries(imageInput, arch, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.GetAuthToken(registry, imageInfo.Repository, username, password)
+	if err != nil {
+		return nil, fmt.Errorf("获取认证失败: %v", err)
+	}
+
+	result := &InspectResult{
+		Image:        imageInput,
+		Registry:     registry.URL,
+		RegistryName: registry.Name,
+		Repository:   imageInfo.Repository,
+		Tag:          imageInfo.Tag,
+		MediaType:    manifest.MediaType,
+		ConfigDigest: manifest.Config.Digest,
+		Layers:       manifest.Layers,
+	}
+	for _, layer := range manifest.Layers {
+		result.Size += layer.Size
+	}
+
+	// 标签指向多架构清单时记录全部平台，并找到所选平台的清单digest
+	data, mediaType, err := p.fetchRawManifest(registry, imageInfo.Repository, imageInfo.Tag, token)
+	if err != nil {
+		return nil, err
+	}
+	var top ManifestResponse
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, fmt.Errorf("解析清单失败: %v", err)
+	}
+	if IsManifestIndex(mediaType, &top) {
+		result.IndexDigest = sha256Digest(data)
+		for _, m := range top.Manifests {
+			result.Platforms = append(result.Platforms, formatPlatform(m.Platform))
+		}
+		result.Digest = p.selectManifest(top.Manifests, arch)
+	} else {
+		result.MediaType = mediaType
+		result.Digest = sha256Digest(data)
+	}
+
+	imageConfig, err := p.fetchImageConfig(registry.URL, imageInfo.Repository, manifest.Config.Digest, token)
+	if err != nil {
+		return nil, err
+	}
+	result.Platform = formatPlatform(Platform{OS: imageConfig.OS, Architecture: imageConfig.Architecture, Variant: imageConfig.Variant})
+	result.Created = imageConfig.Created
+	result.Author = imageConfig.Author
+	result.Entrypoint = imageConfig.Config.Entrypoint
+	result.Cmd = imageConfig.Config.Cmd
+	result.Env = imageConfig.Config.Env
+	result.WorkingDir = imageConfig.Config.WorkingDir
+	result.User = imageConfig.Config.User
+	result.Labels = imageConfig.Config.Labels
+	for port := range imageConfig.Config.ExposedPorts {
+		result.ExposedPorts = append(result.ExposedPorts, port)
+	}
+	sort.Strings(result.ExposedPorts)
+
+	return result, nil
+}
+
+// fetchImageConfig 下载并校验镜像配置 blob
+func (p *MultiRegistryImagePuller) fetchImageConfig(registryURL, repository, digest, token string) (*remoteImageConfig, error) {
+	body, err := p.openBlob(fmt.Sprintf("https://%s/v2/%s/blobs/%s", registryURL, repository, digest), token)
+	if err != nil {
+		return nil, fmt.Errorf("获取镜像配置失败: %v", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxMetadataSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取镜像配置失败: %v", err)
+	}
+	if sha256Digest(data) != digest {
+		return nil, fmt.Errorf("镜像配置 %s 校验失败", digest)
+	}
+
+	var imageConfig remoteImageConfig
+	if err := json.Unmarshal(data, &imageConfig); err != nil {
+		return nil, fmt.Errorf("解析镜像配置失败: %v", err)
+	}
+	return &imageConfig, nil
+}
@@ -0,0 +1,187 @@
+package puller
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BundleManifestName 输出目录中离线传输清单的文件名
+const BundleManifestName = "dockerops-manifest.json"
+
+// BundleManifest 离线传输清单，记录输出目录中每个归档的大小、sha256 及其包含的镜像
+type BundleManifest struct {
+	Version   int                  `json:"version"`
+	UpdatedAt string               `json:"updated_at"`
+	Files     []BundleManifestFile `json:"files"`
+}
+
+// BundleManifestFile 清单中的单个归档文件
+type BundleManifestFile struct {
+	Name      string                `json:"name"` // 清单所在目录中的文件名，不含路径
+	Size      int64                 `json:"size"`
+	SHA256    string                `json:"sha256"`
+	Delta     bool                  `json:"delta,omitempty"`     // 增量归档
+	Encrypted bool                  `json:"encrypted,omitempty"` // 加密归档，images 为加密前的内容
+	Images    []BundleManifestImage `json:"images"`
+}
+
+// BundleManifestImage 归档中的单个镜像
+type BundleManifestImage struct {
+	RepoTags []string `json:"repo_tags"`
+	Digest   string   `json:"digest"` // 镜像ID（配置文件digest）
+	Platform string   `json:"platform"`
+	Layers   int      `json:"layers"`
+	DiffIDs  []string `json:"diff_ids,omitempty"` // 各层的 diff_id，用于生成增量归档
+}
+
+// LoadBundleManifest 加载离线传输清单
+func LoadBundleManifest(manifestPath string) (*BundleManifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析清单 %s 失败: %v", manifestPath, err)
+	}
+	// 清单未签名时内容不可信，文件名中带路径会让校验读取清单目录以外的文件
+	for _, file := range manifest.Files {
+		if !isPlainFileName(file.Name) {
+			return nil, fmt.Errorf("清单 %s 中的文件名无效: %q", manifestPath, file.Name)
+		}
+	}
+	return &manifest, nil
+}
+
+// isPlainFileName 判断名称是否为不含路径的普通文件名
+func isPlainFileName(name string) bool {
+	return name != "" && name != "." && !strings.Contains(name, "..") && !strings.ContainsAny(name, `/\`) && filepath.Base(name) == name
+}
+
+// Save 保存离线传输清单
+func (m *BundleManifest) Save(manifestPath string) error {
+	m.Version = 1
+	m.UpdatedAt = time.Now().Format(time.RFC3339)
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Name < m.Files[j].Name
+	})
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化清单失败: %v", err)
+	}
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		return fmt.Errorf("写入清单失败: %v", err)
+	}
+
+	// 清单变更后原签名失效，需要重新签名
+	os.Remove(filepath.Join(filepath.Dir(manifestPath), BundleSignatureName))
+	return nil
+}
+
+// Find 按相对路径查找清单中的文件
+func (m *BundleManifest) Find(name string) *BundleManifestFile {
+	name = filepath.ToSlash(name)
+	for i := range m.Files {
+		if m.Files[i].Name == name {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// UpdateBundleManifest 检查归档并将其写入所在目录的离线传输清单，已存在的同名条目会被替换
+// 归档内部校验失败时返回错误，不写入清单
+func UpdateBundleManifest(archives []string) error {
+	manifests := make(map[string]*BundleManifest)
+
+	for _, archive := range archives {
+		dir := filepath.Dir(archive)
+		manifestPath := filepath.Join(dir, BundleManifestName)
+
+		manifest, ok := manifests[manifestPath]
+		if !ok {
+			var err error
+			manifest, err = LoadBundleManifest(manifestPath)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					return err
+				}
+				manifest = &BundleManifest{}
+			}
+			manifests[manifestPath] = manifest
+		}
+
+		info, err := InspectArchive(archive)
+		if err != nil {
+			return fmt.Errorf("检查归档 %s 失败: %v", archive, err)
+		}
+		if !info.OK() {
+			return fmt.Errorf("归档 %s 校验失败，未写入清单", archive)
+		}
+
+		file := BundleManifestFile{
+			Name:      filepath.Base(archive),
+			Size:      info.Size,
+			SHA256:    info.SHA256,
+			Delta:     info.Delta,
+			Encrypted: info.Encrypted,
+		}
+		for _, image := range info.Images {
+			file.Images = append(file.Images, BundleManifestImage{
+				RepoTags: image.RepoTags,
+				Digest:   image.ImageID,
+				Platform: image.Platform,
+				Layers:   image.Layers,
+				DiffIDs:  image.DiffIDs,
+			})
+		}
+
+		if existing := manifest.Find(file.Name); existing != nil {
+			*existing = file
+		} else {
+			manifest.Files = append(manifest.Files, file)
+		}
+	}
+
+	for manifestPath, manifest := range manifests {
+		if err := manifest.Save(manifestPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordArchiveReplacement 归档被加密或切分后，将清单中的条目更新为新文件，
+// 保留原有的镜像信息；清单不存在或未包含原文件时不做任何操作
+func RecordArchiveReplacement(oldPath, newPath string) error {
+	manifestPath := filepath.Join(filepath.Dir(oldPath), BundleManifestName)
+	manifest, err := LoadBundleManifest(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	file := manifest.Find(filepath.Base(oldPath))
+	if file == nil {
+		return nil
+	}
+
+	size, digest, err := hashArchiveStream(newPath)
+	if err != nil {
+		return err
+	}
+	file.Name = filepath.Base(newPath)
+	file.Size = size
+	file.SHA256 = digest
+	file.Encrypted = file.Encrypted || IsEncryptedFile(newPath)
+	return manifest.Save(manifestPath)
+}
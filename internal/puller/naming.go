@@ -0,0 +1,100 @@
+package puller
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultNameTemplate 默认的输出文件名模板（不含扩展名）
+const DefaultNameTemplate = "{repo}_{tag}_{arch}"
+
+// OutputNameVars 输出文件名模板变量
+type OutputNameVars struct {
+	Registry string // 镜像仓库地址，例如 registry.cn-hangzhou.aliyuncs.com
+	Repo     string // 仓库路径，例如 library/nginx
+	Tag      string
+	Arch     string
+	Digest   string // 镜像ID（配置文件digest）
+	Date     time.Time
+}
+
+// RenderOutputName 根据模板渲染输出文件名
+// 支持的占位符：{registry} {repo} {tag} {arch} {digest} {date}
+// 模板中不含扩展名时自动追加 ext
+func RenderOutputName(template string, vars OutputNameVars, ext string) string {
+	if template == "" {
+		template = DefaultNameTemplate
+	}
+
+	date := vars.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	digest := strings.TrimPrefix(vars.Digest, "sha256:")
+	if len(digest) > 12 {
+		digest = digest[:12]
+	}
+
+	replacer := strings.NewReplacer(
+		"{registry}", sanitizeNamePart(vars.Registry),
+		"{repo}", sanitizeNamePart(vars.Repo),
+		"{tag}", sanitizeNamePart(vars.Tag),
+		"{arch}", sanitizeNamePart(vars.Arch),
+		"{digest}", digest,
+		"{date}", date.Format("20060102"),
+	)
+	name := replacer.Replace(template)
+
+	if !strings.HasSuffix(name, ".tar") && !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tar.zst") {
+		name += ext
+	}
+	return name
+}
+
+// sanitizeNamePart 将不能出现在文件名中的字符替换为下划线
+func sanitizeNamePart(s string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "\\", "_", "@", "_").Replace(s)
+}
+
+// ResolveOutputPath 拼接输出目录并检查文件是否已存在
+// force 为 false 时若文件已存在则返回错误
+func ResolveOutputPath(outputDir, name string, force bool) (string, error) {
+	path := name
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return "", fmt.Errorf("创建输出目录失败: %v", err)
+		}
+		path = filepath.Join(outputDir, name)
+	}
+
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return "", fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", path)
+		}
+	}
+
+	return path, nil
+}
+
+// FormatSize 将字节数格式化为易读的大小
+func FormatSize(size int64) string {
+	if size <= 0 {
+		return "-"
+	}
+
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,102 @@
+package puller
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderOutputName(t *testing.T) {
+	vars := OutputNameVars{
+		Registry: "registry.cn-hangzhou.aliyuncs.com",
+		Repo:     "library/nginx",
+		Tag:      "1.25",
+		Arch:     "arm64/v8",
+		Digest:   "sha256:0123456789abcdef0123",
+		Date:     time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name     string
+		template string
+		vars     OutputNameVars
+		ext      string
+		want     string
+	}{
+		{"默认模板", "", vars, ".tar", "library_nginx_1.25_arm64_v8.tar"},
+		{"全部占位符", "{registry}-{repo}-{tag}-{arch}-{digest}-{date}", vars, ".tar.gz",
+			"registry.cn-hangzhou.aliyuncs.com-library_nginx-1.25-arm64_v8-0123456789ab-20240305.tar.gz"},
+		{"模板已带扩展名", "{repo}.tar", vars, ".tar.zst", "library_nginx.tar"},
+		{"模板带压缩扩展名", "{tag}.tar.zst", vars, ".tar", "1.25.tar.zst"},
+		{"未知占位符保留", "{repo}_{unknown}", vars, ".tar", "library_nginx_{unknown}.tar"},
+		{"替换不能出现在文件名中的字符", "{repo}_{tag}", OutputNameVars{Repo: `a\b`, Tag: "sha256:abc@x", Date: vars.Date}, ".tar", "a_b_sha256_abc_x.tar"},
+		{"短摘要不截断", "{digest}", OutputNameVars{Digest: "sha256:abc", Date: vars.Date}, ".tar", "abc.tar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderOutputName(tt.template, tt.vars, tt.ext); got != tt.want {
+				t.Errorf("RenderOutputName(%q) = %q，期望 %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderOutputNameDefaultDate(t *testing.T) {
+	before := time.Now().Format("20060102")
+	got := RenderOutputName("{date}", OutputNameVars{}, ".tar")
+	after := time.Now().Format("20060102")
+	if got != before+".tar" && got != after+".tar" {
+		t.Errorf("RenderOutputName({date}) = %q，期望当天日期 %s", got, before)
+	}
+}
+
+func TestResolveOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "exists.tar")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		outputDir string
+		file      string
+		force     bool
+		want      string
+		wantErr   string
+	}{
+		{"新文件", dir, "new.tar", false, filepath.Join(dir, "new.tar"), ""},
+		{"已存在且未指定 --force", dir, "exists.tar", false, "", "--force"},
+		{"已存在且指定 --force", dir, "exists.tar", true, existing, ""},
+		{"自动创建输出目录", filepath.Join(dir, "a", "b"), "x.tar", false, filepath.Join(dir, "a", "b", "x.tar"), ""},
+		{"没有输出目录", "", "relative.tar", false, "relative.tar", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveOutputPath(tt.outputDir, tt.file, tt.force)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveOutputPath: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveOutputPath = %q，期望 %q", got, tt.want)
+			}
+			if tt.outputDir != "" {
+				if info, err := os.Stat(tt.outputDir); err != nil || !info.IsDir() {
+					t.Errorf("输出目录 %s 未创建", tt.outputDir)
+				}
+			}
+		})
+	}
+
+	data, err := os.ReadFile(existing)
+	if err != nil || string(data) != "old" {
+		t.Errorf("ResolveOutputPath 不应修改已存在的文件")
+	}
+}
@@ -0,0 +1,72 @@
+package puller
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// OCILayoutFile OCI 镜像布局目录的标识文件
+const OCILayoutFile = "oci-layout"
+
+// IsOCILayout 判断目录是否为 OCI 镜像布局（包含 oci-layout 文件）
+func IsOCILayout(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, OCILayoutFile))
+	return err == nil && !info.IsDir()
+}
+
+// OpenOCILayout 将 OCI 镜像布局目录打包为 tar 数据流，可直接交给 docker load、podman load 或 ctr import
+func OpenOCILayout(dir string) (io.ReadCloser, error) {
+	if !IsOCILayout(dir) {
+		return nil, fmt.Errorf("%s 不是 OCI 镜像布局目录（缺少 %s）", dir, OCILayoutFile)
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeDirTar(writer, dir))
+	}()
+	return reader, nil
+}
+
+// writeDirTar 将目录中的文件按相对路径写入 tar 流
+func writeDirTar(w io.Writer, dir string) error {
+	tarWriter := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(relPath)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("打包 OCI 镜像布局失败: %v", err)
+	}
+	return tarWriter.Close()
+}
@@ -0,0 +1,228 @@
+package puller
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dockerops/internal/config"
+)
+
+// layerExpansionRatio 估算压缩层解压后大小的倍数，清单中只记录了压缩后的大小
+const layerExpansionRatio = 3
+
+// PlannedLayer 拉取计划中的单个层
+type PlannedLayer struct {
+	LayerDescriptor
+	Cached bool // blob 缓存中已有原始压缩层，无需下载
+}
+
+// DiskRequirement 拉取时某个目录需要的磁盘空间（估算）
+type DiskRequirement struct {
+	Purpose string // 用途，例如 工作目录、输出文件
+	Path    string // 实际检查的已存在目录
+	Need    int64
+	Free    int64
+	Device  string // 文件系统标识，相同时共享可用空间
+}
+
+// PullPlan 拉取计划，只探测仓库并解析清单，不下载层
+type PullPlan struct {
+	Image       string                  // 用户输入的镜像引用
+	Resolved    string                  // 解析版本约束后的镜像引用
+	Registry    *config.RegistryConfig  // 将要使用的仓库
+	Fallbacks   []config.RegistryConfig // 所选仓库失败时依次尝试的仓库
+	Unavailable []config.RegistryConfig // 探测失败的仓库
+	ImageInfo   ImageInfo
+	Arch        string
+	Digest      string // 镜像配置digest
+	Layers      []PlannedLayer
+	Size        int64 // 全部层压缩后的大小
+	Download    int64 // 需要下载的大小
+	OutputFile  string
+	Skip        bool // 输出文件中已存在相同digest的镜像，将跳过下载
+	Disk        []DiskRequirement
+}
+
+// PlanPull 生成拉取计划：解析版本约束、探测仓库并获取清单，然后停止
+func (p *MultiRegistryImagePuller) PlanPull(imageInput, arch, username, password string, options PullOptions) (*PullPlan, error) {
+	plan := &PullPlan{Image: imageInput, Arch: arch}
+
+	resolved, err := p.ResolveTagConstraint(imageInput, username, password)
+	if err != nil {
+		return nil, err
+	}
+	plan.Resolved = resolved
+
+	registry, manifest, imageInfo, err := p.SearchImageInRegistries(resolved, arch, username, password)
+	if err != nil {
+		return nil, err
+	}
+	plan.Registry = registry
+	plan.ImageInfo = imageInfo
+	plan.Digest = manifest.Config.Digest
+	plan.Fallbacks, plan.Unavailable = p.fallbackOrder(registry)
+
+	for _, layer := range manifest.Layers {
+		_, cached := p.blobCache.Has(layer.Digest)
+		plan.Layers = append(plan.Layers, PlannedLayer{LayerDescriptor: layer, Cached: cached})
+		plan.Size += layer.Size
+		if !cached {
+			plan.Download += layer.Size
+		}
+	}
+
+	outputFile, err := p.resolveOutputFile(registry, imageInfo, manifest, arch, options)
+	if err != nil {
+		return nil, err
+	}
+	plan.OutputFile = archiveOutputPath(outputFile, options)
+	if outputFile != "-" {
+		if _, err := os.Stat(plan.OutputFile); err == nil && options.SkipExisting {
+			if _, ok := archiveImageTags(plan.OutputFile, manifest.Config.Digest); ok {
+				plan.Skip = true
+				return plan, nil
+			}
+		}
+	}
+
+	plan.Disk, err = p.diskRequirements(manifest, outputFile, options)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// CheckDiskSpace 检查计划中的磁盘空间是否足够
+func (plan *PullPlan) CheckDiskSpace() error {
+	return checkDiskRequirements(plan.Disk)
+}
+
+// fallbackOrder 返回所选仓库之后按探测顺序排列的备选仓库，以及探测失败的仓库
+func (p *MultiRegistryImagePuller) fallbackOrder(selected *config.RegistryConfig) ([]config.RegistryConfig, []config.RegistryConfig) {
+	p.probeMu.Lock()
+	probed := p.probed
+	p.probeMu.Unlock()
+
+	var fallbacks []config.RegistryConfig
+	available := make(map[string]bool)
+	found := false
+	for _, registry := range probed {
+		available[registry.URL] = true
+		if found {
+			fallbacks = append(fallbacks, registry)
+		}
+		if registry.URL == selected.URL {
+			found = true
+		}
+	}
+
+	var unavailable []config.RegistryConfig
+	for _, registry := range p.registries {
+		if !available[registry.URL] && registry.URL != selected.URL {
+			unavailable = append(unavailable, registry)
+		}
+	}
+	return fallbacks, unavailable
+}
+
+// estimateUncompressedSize 估算层解压后的大小，未压缩的层按原始大小计算
+func estimateUncompressedSize(layer LayerDescriptor) int64 {
+	if strings.Contains(layer.MediaType, "gzip") || strings.Contains(layer.MediaType, "zstd") {
+		return layer.Size * layerExpansionRatio
+	}
+	return layer.Size
+}
+
+// existingDir 返回路径本身或最近的已存在上级目录，用于检查尚未创建的目录的可用空间
+func existingDir(path string) string {
+	dir := filepath.Clean(path)
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "."
+		}
+		dir = parent
+	}
+}
+
+// diskRequirements 估算拉取时工作目录和输出文件需要的磁盘空间：
+// 工作目录保存解压后的层，另需一个最大压缩层的下载空间；输出文件未压缩时约为解压后的大小
+func (p *MultiRegistryImagePuller) diskRequirements(manifest *ManifestResponse, outputFile string, options PullOptions) ([]DiskRequirement, error) {
+	var uncompressed, largestBlob, compressed int64
+	for _, layer := range manifest.Layers {
+		uncompressed += estimateUncompressedSize(layer)
+		compressed += layer.Size
+		if _, cached := p.blobCache.Has(layer.Digest); !cached && layer.Size > largestBlob {
+			largestBlob = layer.Size
+		}
+	}
+
+	requirements := []DiskRequirement{{
+		Purpose: "工作目录",
+		Path:    existingDir("tmp"),
+		Need:    uncompressed + largestBlob + manifest.Config.Size,
+	}}
+
+	if outputFile != "-" {
+		compression, err := NormalizeCompression(options.Compression)
+		if err != nil {
+			return nil, err
+		}
+		need := uncompressed
+		if compression != "none" {
+			need = compressed
+		}
+		requirements = append(requirements, DiskRequirement{
+			Purpose: "输出文件",
+			Path:    existingDir(filepath.Dir(outputFile)),
+			Need:    need,
+		})
+	}
+
+	for i := range requirements {
+		free, device, err := diskUsage(requirements[i].Path)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 的可用空间失败: %v", requirements[i].Path, err)
+		}
+		requirements[i].Free = int64(free)
+		requirements[i].Device = device
+	}
+	return requirements, nil
+}
+
+// checkDiskRequirements 按文件系统汇总需要的空间，与可用空间比较
+func checkDiskRequirements(requirements []DiskRequirement) error {
+	need := make(map[string]int64)
+	for _, requirement := range requirements {
+		need[requirement.Device] += requirement.Need
+	}
+
+	checked := make(map[string]bool)
+	for _, requirement := range requirements {
+		if checked[requirement.Device] {
+			continue
+		}
+		checked[requirement.Device] = true
+		if need[requirement.Device] > requirement.Free {
+			return fmt.Errorf("磁盘空间不足：%s 所在文件系统预计需要 %s，可用 %s（可使用 --skip-disk-check 跳过检查）",
+				requirement.Path, FormatSize(need[requirement.Device]), FormatSize(requirement.Free))
+		}
+	}
+	return nil
+}
+
+// checkDiskSpace 开始下载前检查磁盘空间，无法获取可用空间时只记录警告
+func (p *MultiRegistryImagePuller) checkDiskSpace(manifest *ManifestResponse, outputFile string, options PullOptions) error {
+	requirements, err := p.diskRequirements(manifest, outputFile, options)
+	if err != nil {
+		log.Printf("⚠️ 跳过磁盘空间检查: %v", err)
+		return nil
+	}
+	return checkDiskRequirements(requirements)
+}
@@ -2,10 +2,11 @@ package puller
 
 import (
 	"archive/tar"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -66,6 +67,7 @@ type PlatformManifest struct {
 type Platform struct {
 	Architecture string `json:"architecture"`
 	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
 }
 
 // AuthToken 认证令牌
@@ -75,6 +77,22 @@ type AuthToken struct {
 	ExpiresIn   int    `json:"expires_in"`
 }
 
+// PullOptions 拉取输出选项
+type PullOptions struct {
+	Output        string    // 输出文件路径，为空时按模板生成，"-" 表示写入标准输出
+	OutputDir     string    // 输出目录，为空时使用配置或当前目录
+	NameTemplate  string    // 文件名模板，为空时使用配置或默认模板
+	Force         bool      // 是否覆盖已存在的输出文件
+	Compression   string    // 输出压缩格式: none/gzip/zstd
+	TagMode       string    // 打标签模式，多个模式用逗号分隔，为空时使用配置
+	Tags          []string  // 额外的自定义 RepoTag
+	Stdout        io.Writer // Output 为 "-" 时实际写入的目标，默认 os.Stdout
+	SplitSize     int64     // 分卷大小，大于0时输出编号分卷和分卷索引
+	Baseline      string    // 基线离线传输清单，设置时只输出基线中没有的层（增量归档）
+	SkipDiskCheck bool      // 跳过下载前的磁盘空间检查
+	SkipExisting  bool      // 输出文件中已包含校验通过的相同镜像时跳过下载，用于批量拉取
+}
+
 // MultiRegistryImagePuller 多仓库镜像拉取器
 type MultiRegistryImagePuller struct {
 	configManager *config.ConfigManager
@@ -82,6 +100,16 @@ type MultiRegistryImagePuller struct {
 	httpClient    *http.Client
 	stopChan      chan struct{}
 	apiClient     *AdvancedAPIClient // 添加高级API客户端
+	blobCache     *BlobCache         // 原始压缩层缓存，未配置时为nil
+	options       PullOptions
+	// 本次运行中已推送的镜像路径（按仓库地址），用作跨仓库挂载的来源
+	pushMu sync.Mutex
+	pushed map[string][]string
+	// 最近一次探测到的可用仓库，按尝试顺序排列
+	probeMu sync.Mutex
+	probed  []config.RegistryConfig
+	// 并发拉取时多个进度条会互相覆盖，需要关闭
+	disableProgress bool
 }
 
 // NewMultiRegistryImagePuller 创建多仓库镜像拉取器
@@ -119,9 +147,20 @@ func NewMultiRegistryImagePuller(configManager *config.ConfigManager) *MultiRegi
 		httpClient:    client,
 		stopChan:      make(chan struct{}),
 		apiClient:     apiClient,
+		blobCache:     NewBlobCache(configManager.GetConfig().Settings.BlobCacheDir),
 	}
 }
 
+// SetPullOptions 设置拉取输出选项
+func (p *MultiRegistryImagePuller) SetPullOptions(options PullOptions) {
+	p.options = options
+}
+
+// DisableProgressBar 关闭下载进度条
+func (p *MultiRegistryImagePuller) DisableProgressBar() {
+	p.disableProgress = true
+}
+
 // ParseImageInput 解析镜像输入
 func (p *MultiRegistryImagePuller) ParseImageInput(imageInput string) ImageInfo {
 	// 检查是否包含私有仓库地址
@@ -443,48 +482,11 @@ func (p *MultiRegistryImagePuller) SearchImageInRegistries(imageInput, arch, use
 
 	log.Printf("开始在 %d 个仓库中搜索镜像: %s:%s", len(p.registries), originalImageInfo.Repository, originalImageInfo.Tag)
 
-	// 测试仓库可用性
-	var availableRegistries []config.RegistryConfig
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	maxWorkers := p.configManager.GetConfig().Settings.MaxConcurrentRegistries
-	semaphore := make(chan struct{}, maxWorkers)
-
-	for _, registry := range p.registries {
-		wg.Add(1)
-		go func(reg config.RegistryConfig) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			if p.TestRegistryAvailability(&reg) {
-				mu.Lock()
-				availableRegistries = append(availableRegistries, reg)
-				mu.Unlock()
-			}
-		}(registry)
-	}
-
-	wg.Wait()
-
+	availableRegistries := p.availableRegistries()
 	if len(availableRegistries) == 0 {
 		return nil, nil, originalImageInfo, fmt.Errorf("没有可用的镜像仓库")
 	}
 
-	// 按优先级和响应时间排序
-	sort.Slice(availableRegistries, func(i, j int) bool {
-		if availableRegistries[i].Priority != availableRegistries[j].Priority {
-			return availableRegistries[i].Priority < availableRegistries[j].Priority
-		}
-		if availableRegistries[i].ResponseTime != nil && availableRegistries[j].ResponseTime != nil {
-			return *availableRegistries[i].ResponseTime < *availableRegistries[j].ResponseTime
-		}
-		return false
-	})
-
-	log.Printf("发现 %d 个可用仓库", len(availableRegistries))
-
 	// 依次尝试每个可用仓库
 	for _, registry := range availableRegistries {
 		log.Printf("正在尝试 %s (%s)...", registry.Name, registry.URL)
@@ -529,6 +531,53 @@ func (p *MultiRegistryImagePuller) SearchImageInRegistries(imageInput, arch, use
 	return nil, nil, originalImageInfo, fmt.Errorf("在所有可用仓库中都未找到镜像: %s:%s", originalImageInfo.Repository, originalImageInfo.Tag)
 }
 
+// availableRegistries 并发测试配置的仓库，返回按优先级和响应时间排序的可用仓库
+func (p *MultiRegistryImagePuller) availableRegistries() []config.RegistryConfig {
+	var availableRegistries []config.RegistryConfig
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	maxWorkers := p.configManager.GetConfig().Settings.MaxConcurrentRegistries
+	semaphore := make(chan struct{}, maxWorkers)
+
+	for _, registry := range p.registries {
+		wg.Add(1)
+		go func(reg config.RegistryConfig) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if p.TestRegistryAvailability(&reg) {
+				mu.Lock()
+				availableRegistries = append(availableRegistries, reg)
+				mu.Unlock()
+			}
+		}(registry)
+	}
+
+	wg.Wait()
+
+	// 按优先级和响应时间排序
+	sort.Slice(availableRegistries, func(i, j int) bool {
+		if availableRegistries[i].Priority != availableRegistries[j].Priority {
+			return availableRegistries[i].Priority < availableRegistries[j].Priority
+		}
+		if availableRegistries[i].ResponseTime != nil && availableRegistries[j].ResponseTime != nil {
+			return *availableRegistries[i].ResponseTime < *availableRegistries[j].ResponseTime
+		}
+		return false
+	})
+
+	if len(availableRegistries) > 0 {
+		log.Printf("发现 %d 个可用仓库", len(availableRegistries))
+	}
+
+	p.probeMu.Lock()
+	p.probed = availableRegistries
+	p.probeMu.Unlock()
+	return availableRegistries
+}
+
 // selectManifest 选择适合指定架构的清单
 func (p *MultiRegistryImagePuller) selectManifest(manifests []PlatformManifest, arch string) string {
 	for _, m := range manifests {
@@ -573,6 +622,11 @@ func (p *MultiRegistryImagePuller) FetchManifestByDigest(registry *config.Regist
 
 // DownloadFileWithProgress 下载文件并显示进度
 func (p *MultiRegistryImagePuller) DownloadFileWithProgress(url, token, savePath, desc string) error {
+	return p.DownloadBlobWithProgress(url, token, savePath, desc, "")
+}
+
+// DownloadBlobWithProgress 下载blob并显示进度，expectedDigest 不为空时校验内容的 sha256
+func (p *MultiRegistryImagePuller) DownloadBlobWithProgress(url, token, savePath, desc, expectedDigest string) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("创建请求失败: %v", err)
@@ -606,32 +660,73 @@ func (p *MultiRegistryImagePuller) DownloadFileWithProgress(url, token, savePath
 
 	// 创建进度条
 	var bar *progressbar.ProgressBar
-	if p.configManager.GetConfig().Settings.EnableProgressBar {
+	if p.configManager.GetConfig().Settings.EnableProgressBar && !p.disableProgress {
 		bar = progressbar.DefaultBytes(resp.ContentLength, desc)
 	}
 
-	// 复制数据
-	var writer io.Writer = file
+	// 复制数据，同时计算digest
+	hasher := sha256.New()
+	writers := []io.Writer{file, hasher}
 	if bar != nil {
-		writer = io.MultiWriter(file, bar)
+		writers = append(writers, bar)
 	}
 
-	_, err = io.Copy(writer, resp.Body)
+	_, err = io.Copy(io.MultiWriter(writers...), resp.Body)
 	if err != nil {
 		os.Remove(savePath) // 删除部分下载的文件
 		return fmt.Errorf("下载失败: %v", err)
 	}
 
+	if expectedDigest != "" && strings.HasPrefix(expectedDigest, "sha256:") {
+		actual := "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+		if actual != expectedDigest {
+			os.Remove(savePath)
+			return fmt.Errorf("digest校验失败: 期望 %s，实际 %s", expectedDigest, actual)
+		}
+	}
+
 	return nil
 }
 
+// PullResult 单个镜像的拉取结果
+type PullResult struct {
+	Image      string        // 用户输入的镜像引用
+	OutputFile string        // 输出文件路径
+	Registry   string        // 实际使用的镜像仓库
+	Digest     string        // 镜像ID（配置文件digest）
+	RepoTags   []string      // 导入后的镜像标签
+	Size       int64         // 输出文件大小，写入标准输出时为0；打包为多镜像归档时为该镜像各层压缩后的大小之和
+	Skipped    bool          // 输出文件中已存在相同digest的镜像，未重新下载；归档不是本次运行生成的
+	Duration   time.Duration // 耗时
+}
+
 // PullImage 拉取镜像
 func (p *MultiRegistryImagePuller) PullImage(imageInput, arch, username, password string) (string, error) {
+	result, err := p.Pull(imageInput, arch, username, password, p.options)
+	if err != nil {
+		return "", err
+	}
+	return result.OutputFile, nil
+}
+
+// Pull 使用指定的输出选项拉取镜像，可被多个goroutine并发调用
+func (p *MultiRegistryImagePuller) Pull(imageInput, arch, username, password string, options PullOptions) (*PullResult, error) {
+	start := time.Now()
+	result := &PullResult{Image: imageInput}
+
+	// 将版本约束（例如 nginx:~1.25）解析为具体标签
+	imageInput, err := p.ResolveTagConstraint(imageInput, username, password)
+	if err != nil {
+		return result, err
+	}
+
 	// 搜索镜像
 	registry, manifest, imageInfo, err := p.SearchImageInRegistries(imageInput, arch, username, password)
 	if err != nil {
-		return "", err
+		return result, err
 	}
+	result.Registry = registry.URL
+	result.Digest = manifest.Config.Digest
 
 	log.Printf("选择的仓库：%s (%s)", registry.Name, registry.URL)
 	log.Printf("镜像：%s", imageInfo.Repository)
@@ -640,188 +735,398 @@ func (p *MultiRegistryImagePuller) PullImage(imageInput, arch, username, passwor
 
 	// 检查清单中的层
 	if len(manifest.Layers) == 0 {
-		return "", fmt.Errorf("清单中没有层")
+		return result, fmt.Errorf("清单中没有层")
+	}
+
+	// 在下载之前确定输出文件，避免下载完成后才发现文件已存在
+	outputFile, err := p.resolveOutputFile(registry, imageInfo, manifest, arch, options)
+	if err != nil {
+		return result, err
+	}
+	archivePath := archiveOutputPath(outputFile, options)
+	result.OutputFile = archivePath
+	repoTags := p.resolveRepoTags(imageInput, registry, imageInfo, options)
+	result.RepoTags = repoTags
+
+	if outputFile != "-" {
+		if _, err := os.Stat(archivePath); err == nil {
+			// 批量拉取时已存在相同digest且校验通过的镜像直接跳过
+			if options.SkipExisting {
+				if existingTags, ok := archiveImageTags(archivePath, manifest.Config.Digest); ok {
+					log.Printf("⏭️ %s 中已存在相同digest的镜像，跳过下载", archivePath)
+					// 导入的是已有归档，标签以归档中记录的为准
+					result.RepoTags = existingTags
+					result.Skipped = true
+					result.Size = archiveSize(archivePath)
+					result.Duration = time.Since(start)
+					return result, nil
+				}
+			}
+			if !options.Force {
+				return result, fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", archivePath)
+			}
+		}
+	}
+
+	// 磁盘空间不足时在下载前拒绝执行
+	if !options.SkipDiskCheck {
+		if err := p.checkDiskSpace(manifest, outputFile, options); err != nil {
+			return result, err
+		}
 	}
 
 	// 获取认证令牌
 	token, err := p.GetAuthToken(registry, imageInfo.Repository, username, password)
 	if err != nil {
-		return "", fmt.Errorf("获取认证失败: %v", err)
+		return result, fmt.Errorf("获取认证失败: %v", err)
 	}
 
-	// 创建临时目录
-	tmpDir := "tmp"
-	if err := os.MkdirAll(tmpDir, 0755); err != nil {
-		return "", fmt.Errorf("创建临时目录失败: %v", err)
+	// 创建临时目录，每次拉取使用独立的工作目录以支持并发
+	tmpDir, err := newWorkDir("pull-")
+	if err != nil {
+		return result, err
+	}
+	if p.configManager.GetConfig().Settings.CleanupTempFiles {
+		defer os.RemoveAll(tmpDir)
 	}
 
 	log.Println("开始下载")
 
-	// 下载配置文件
-	configFilename := manifest.Config.Digest[7:] + ".json"
-	configPath := filepath.Join(tmpDir, configFilename)
-	configURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", registry.URL, imageInfo.Repository, manifest.Config.Digest)
-
-	if err := p.DownloadFileWithProgress(configURL, token, configPath, "Config"); err != nil {
-		return "", fmt.Errorf("下载配置文件失败: %v", err)
+	// 下载配置文件和层
+	store := newLayerStore()
+	if err := store.setBaseline(options.Baseline); err != nil {
+		return result, err
+	}
+	entry, topLayerID, err := p.fetchImage(registry, imageInfo, manifest, repoTags, token, tmpDir, store)
+	if err != nil {
+		return result, err
 	}
 
-	// 下载层
-	if err := p.downloadLayers(registry, imageInfo, manifest, token, tmpDir); err != nil {
-		return "", fmt.Errorf("下载层失败: %v", err)
+	if err := writeArchiveMetadata(tmpDir, []ArchiveManifestEntry{*entry}, buildRepositories(repoTags, topLayerID)); err != nil {
+		return result, err
+	}
+	if err := store.writeDeltaMetadata(tmpDir); err != nil {
+		return result, err
+	}
+	if options.Baseline != "" {
+		log.Printf("增量归档：%d 个层已在基线中，未写入归档", store.baselineLayers())
 	}
 
 	// 打包镜像
-	outputFile, err := p.createImageTar(tmpDir, imageInfo, arch)
-	if err != nil {
-		return "", fmt.Errorf("打包镜像失败: %v", err)
+	if err := p.createImageTar(tmpDir, outputFile, store.symlinks(), options); err != nil {
+		return result, fmt.Errorf("打包镜像失败: %v", err)
 	}
 
 	log.Printf("✅ 镜像 %s:%s 下载完成！", imageInfo.Image, imageInfo.Tag)
-	log.Printf("镜像已保存为: %s", outputFile)
-	log.Printf("可使用以下命令导入镜像: docker load -i %s", outputFile)
-
-	if p.configManager.GetConfig().Settings.RemoveRegistryPrefix {
-		log.Printf("导入后的镜像标签: %s:%s", imageInfo.Image, imageInfo.Tag)
+	if outputFile == "-" {
+		log.Printf("镜像已写入标准输出")
 	} else {
-		log.Printf("导入后的镜像标签: %s:%s", imageInfo.Repository, imageInfo.Tag)
+		log.Printf("镜像已保存为: %s", archivePath)
+		if options.SplitSize > 0 {
+			log.Printf("可使用以下命令合并分卷: DockerOps join %s", archivePath)
+		} else {
+			log.Printf("可使用以下命令导入镜像: docker load -i %s", archivePath)
+		}
+		result.Size = archiveSize(archivePath)
+	}
+
+	log.Printf("导入后的镜像标签: %s", strings.Join(repoTags, ", "))
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// archiveOutputPath 返回实际生成的归档路径，分卷输出时为分卷索引
+func archiveOutputPath(outputFile string, options PullOptions) string {
+	if options.SplitSize > 0 && outputFile != "-" {
+		return SplitIndexPath(outputFile)
+	}
+	return outputFile
+}
+
+// archiveSize 获取归档大小，分卷索引返回合并后的大小
+func archiveSize(path string) int64 {
+	if IsSplitIndex(path) {
+		index, err := LoadSplitIndex(path)
+		if err != nil {
+			return 0
+		}
+		return index.Size
 	}
+	return fileSize(path)
+}
+
+// fileSize 获取文件大小，失败时返回0
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
 
-	return outputFile, nil
+// newWorkDir 在 tmp 目录下创建独立的工作目录
+func newWorkDir(pattern string) (string, error) {
+	if err := os.MkdirAll("tmp", 0755); err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	dir, err := os.MkdirTemp("tmp", pattern)
+	if err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	return dir, nil
 }
 
-// downloadLayers 下载镜像层
-// func (p *MultiRegistryImagePuller) downloadLayers(registry *config.RegistryConfig, imageInfo ImageInfo, layers []LayerDescriptor, token, tmpDir string) error {
-func (p *MultiRegistryImagePuller) downloadLayers(registry *config.RegistryConfig, imageInfo ImageInfo, manifest *ManifestResponse, token, tmpDir string) error {
-	// 创建层目录和JSON映射
-	layerJSONMap := make(map[string]map[string]interface{})
-	var parentID string
+// fetchImage 下载镜像配置和层到工作目录，返回 manifest.json 条目和顶层的 v1 镜像ID
+// 层目录名使用根据 rootfs.diff_ids 计算的 v1 层ID，解压后的层会与 diff_ids 逐一校验；
+// store 中已存在的层不会重复下载，而是在归档中以符号链接引用
+func (p *MultiRegistryImagePuller) fetchImage(registry *config.RegistryConfig, imageInfo ImageInfo, manifest *ManifestResponse, repoTags []string, token, workDir string, store *layerStore) (*ArchiveManifestEntry, string, error) {
+	// 下载配置文件
+	configFilename := manifest.Config.Digest[7:] + ".json"
+	configPath := filepath.Join(workDir, configFilename)
+	configURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", registry.URL, imageInfo.Repository, manifest.Config.Digest)
+
+	if err := p.DownloadBlobWithProgress(configURL, token, configPath, "Config", manifest.Config.Digest); err != nil {
+		return nil, "", fmt.Errorf("下载配置文件失败: %v", err)
+	}
+
+	rawConfig, imageConfig, err := readImageConfig(configPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(imageConfig.RootFS.DiffIDs) != len(manifest.Layers) {
+		return nil, "", fmt.Errorf("镜像配置中的 diff_ids 数量(%d)与清单中的层数量(%d)不一致", len(imageConfig.RootFS.DiffIDs), len(manifest.Layers))
+	}
+
+	v1Layers, err := buildV1Layers(rawConfig, imageConfig)
+	if err != nil {
+		return nil, "", err
+	}
+
 	var layerPaths []string
-	var lastLayerID string
 
 	// 下载所有层
 	for i, layer := range manifest.Layers {
-		// 使用真是的层digest ID
-		fakeLayerID := layer.Digest[7:] // 去掉sha256:前缀
+		v1Layer := v1Layers[i]
+		layerPath := v1Layer.ID + "/layer.tar"
 
-		layerDir := filepath.Join(tmpDir, fakeLayerID)
+		layerDir := filepath.Join(workDir, v1Layer.ID)
 		if err := os.MkdirAll(layerDir, 0755); err != nil {
-			return fmt.Errorf("创建层目录失败: %v", err)
+			return nil, "", fmt.Errorf("创建层目录失败: %v", err)
 		}
 
-		// 创建层JSON
-		layerJSON := map[string]interface{}{
-			"id": fakeLayerID,
-		}
-		if parentID != "" {
-			layerJSON["parent"] = parentID
-		}
-		layerJSONMap[fakeLayerID] = layerJSON
+		if store.fromBaseline(v1Layer.DiffID, layerPath) {
+			log.Printf("层 %d/%d 已在基线中，不写入归档 (%s)", i+1, len(manifest.Layers), layer.Digest[:19])
+		} else if existing, ok := store.lookup(v1Layer.DiffID); ok {
+			log.Printf("层 %d/%d 已存在，跳过下载 (%s)", i+1, len(manifest.Layers), layer.Digest[:19])
+			store.link(layerPath, existing)
+		} else {
+			// 下载层文件，使用临时文件名以免并发下载同一层时互相覆盖；缓存中已有的原始层直接使用
+			suffix := fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())
+			blobPath, cached := p.blobCache.Has(layer.Digest)
+			if cached {
+				log.Printf("层 %d/%d 使用缓存的原始层 (%s)", i+1, len(manifest.Layers), layer.Digest[:19])
+			} else {
+				layerURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", registry.URL, imageInfo.Repository, layer.Digest)
+				blobPath = filepath.Join(layerDir, "layer_blob."+suffix)
 
-		// 下载层文件
-		layerURL := fmt.Sprintf("https://%s/v2/%s/blobs/%s", registry.URL, imageInfo.Repository, layer.Digest)
-		gzipPath := filepath.Join(layerDir, "layer_gzip.tar")
+				desc := fmt.Sprintf("Layer %d/%d", i+1, len(manifest.Layers))
+				if err := p.DownloadBlobWithProgress(layerURL, token, blobPath, desc, layer.Digest); err != nil {
+					return nil, "", fmt.Errorf("下载层 %s 失败: %v", layer.Digest[:12], err)
+				}
+			}
 
-		desc := fmt.Sprintf("Layer %d/%d", i+1, len(manifest.Layers))
-		if err := p.DownloadFileWithProgress(layerURL, token, gzipPath, desc); err != nil {
-			return fmt.Errorf("下载层 %s 失败: %v", layer.Digest[:12], err)
-		}
+			// 解压层文件并校验 diff_id
+			tmpTarPath := filepath.Join(layerDir, "layer.tar."+suffix)
+			diffID, err := p.decompressLayer(blobPath, tmpTarPath)
 
-		// 解压层文件
-		tarPath := filepath.Join(layerDir, "layer.tar")
-		if err := p.decompressGzip(gzipPath, tarPath); err != nil {
-			return fmt.Errorf("解压层失败: %v", err)
-		}
+			// 校验通过的压缩层移入缓存供推送复用，其余情况删除压缩的层文件
+			if !cached {
+				if err == nil && diffID == v1Layer.DiffID && p.blobCache != nil {
+					if err := p.blobCache.Store(blobPath, layer, diffID); err != nil {
+						log.Printf("⚠️ 缓存层 %s 失败: %v", layer.Digest[:19], err)
+					}
+				}
+				os.Remove(blobPath)
+			}
+
+			if err != nil {
+				os.Remove(tmpTarPath)
+				return nil, "", fmt.Errorf("解压层失败: %v", err)
+			}
+
+			if diffID != v1Layer.DiffID {
+				os.Remove(tmpTarPath)
+				return nil, "", fmt.Errorf("层 %s 校验失败: diff_id 期望 %s，实际 %s", layer.Digest[:19], v1Layer.DiffID, diffID)
+			}
 
-		// 删除gzip文件
-		os.Remove(gzipPath)
+			// 并发下载同一层时只保留先完成的一份
+			if existing, ok := store.add(v1Layer.DiffID, layerPath); ok {
+				if err := os.Rename(tmpTarPath, filepath.Join(layerDir, "layer.tar")); err != nil {
+					return nil, "", fmt.Errorf("保存层失败: %v", err)
+				}
+			} else {
+				os.Remove(tmpTarPath)
+				store.link(layerPath, existing)
+			}
+		}
 
-		// 写入层JSON
-		jsonPath := filepath.Join(layerDir, "json")
-		jsonData, _ := json.Marshal(layerJSON)
-		if err := os.WriteFile(jsonPath, jsonData, 0644); err != nil {
-			return fmt.Errorf("写入层JSON失败: %v", err)
+		// 写入层元数据
+		if err := os.WriteFile(filepath.Join(layerDir, "VERSION"), []byte("1.0"), 0644); err != nil {
+			return nil, "", fmt.Errorf("写入层VERSION失败: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(layerDir, "json"), v1Layer.JSON, 0644); err != nil {
+			return nil, "", fmt.Errorf("写入层JSON失败: %v", err)
 		}
 
-		layerPaths = append(layerPaths, fakeLayerID+"/layer.tar")
-		parentID = fakeLayerID
-		lastLayerID = fakeLayerID
+		layerPaths = append(layerPaths, layerPath)
 	}
 
-	// 创建manifest.json
-	repoTag := fmt.Sprintf("%s:%s", imageInfo.Image, imageInfo.Tag)
-	if !p.configManager.GetConfig().Settings.RemoveRegistryPrefix {
-		repoTag = fmt.Sprintf("%s:%s", imageInfo.Repository, imageInfo.Tag)
+	entry := &ArchiveManifestEntry{
+		Config:   configFilename,
+		RepoTags: repoTags,
+		Layers:   layerPaths,
 	}
+	return entry, v1Layers[len(v1Layers)-1].ID, nil
+}
 
-	manifestContent := []map[string]interface{}{
-		{
-			// "Config":   filepath.Base(tmpDir) + "/" + layers[0].Digest[7:] + ".json",
-			"Config":   manifest.Config.Digest[7:] + ".json",
-			"RepoTags": []string{repoTag},
-			"Layers":   layerPaths,
-		},
+// decompressLayer 解压层文件（支持 gzip/zstd/未压缩），返回解压后内容的 diff_id
+func (p *MultiRegistryImagePuller) decompressLayer(src, dst string) (string, error) {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return "", err
 	}
+	defer srcFile.Close()
 
-	manifestData, _ := json.Marshal(manifestContent)
-	manifestPath := filepath.Join(tmpDir, "manifest.json")
-	if err := os.WriteFile(manifestPath, manifestData, 0644); err != nil {
-		return fmt.Errorf("写入manifest.json失败: %v", err)
+	reader, err := newDecompressReader(srcFile)
+	if err != nil {
+		return "", err
 	}
+	defer reader.Close()
 
-	// 创建repositories文件
-	repositories := map[string]map[string]string{
-		imageInfo.Image: {
-			imageInfo.Tag: lastLayerID, // 使用最后一个层的digest ID
-		},
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return "", err
 	}
+	defer dstFile.Close()
 
-	repositoriesData, _ := json.Marshal(repositories)
-	repositoriesPath := filepath.Join(tmpDir, "repositories")
-	if err := os.WriteFile(repositoriesPath, repositoriesData, 0644); err != nil {
-		return fmt.Errorf("写入repositories失败: %v", err)
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dstFile, hasher), reader); err != nil {
+		return "", err
 	}
 
-	return nil
+	return "sha256:" + hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// decompressGzip 解压gzip文件
-func (p *MultiRegistryImagePuller) decompressGzip(src, dst string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
+// resolveOutputFile 根据输出选项和文件名模板确定输出文件路径
+func (p *MultiRegistryImagePuller) resolveOutputFile(registry *config.RegistryConfig, imageInfo ImageInfo, manifest *ManifestResponse, arch string, options PullOptions) (string, error) {
+	if options.Output == "-" {
+		return "-", nil
 	}
-	defer srcFile.Close()
 
-	gzipReader, err := gzip.NewReader(srcFile)
+	compression, err := NormalizeCompression(options.Compression)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer gzipReader.Close()
 
-	dstFile, err := os.Create(dst)
-	if err != nil {
-		return err
+	settings := p.configManager.GetConfig().Settings
+	outputDir := options.OutputDir
+	if outputDir == "" {
+		outputDir = settings.OutputDir
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, gzipReader)
-	return err
+	name := options.Output
+	if name == "" {
+		template := options.NameTemplate
+		if template == "" {
+			template = settings.FilenameTemplate
+		}
+		name = RenderOutputName(template, OutputNameVars{
+			Registry: registry.URL,
+			Repo:     imageInfo.Repository,
+			Tag:      imageInfo.Tag,
+			Arch:     arch,
+			Digest:   manifest.Config.Digest,
+		}, CompressionExt(compression))
+	} else if filepath.IsAbs(name) {
+		// 显式指定的绝对路径不再拼接输出目录
+		outputDir = ""
+	}
+
+	// 批量拉取时是否覆盖由调用方根据已有文件的校验结果决定
+	return ResolveOutputPath(outputDir, name, options.Force || options.SkipExisting)
 }
 
 // createImageTar 创建镜像tar文件
-func (p *MultiRegistryImagePuller) createImageTar(tmpDir string, imageInfo ImageInfo, arch string) (string, error) {
-	safeRepo := strings.ReplaceAll(imageInfo.Repository, "/", "_")
-	outputFile := fmt.Sprintf("%s_%s_%s.tar", safeRepo, imageInfo.Tag, arch)
+func (p *MultiRegistryImagePuller) createImageTar(tmpDir, outputFile string, links map[string]string, options PullOptions) error {
+	compression, err := NormalizeCompression(options.Compression)
+	if err != nil {
+		return err
+	}
+
+	// 输出到标准输出时直接写入，便于通过管道传给 docker load 或 ssh
+	var out io.Writer
+	if outputFile == "-" {
+		out = options.Stdout
+		if out == nil {
+			out = os.Stdout
+		}
+	} else if options.SplitSize > 0 {
+		// 按大小切分为编号分卷，适用于 FAT32 介质或有单文件大小限制的传输通道
+		removeSplitSet(outputFile)
+		writer := newSplitWriter(outputFile, options.SplitSize)
+		if err := writeImageTar(writer, tmpDir, compression, links); err != nil {
+			writer.Abort()
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			writer.Abort()
+			return err
+		}
+		return nil
+	} else {
+		// 先写入临时文件，完整写入并关闭后再重命名，中断时不会留下不完整的归档
+		tmpFile := outputFile + ".tmp"
+		file, err := os.Create(tmpFile)
+		if err != nil {
+			return fmt.Errorf("创建tar文件失败: %v", err)
+		}
+		if err := writeImageTar(file, tmpDir, compression, links); err != nil {
+			file.Close()
+			os.Remove(tmpFile)
+			return err
+		}
+		if err := file.Close(); err != nil {
+			os.Remove(tmpFile)
+			return fmt.Errorf("写入tar文件失败: %v", err)
+		}
+		if err := os.Rename(tmpFile, outputFile); err != nil {
+			os.Remove(tmpFile)
+			return fmt.Errorf("重命名tar文件失败: %v", err)
+		}
+		return nil
+	}
+
+	return writeImageTar(out, tmpDir, compression, links)
+}
 
-	file, err := os.Create(outputFile)
+// writeImageTar 将临时目录打包为（可选压缩的）tar流，links 为需要额外写入的层符号链接
+func writeImageTar(out io.Writer, tmpDir, compression string, links map[string]string) error {
+	compressWriter, err := newCompressWriter(out, compression)
 	if err != nil {
-		return "", fmt.Errorf("创建tar文件失败: %v", err)
+		return err
 	}
-	defer file.Close()
 
-	tarWriter := tar.NewWriter(file)
-	defer tarWriter.Close()
+	tarWriter := tar.NewWriter(compressWriter)
+
+	// 元数据文件写在最前面，读取归档信息时无需扫描全部层数据
+	metadataFiles := []string{"manifest.json", "repositories", DeltaMetadataName}
+	for _, name := range metadataFiles {
+		if err := addFileToTar(tarWriter, filepath.Join(tmpDir, name), name); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("创建tar失败: %v", err)
+		}
+	}
 
 	// 遍历临时目录，添加所有文件到tar
 	err = filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
@@ -835,8 +1140,8 @@ func (p *MultiRegistryImagePuller) createImageTar(tmpDir string, imageInfo Image
 			return err
 		}
 
-		// 跳过根目录
-		if relPath == "." {
+		// 跳过根目录和已写入的元数据文件
+		if relPath == "." || relPath == metadataFiles[0] || relPath == metadataFiles[1] || relPath == metadataFiles[2] {
 			return nil
 		}
 
@@ -870,10 +1175,47 @@ func (p *MultiRegistryImagePuller) createImageTar(tmpDir string, imageInfo Image
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("创建tar失败: %v", err)
+		return fmt.Errorf("创建tar失败: %v", err)
+	}
+
+	if err := writeSymlinks(tarWriter, links); err != nil {
+		return fmt.Errorf("创建tar失败: %v", err)
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("写入tar失败: %v", err)
+	}
+	if err := compressWriter.Close(); err != nil {
+		return fmt.Errorf("压缩输出失败: %v", err)
+	}
+
+	return nil
+}
+
+// addFileToTar 将单个文件以指定名称写入tar
+func addFileToTar(tarWriter *tar.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
 	}
+	header.Name = name
 
-	return outputFile, nil
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tarWriter, file)
+	return err
 }
 
 // CleanupTmpDir 清理临时目录
@@ -0,0 +1,898 @@
+package puller
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// 推送时使用的媒体类型
+const (
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerConfig   = "application/vnd.docker.container.image.v1+json"
+	mediaTypeDockerLayer    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	mediaTypeOCILayerGzip   = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// PushTarget 推送目标镜像引用
+type PushTarget struct {
+	Registry   string // 仓库地址，例如 registry.local:5000
+	Repository string // 仓库中的镜像路径，例如 proj/app
+	Tag        string
+}
+
+// String 返回完整的镜像引用
+func (t PushTarget) String() string {
+	return fmt.Sprintf("%s/%s:%s", t.Registry, t.Repository, t.Tag)
+}
+
+// ParsePushTarget 解析推送目标，引用中必须包含仓库地址
+func ParsePushTarget(ref string) (PushTarget, error) {
+	ref = strings.TrimSpace(ref)
+	if strings.Contains(ref, "@") {
+		return PushTarget{}, fmt.Errorf("推送目标不能使用 digest 引用: %s", ref)
+	}
+
+	i := strings.Index(ref, "/")
+	if i == -1 {
+		return PushTarget{}, fmt.Errorf("推送目标 %s 缺少仓库地址（例如 registry.local/proj/app:tag）", ref)
+	}
+	registry, remainder := ref[:i], ref[i+1:]
+	if !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		return PushTarget{}, fmt.Errorf("推送目标 %s 缺少仓库地址（例如 registry.local/proj/app:tag）", ref)
+	}
+
+	repository, tag := remainder, "latest"
+	if j := strings.LastIndex(remainder, ":"); j > strings.LastIndex(remainder, "/") {
+		repository, tag = remainder[:j], remainder[j+1:]
+	}
+	if repository == "" || tag == "" {
+		return PushTarget{}, fmt.Errorf("无效的推送目标: %s", ref)
+	}
+	return PushTarget{Registry: registry, Repository: repository, Tag: tag}, nil
+}
+
+// PushOptions 推送选项
+type PushOptions struct {
+	Username  string
+	Password  string
+	Image     string   // 归档包含多个镜像时，按 RepoTag 选择要推送的镜像
+	ChunkSize int64    // 分块上传的块大小，0 表示整体上传
+	MountFrom []string // 目标仓库中可能已有相同层的其他镜像路径，尝试跨仓库挂载
+	PlainHTTP bool     // 使用 HTTP 访问目标仓库
+	Keys      *Keyring // 加密归档的解密密钥
+}
+
+// PushResult 推送结果
+type PushResult struct {
+	Target   string        // 推送目标
+	Source   []string      // 归档中镜像的 RepoTags
+	Digest   string        // 推送后的清单digest
+	Layers   int           // 层数量
+	Uploaded int           // 实际上传的 blob 数量（含配置）
+	Mounted  int           // 通过跨仓库挂载复用的 blob 数量
+	Existing int           // 目标仓库中已存在的 blob 数量
+	Cached   int           // 直接使用缓存中原始 blob 的层数量
+	Size     int64         // 实际上传的字节数
+	Duration time.Duration // 耗时
+}
+
+// pushBlob 待推送的 blob
+type pushBlob struct {
+	Path      string
+	MediaType string
+	Digest    string
+	Size      int64
+}
+
+// archiveImage 从归档中提取的待推送镜像
+type archiveImage struct {
+	RepoTags   []string
+	ConfigPath string
+	Layers     []string // 未压缩层文件在工作目录中的路径
+}
+
+// PushArchive 将归档（支持压缩、分卷和加密）中的镜像直接推送到目标仓库，不依赖 docker 命令。
+// 层优先使用 blob 缓存中的原始压缩 blob，没有缓存时重新以 gzip 压缩
+func (p *MultiRegistryImagePuller) PushArchive(archivePath, target string, options PushOptions) (*PushResult, error) {
+	start := time.Now()
+	result := &PushResult{Target: target}
+
+	ref, err := ParsePushTarget(target)
+	if err != nil {
+		return result, err
+	}
+	result.Target = ref.String()
+
+	workDir, err := newWorkDir("push-")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(workDir)
+
+	log.Printf("正在读取归档 %s...", archivePath)
+	images, err := extractArchiveImages(archivePath, options.Keys, workDir)
+	if err != nil {
+		return result, err
+	}
+	image, err := selectArchiveImage(images, options.Image)
+	if err != nil {
+		return result, err
+	}
+	result.Source = image.RepoTags
+
+	configData, imageConfig, err := readImageConfig(image.ConfigPath)
+	if err != nil {
+		return result, err
+	}
+	if len(imageConfig.RootFS.DiffIDs) != len(image.Layers) {
+		return result, fmt.Errorf("镜像配置中的 diff_ids 数量(%d)与层数量(%d)不一致", len(imageConfig.RootFS.DiffIDs), len(image.Layers))
+	}
+	result.Layers = len(image.Layers)
+
+	client := p.newRegistryClient(ref.Registry, options)
+	mountFrom := append(p.pushedRepositories(ref.Registry), options.MountFrom...)
+	if err := client.authorize(ref.Repository, mountFrom); err != nil {
+		return result, fmt.Errorf("目标仓库认证失败: %v", err)
+	}
+
+	manifest := ManifestResponse{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeDockerManifest,
+		Config: ConfigDescriptor{
+			MediaType: mediaTypeDockerConfig,
+			Size:      int64(len(configData)),
+			Digest:    sha256Digest(configData),
+		},
+	}
+
+	for i, layerPath := range image.Layers {
+		diffID := imageConfig.RootFS.DiffIDs[i]
+		blob, cached, err := p.prepareLayerBlob(layerPath, diffID, workDir)
+		if err != nil {
+			return result, fmt.Errorf("准备层 %d/%d 失败: %v", i+1, len(image.Layers), err)
+		}
+		if cached {
+			result.Cached++
+		}
+
+		desc := fmt.Sprintf("Layer %d/%d", i+1, len(image.Layers))
+		if err := p.pushBlobTo(client, ref, blob, desc, mountFrom, options.ChunkSize, result); err != nil {
+			return result, fmt.Errorf("推送层 %s 失败: %v", blob.Digest[:19], err)
+		}
+		manifest.Layers = append(manifest.Layers, LayerDescriptor{
+			MediaType: blob.MediaType,
+			Size:      blob.Size,
+			Digest:    blob.Digest,
+		})
+	}
+
+	configBlob := pushBlob{
+		Path:      image.ConfigPath,
+		MediaType: mediaTypeDockerConfig,
+		Digest:    manifest.Config.Digest,
+		Size:      manifest.Config.Size,
+	}
+	if err := p.pushBlobTo(client, ref, configBlob, "Config", mountFrom, options.ChunkSize, result); err != nil {
+		return result, fmt.Errorf("推送配置失败: %v", err)
+	}
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return result, fmt.Errorf("序列化清单失败: %v", err)
+	}
+	digest, err := client.putManifest(ref.Repository, ref.Tag, mediaTypeDockerManifest, manifestData)
+	if err != nil {
+		return result, fmt.Errorf("推送清单失败: %v", err)
+	}
+	result.Digest = digest
+	p.recordPushedRepository(ref.Registry, ref.Repository)
+
+	log.Printf("✅ 已推送 %s@%s", result.Target, digest)
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// extractArchiveImages 将归档中的文件解压到工作目录，并根据 manifest.json 返回其中的镜像
+func extractArchiveImages(archivePath string, keys *Keyring, workDir string) ([]archiveImage, error) {
+	stream, err := OpenArchive(archivePath, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	reader, err := newDecompressReader(stream)
+	if err != nil {
+		return nil, fmt.Errorf("解压归档失败: %v", err)
+	}
+	defer reader.Close()
+
+	links := make(map[string]string)
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取归档失败: %v", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if !isContainedPath(name) {
+			return nil, fmt.Errorf("归档中包含非法路径: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeSymlink, tar.TypeLink:
+			target := path.Clean(header.Linkname)
+			if header.Typeflag == tar.TypeSymlink {
+				target = path.Clean(path.Join(path.Dir(name), header.Linkname))
+			}
+			if path.IsAbs(header.Linkname) || !isContainedPath(target) {
+				return nil, fmt.Errorf("归档中的链接 %s 指向工作目录之外: %s", header.Name, header.Linkname)
+			}
+			links[name] = target
+		case tar.TypeReg:
+			target := filepath.Join(workDir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(out, tarReader)
+			out.Close()
+			if err != nil {
+				return nil, fmt.Errorf("读取 %s 失败: %v", name, err)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(workDir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("归档中未找到 manifest.json")
+	}
+	var entries []ArchiveManifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析 manifest.json 失败: %v", err)
+	}
+
+	// manifest.json 中的路径和链接目标都不可信，解析结果必须位于工作目录内
+	resolve := func(name string) (string, error) {
+		resolved := path.Clean(strings.TrimPrefix(name, "./"))
+		for i := 0; i < 16; i++ {
+			target, ok := links[resolved]
+			if !ok {
+				break
+			}
+			resolved = target
+		}
+		if !isContainedPath(resolved) {
+			return "", fmt.Errorf("manifest.json 中的路径 %s 指向工作目录之外", name)
+		}
+		return filepath.Join(workDir, filepath.FromSlash(resolved)), nil
+	}
+
+	_, deltaErr := os.Stat(filepath.Join(workDir, DeltaMetadataName))
+	var images []archiveImage
+	for _, entry := range entries {
+		configPath, err := resolve(entry.Config)
+		if err != nil {
+			return nil, err
+		}
+		image := archiveImage{RepoTags: entry.RepoTags, ConfigPath: configPath}
+		for _, layer := range entry.Layers {
+			layerPath, err := resolve(layer)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(layerPath); err != nil {
+				if deltaErr == nil {
+					return nil, fmt.Errorf("增量归档缺少基线层 %s，请先使用 merge 补全后再推送", layer)
+				}
+				return nil, fmt.Errorf("归档中缺少层 %s", layer)
+			}
+			image.Layers = append(image.Layers, layerPath)
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// isContainedPath 检查已规范化的归档内相对路径是否位于根目录之内
+func isContainedPath(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.HasPrefix(name, "../") && !path.IsAbs(name)
+}
+
+// selectArchiveImage 选择要推送的镜像，归档只有一个镜像时可以不指定
+func selectArchiveImage(images []archiveImage, name string) (*archiveImage, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("归档中没有镜像")
+	}
+	if name == "" {
+		if len(images) == 1 {
+			return &images[0], nil
+		}
+		var tags []string
+		for _, image := range images {
+			tags = append(tags, image.RepoTags...)
+		}
+		return nil, fmt.Errorf("归档包含 %d 个镜像（%s），请指定要推送的镜像", len(images), strings.Join(tags, ", "))
+	}
+
+	// 默认打标签模式会去掉仓库前缀，因此 bitnami/redis:7 也可以匹配 redis:7
+	want := normalizeReference(name, "latest")
+	for i := range images {
+		for _, tag := range images[i].RepoTags {
+			if tag == want || strings.HasSuffix(want, "/"+tag) {
+				return &images[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("归档中没有镜像 %s", name)
+}
+
+// prepareLayerBlob 返回层对应的压缩 blob：优先使用缓存中的原始 blob，否则以 gzip 压缩并校验 diff_id
+func (p *MultiRegistryImagePuller) prepareLayerBlob(layerPath, diffID, workDir string) (pushBlob, bool, error) {
+	if layer, ok := p.blobCache.Lookup(diffID); ok {
+		switch layer.MediaType {
+		case mediaTypeDockerLayer, mediaTypeOCILayerGzip:
+			return pushBlob{
+				Path:      p.blobCache.blobPath(layer.Digest),
+				MediaType: mediaTypeDockerLayer,
+				Digest:    layer.Digest,
+				Size:      layer.Size,
+			}, true, nil
+		}
+	}
+
+	src, err := os.Open(layerPath)
+	if err != nil {
+		return pushBlob{}, false, err
+	}
+	defer src.Close()
+
+	blobPath := filepath.Join(workDir, strings.TrimPrefix(diffID, "sha256:")+".tar.gz")
+	out, err := os.Create(blobPath)
+	if err != nil {
+		return pushBlob{}, false, err
+	}
+	defer out.Close()
+
+	blobHasher := sha256.New()
+	counter := &countingWriter{}
+	gzipWriter := gzip.NewWriter(io.MultiWriter(out, blobHasher, counter))
+	diffHasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(gzipWriter, diffHasher), src); err != nil {
+		return pushBlob{}, false, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return pushBlob{}, false, err
+	}
+
+	if actual := "sha256:" + hex.EncodeToString(diffHasher.Sum(nil)); actual != diffID {
+		return pushBlob{}, false, fmt.Errorf("diff_id 校验失败: 期望 %s，实际 %s", diffID, actual)
+	}
+	return pushBlob{
+		Path:      blobPath,
+		MediaType: mediaTypeDockerLayer,
+		Digest:    "sha256:" + hex.EncodeToString(blobHasher.Sum(nil)),
+		Size:      counter.n,
+	}, false, nil
+}
+
+// blobTransfer blob 推送到目标仓库的方式
+type blobTransfer int
+
+const (
+	blobUploaded blobTransfer = iota // 实际上传
+	blobMounted                      // 跨仓库挂载
+	blobExisting                     // 目标仓库中已存在
+)
+
+// transferBlob 推送单个 blob：已存在时跳过，其次尝试跨仓库挂载，最后调用 open 读取内容并上传
+func (p *MultiRegistryImagePuller) transferBlob(client *registryClient, repository, digest string, size int64, desc string, mountFrom []string, chunkSize int64, open func() (io.ReadCloser, error)) (blobTransfer, error) {
+	exists, err := client.blobExists(repository, digest)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		log.Printf("%s 已存在于目标仓库，跳过 (%s)", desc, digest[:19])
+		return blobExisting, nil
+	}
+
+	location := ""
+	for _, from := range mountFrom {
+		if from == repository {
+			continue
+		}
+		mounted, uploadLocation, err := client.mountBlob(repository, digest, from)
+		if err != nil {
+			return 0, err
+		}
+		if mounted {
+			log.Printf("%s 已从 %s 挂载 (%s)", desc, from, digest[:19])
+			return blobMounted, nil
+		}
+		// 挂载失败时仓库会直接开启上传会话，只使用第一个
+		if location == "" {
+			location = uploadLocation
+		}
+	}
+	if location == "" {
+		if location, err = client.startUpload(repository); err != nil {
+			return 0, err
+		}
+	}
+
+	reader, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	var bar *progressbar.ProgressBar
+	if p.configManager.GetConfig().Settings.EnableProgressBar && !p.disableProgress {
+		bar = progressbar.DefaultBytes(size, desc)
+	}
+
+	if chunkSize > 0 && size > chunkSize {
+		err = client.uploadChunked(location, progressReader(reader, bar), digest, size, chunkSize)
+	} else {
+		err = client.uploadMonolithic(location, progressReader(reader, bar), digest, size)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return blobUploaded, nil
+}
+
+// pushBlobTo 推送工作目录或缓存中的 blob 并累计推送结果
+func (p *MultiRegistryImagePuller) pushBlobTo(client *registryClient, ref PushTarget, blob pushBlob, desc string, mountFrom []string, chunkSize int64, result *PushResult) error {
+	transfer, err := p.transferBlob(client, ref.Repository, blob.Digest, blob.Size, desc, mountFrom, chunkSize, func() (io.ReadCloser, error) {
+		return os.Open(blob.Path)
+	})
+	if err != nil {
+		return err
+	}
+	switch transfer {
+	case blobExisting:
+		result.Existing++
+	case blobMounted:
+		result.Mounted++
+	default:
+		result.Uploaded++
+		result.Size += blob.Size
+	}
+	return nil
+}
+
+// pushedRepositories 返回本次运行中已推送到指定仓库地址的镜像路径，用作跨仓库挂载的来源
+func (p *MultiRegistryImagePuller) pushedRepositories(registry string) []string {
+	p.pushMu.Lock()
+	defer p.pushMu.Unlock()
+	return append([]string(nil), p.pushed[registry]...)
+}
+
+// recordPushedRepository 记录已推送的镜像路径
+func (p *MultiRegistryImagePuller) recordPushedRepository(registry, repository string) {
+	p.pushMu.Lock()
+	defer p.pushMu.Unlock()
+	if p.pushed == nil {
+		p.pushed = make(map[string][]string)
+	}
+	p.pushed[registry] = appendUnique(p.pushed[registry], repository)
+}
+
+// registryClient 推送使用的 OCI distribution 客户端
+type registryClient struct {
+	client        *http.Client
+	baseURL       string
+	username      string
+	password      string
+	authorization string   // 认证后的 Authorization 头
+	scopes        []string // 令牌的权限范围，令牌过期时按相同范围重新申请
+}
+
+// newRegistryClient 创建目标仓库客户端
+func (p *MultiRegistryImagePuller) newRegistryClient(registry string, options PushOptions) *registryClient {
+	scheme := "https"
+	if options.PlainHTTP {
+		scheme = "http"
+	}
+	return &registryClient{
+		client:   p.httpClient,
+		baseURL:  scheme + "://" + registry,
+		username: options.Username,
+		password: options.Password,
+	}
+}
+
+// parseAuthChallenge 解析 WWW-Authenticate 头，返回认证方式和参数
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end == -1 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			params[key] = value
+		}
+	}
+	return strings.ToLower(scheme), params
+}
+
+// authorize 根据仓库返回的认证要求获取推送令牌，mountFrom 中的镜像路径同时申请拉取权限
+func (c *registryClient) authorize(repository string, mountFrom []string) error {
+	scopes := []string{fmt.Sprintf("repository:%s:pull,push", repository)}
+	for _, from := range mountFrom {
+		if from != repository {
+			scopes = append(scopes, fmt.Sprintf("repository:%s:pull", from))
+		}
+	}
+	return c.authorizeScopes(scopes)
+}
+
+// authorizeScopes 根据仓库返回的认证要求获取指定权限范围的令牌，仓库使用 Basic 认证时直接使用用户名和密码
+func (c *registryClient) authorizeScopes(scopes []string) error {
+	c.scopes = scopes
+	resp, err := c.client.Get(c.baseURL + "/v2/")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return fmt.Errorf("访问 %s 失败，状态码: %d", c.baseURL, resp.StatusCode)
+	}
+
+	basic := ""
+	if c.username != "" || c.password != "" {
+		basic = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password))
+	}
+
+	scheme, params := parseAuthChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch scheme {
+	case "basic":
+		if basic == "" {
+			return fmt.Errorf("目标仓库需要用户名和密码")
+		}
+		c.authorization = basic
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("不支持的认证方式: %s", scheme)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("认证头格式错误")
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	for _, scope := range scopes {
+		query.Add("scope", scope)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if basic != "" {
+		req.Header.Set("Authorization", basic)
+	}
+	tokenResp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("获取令牌失败: %v", err)
+	}
+	defer tokenResp.Body.Close()
+	if tokenResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("获取令牌失败，状态码: %d", tokenResp.StatusCode)
+	}
+
+	var token AuthToken
+	if err := json.NewDecoder(tokenResp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("解析令牌失败: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	c.authorization = "Bearer " + token.Token
+	return nil
+}
+
+// do 发送带认证头的请求；Bearer 令牌过期（401）时重新申请令牌，请求体可以重放时重试一次。
+// 流式上传的请求体无法重放，此时只刷新令牌并返回 401 响应，后续请求使用新令牌
+func (c *registryClient) do(method, rawURL string, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	req, err := c.newRequest(method, rawURL, body, size, headers)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(c.authorization, "Bearer ") {
+		return resp, nil
+	}
+
+	if err := c.authorizeScopes(c.scopes); err != nil {
+		return resp, nil
+	}
+	if body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if body != nil {
+		if body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("创建请求失败: %v", err)
+		}
+	}
+	if req, err = c.newRequest(method, rawURL, body, size, headers); err != nil {
+		return nil, err
+	}
+	if resp, err = c.client.Do(req); err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	return resp, nil
+}
+
+// newRequest 创建带认证头的请求
+func (c *registryClient) newRequest(method, rawURL string, body io.Reader, size int64, headers map[string]string) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+	return req, nil
+}
+
+// registryError 从仓库的错误响应中提取错误信息
+func registryError(action string, resp *http.Response) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if json.Unmarshal(data, &body) == nil && len(body.Errors) > 0 {
+		var messages []string
+		for _, e := range body.Errors {
+			messages = append(messages, strings.TrimSpace(e.Code+" "+e.Message))
+		}
+		return fmt.Errorf("%s失败，状态码: %d (%s)", action, resp.StatusCode, strings.Join(messages, "; "))
+	}
+	return fmt.Errorf("%s失败，状态码: %d", action, resp.StatusCode)
+}
+
+// resolveLocation 将上传会话的 Location 解析为绝对地址
+func (c *registryClient) resolveLocation(location string) (string, error) {
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("无效的上传地址: %s", location)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// uploadLocation 读取响应中的上传地址
+func (c *registryClient) uploadLocation(resp *http.Response) (string, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("仓库未返回上传地址")
+	}
+	return c.resolveLocation(location)
+}
+
+// blobExists 检查目标仓库中是否已存在 blob
+func (c *registryClient) blobExists(repository, digest string) (bool, error) {
+	resp, err := c.do("HEAD", fmt.Sprintf("%s/v2/%s/blobs/%s", c.baseURL, repository, digest), nil, 0, nil)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("检查 blob 失败，状态码: %d", resp.StatusCode)
+	}
+}
+
+// mountBlob 尝试从同一仓库地址下的其他镜像路径挂载 blob；未挂载时返回仓库开启的上传地址
+func (c *registryClient) mountBlob(repository, digest, from string) (bool, string, error) {
+	query := url.Values{"mount": {digest}, "from": {from}}
+	resp, err := c.do("POST", fmt.Sprintf("%s/v2/%s/blobs/uploads/?%s", c.baseURL, repository, query.Encode()), nil, 0, nil)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, "", nil
+	case http.StatusAccepted:
+		location, err := c.uploadLocation(resp)
+		return false, location, err
+	default:
+		// 无权访问来源镜像等情况不影响推送，改为直接上传
+		return false, "", nil
+	}
+}
+
+// startUpload 开启上传会话
+func (c *registryClient) startUpload(repository string) (string, error) {
+	resp, err := c.do("POST", fmt.Sprintf("%s/v2/%s/blobs/uploads/", c.baseURL, repository), nil, 0, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return "", registryError("开启上传会话", resp)
+	}
+	return c.uploadLocation(resp)
+}
+
+// withDigest 为上传地址添加 digest 参数
+func withDigest(location, digest string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("digest", digest)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// progressReader 包装读取器，同时更新进度条
+func progressReader(r io.Reader, bar *progressbar.ProgressBar) io.Reader {
+	if bar == nil {
+		return r
+	}
+	return io.TeeReader(r, bar)
+}
+
+// uploadMonolithic 使用单个 PUT 请求上传整个 blob
+func (c *registryClient) uploadMonolithic(location string, r io.Reader, digest string, size int64) error {
+	putURL, err := withDigest(location, digest)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do("PUT", putURL, r, size, map[string]string{
+		"Content-Type": "application/octet-stream",
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return registryError("上传 blob ", resp)
+	}
+	return nil
+}
+
+// uploadChunked 使用 PATCH 按顺序分块上传 blob，最后以 PUT 提交
+func (c *registryClient) uploadChunked(location string, r io.Reader, digest string, size, chunkSize int64) error {
+	for offset := int64(0); offset < size; offset += chunkSize {
+		n := chunkSize
+		if remaining := size - offset; remaining < n {
+			n = remaining
+		}
+		resp, err := c.do("PATCH", location, io.LimitReader(r, n), n, map[string]string{
+			"Content-Type":  "application/octet-stream",
+			"Content-Range": fmt.Sprintf("%d-%d", offset, offset+n-1),
+		})
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			err := registryError("上传分块", resp)
+			resp.Body.Close()
+			return err
+		}
+		location, err = c.uploadLocation(resp)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	putURL, err := withDigest(location, digest)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do("PUT", putURL, bytes.NewReader(nil), 0, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return registryError("提交上传", resp)
+	}
+	return nil
+}
+
+// manifestDigest 查询目标仓库中标签对应的清单digest，标签不存在时返回空字符串
+func (c *registryClient) manifestDigest(repository, reference string) (string, error) {
+	resp, err := c.do("HEAD", fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL, repository, reference), nil, 0, map[string]string{
+		"Accept": manifestAccept,
+	})
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("Docker-Content-Digest"), nil
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("查询清单失败，状态码: %d", resp.StatusCode)
+	}
+}
+
+// putManifest 上传清单，返回清单digest
+func (c *registryClient) putManifest(repository, tag, mediaType string, data []byte) (string, error) {
+	resp, err := c.do("PUT", fmt.Sprintf("%s/v2/%s/manifests/%s", c.baseURL, repository, tag), bytes.NewReader(data), int64(len(data)), map[string]string{
+		"Content-Type": mediaType,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", registryError("上传清单", resp)
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	return sha256Digest(data), nil
+}
@@ -0,0 +1,387 @@
+package puller
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version 从镜像标签解析出的版本号，例如 v1.27.4、1.25、1.25.3-alpine
+type Version struct {
+	Numbers [3]int // 主版本号、次版本号、修订号，缺省部分为 0
+	Parts   int    // 标签中实际给出的数字段数
+	Suffix  string // 连字符后的后缀，例如 alpine、rc1
+	Tag     string // 原始标签
+}
+
+// ParseVersion 解析标签中的版本号，不是版本号格式时返回 false
+func ParseVersion(tag string) (Version, bool) {
+	version := Version{Tag: tag}
+	s := strings.TrimPrefix(strings.TrimPrefix(tag, "v"), "V")
+	if i := strings.Index(s, "-"); i != -1 {
+		version.Suffix = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return version, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part == "" {
+			return version, false
+		}
+		version.Numbers[i] = n
+	}
+	version.Parts = len(parts)
+	return version, true
+}
+
+// Compare 比较两个版本，依次比较数字段、给出的段数和后缀；
+// 与 semver 的预发布版本一致，带后缀的版本（例如 1.25.3-rc1）排在不带后缀的正式版本之前
+func (v Version) Compare(other Version) int {
+	for i := 0; i < 3; i++ {
+		if v.Numbers[i] != other.Numbers[i] {
+			if v.Numbers[i] < other.Numbers[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	if v.Parts != other.Parts {
+		if v.Parts < other.Parts {
+			return -1
+		}
+		return 1
+	}
+	switch {
+	case v.Suffix == other.Suffix:
+		return 0
+	case v.Suffix == "":
+		return 1
+	case other.Suffix == "":
+		return -1
+	}
+	return compareSuffix(v.Suffix, other.Suffix)
+}
+
+// compareSuffix 按点分隔的标识逐段比较后缀，每段中的数字按数值比较，例如 rc2 < rc10、beta.2 < beta.11
+func compareSuffix(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareNatural(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// compareNatural 比较字符串，连续的数字按数值比较
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		aDigit, bDigit := isDigit(a[0]), isDigit(b[0])
+		if aDigit && bDigit {
+			i, j := digitRun(a), digitRun(b)
+			x := strings.TrimLeft(a[:i], "0")
+			y := strings.TrimLeft(b[:j], "0")
+			if len(x) != len(y) {
+				if len(x) < len(y) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+			a, b = a[i:], b[j:]
+			continue
+		}
+		if a[0] != b[0] {
+			if a[0] < b[0] {
+				return -1
+			}
+			return 1
+		}
+		a, b = a[1:], b[1:]
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digitRun 返回字符串开头连续数字的长度
+func digitRun(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// versionBound 版本范围的一个边界
+type versionBound struct {
+	numbers   [3]int
+	inclusive bool
+}
+
+// compareNumbers 比较版本号的数字段
+func compareNumbers(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionRange 单个比较条件对应的版本范围
+type versionRange struct {
+	lower *versionBound
+	upper *versionBound
+}
+
+// contains 判断版本是否在范围内
+func (r versionRange) contains(numbers [3]int) bool {
+	if r.lower != nil {
+		c := compareNumbers(numbers, r.lower.numbers)
+		if c < 0 || (c == 0 && !r.lower.inclusive) {
+			return false
+		}
+	}
+	if r.upper != nil {
+		c := compareNumbers(numbers, r.upper.numbers)
+		if c > 0 || (c == 0 && !r.upper.inclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// VersionConstraint 标签版本约束，例如 ~1.25、^1.27、>=1.2,<2、1.25.x
+type VersionConstraint struct {
+	ranges []versionRange
+	suffix string // 约束中的后缀，例如 ~1.25-alpine 只匹配 -alpine 标签
+	raw    string
+}
+
+// String 返回约束的原始文本
+func (c *VersionConstraint) String() string {
+	return c.raw
+}
+
+// constraintOperators 支持的比较运算符，较长的运算符在前
+var constraintOperators = []string{">=", "<=", "==", "~>", ">", "<", "=", "~", "^"}
+
+// wildcardVersion 匹配带 x 或 * 通配段的版本，例如 1.25.x
+var wildcardVersion = regexp.MustCompile(`^v?\d+(\.\d+)?\.[xX*]$`)
+
+// IsTagConstraint 判断标签是否为版本约束而不是具体标签。
+// 镜像标签只能包含字母、数字、点、下划线和连字符，因此以 ~ ^ < > = 开头的一定是约束
+func IsTagConstraint(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	if strings.ContainsAny(tag[:1], "~^<>=") || strings.ContainsAny(tag, ", ") {
+		return true
+	}
+	base := tag
+	if i := strings.Index(base, "-"); i != -1 {
+		base = base[:i]
+	}
+	return wildcardVersion.MatchString(base)
+}
+
+// ParseVersionConstraint 解析版本约束，多个条件用逗号或空格分隔，需同时满足
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	constraint := &VersionConstraint{raw: s}
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("版本约束为空")
+	}
+
+	for _, field := range fields {
+		op := ""
+		for _, candidate := range constraintOperators {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				break
+			}
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(field, op))
+
+		if i := strings.Index(text, "-"); i != -1 {
+			suffix := text[i+1:]
+			if constraint.suffix != "" && constraint.suffix != suffix {
+				return nil, fmt.Errorf("版本约束 %s 中的后缀不一致", s)
+			}
+			constraint.suffix = suffix
+			text = text[:i]
+		}
+
+		text = strings.TrimPrefix(strings.TrimPrefix(text, "v"), "V")
+		var numbers [3]int
+		parts := 0
+		for _, part := range strings.Split(text, ".") {
+			if part == "x" || part == "X" || part == "*" {
+				break
+			}
+			if parts == 3 {
+				return nil, fmt.Errorf("无效的版本约束: %s", field)
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("无效的版本约束: %s", field)
+			}
+			numbers[parts] = n
+			parts++
+		}
+		if parts == 0 && op != "" {
+			return nil, fmt.Errorf("无效的版本约束: %s", field)
+		}
+
+		constraint.ranges = append(constraint.ranges, newVersionRange(op, numbers, parts))
+	}
+	return constraint, nil
+}
+
+// bumpVersion 将第 index 段加一并清零后续段
+func bumpVersion(numbers [3]int, index int) [3]int {
+	numbers[index]++
+	for i := index + 1; i < 3; i++ {
+		numbers[i] = 0
+	}
+	return numbers
+}
+
+// newVersionRange 根据运算符和版本生成范围，parts 为约束中给出的数字段数
+func newVersionRange(op string, numbers [3]int, parts int) versionRange {
+	lower := &versionBound{numbers: numbers, inclusive: true}
+
+	// 部分版本（例如 1.25）在 = 和通配中表示该前缀下的所有版本
+	prefixUpper := func() *versionBound {
+		if parts == 0 {
+			return nil
+		}
+		if parts == 3 {
+			return &versionBound{numbers: numbers, inclusive: true}
+		}
+		return &versionBound{numbers: bumpVersion(numbers, parts-1)}
+	}
+
+	switch op {
+	case ">=":
+		return versionRange{lower: lower}
+	case ">":
+		if parts < 3 {
+			return versionRange{lower: &versionBound{numbers: bumpVersion(numbers, parts-1), inclusive: true}}
+		}
+		return versionRange{lower: &versionBound{numbers: numbers}}
+	case "<":
+		return versionRange{upper: &versionBound{numbers: numbers}}
+	case "<=":
+		if parts < 3 {
+			return versionRange{upper: &versionBound{numbers: bumpVersion(numbers, parts-1)}}
+		}
+		return versionRange{upper: &versionBound{numbers: numbers, inclusive: true}}
+	case "~", "~>":
+		// ~1.25.3 和 ~1.25 表示 1.25.x，~1 表示 1.x
+		index := 1
+		if parts == 1 {
+			index = 0
+		}
+		return versionRange{lower: lower, upper: &versionBound{numbers: bumpVersion(numbers, index)}}
+	case "^":
+		// ^1.25 表示 1.x，^0.3 表示 0.3.x
+		index := 0
+		if numbers[0] == 0 && parts > 1 {
+			index = 1
+		}
+		return versionRange{lower: lower, upper: &versionBound{numbers: bumpVersion(numbers, index)}}
+	default:
+		return versionRange{lower: lower, upper: prefixUpper()}
+	}
+}
+
+// Match 判断版本是否满足约束，后缀必须与约束中的后缀一致
+func (c *VersionConstraint) Match(version Version) bool {
+	if !strings.EqualFold(version.Suffix, c.suffix) {
+		return false
+	}
+	for _, r := range c.ranges {
+		if !r.contains(version.Numbers) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortTagsBySemver 按版本号从高到低排序，非版本号标签按名称排在最后
+func SortTagsBySemver(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		a, aok := ParseVersion(tags[i])
+		b, bok := ParseVersion(tags[j])
+		switch {
+		case aok && bok:
+			return a.Compare(b) > 0
+		case aok != bok:
+			return aok
+		default:
+			return tags[i] < tags[j]
+		}
+	})
+}
+
+// MatchTags 返回满足约束的标签，按版本号从高到低排序
+func MatchTags(tags []string, constraint *VersionConstraint) []string {
+	var matched []string
+	for _, tag := range tags {
+		if version, ok := ParseVersion(tag); ok && constraint.Match(version) {
+			matched = append(matched, tag)
+		}
+	}
+	SortTagsBySemver(matched)
+	return matched
+}
+
+// ResolveTagConstraint 将镜像引用中的版本约束（例如 nginx:~1.25）解析为满足约束的最高标签，
+// 标签不是约束时原样返回
+func (p *MultiRegistryImagePuller) ResolveTagConstraint(imageInput, username, password string) (string, error) {
+	imageInfo := p.ParseImageInput(imageInput)
+	if !IsTagConstraint(imageInfo.Tag) {
+		return imageInput, nil
+	}
+
+	constraint, err := ParseVersionConstraint(imageInfo.Tag)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSuffix(imageInput, ":"+imageInfo.Tag)
+	registry, _, tags, err := p.ListTags(name, username, password)
+	if err != nil {
+		return "", err
+	}
+
+	matched := MatchTags(tags, constraint)
+	if len(matched) == 0 {
+		return "", fmt.Errorf("%s 在 %s 中没有满足约束 %s 的标签", imageInfo.Repository, registry.Name, constraint)
+	}
+
+	log.Printf("🏷️ 版本约束 %s 解析为标签 %s（共 %d 个匹配）", constraint, matched[0], len(matched))
+	return name + ":" + matched[0], nil
+}
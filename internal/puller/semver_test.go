@@ -0,0 +1,174 @@
+package puller
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVersionCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.25.3", "1.25.3", 0},
+		{"1.25.3", "1.25.4", -1},
+		{"1.26.0", "1.25.9", 1},
+		{"v1.2.0", "1.2.0", 0},
+		{"1.25", "1.25.0", -1},
+		{"1.25.3-rc1", "1.25.3", -1},
+		{"1.25.3", "1.25.3-rc1", 1},
+		{"1.25.3-rc1", "1.25.2", 1},
+		{"1.25.3-rc2", "1.25.3-rc10", -1},
+		{"1.25.3-beta.2", "1.25.3-beta.11", -1},
+		{"1.25.3-alpha", "1.25.3-beta", -1},
+		{"1.25.3-rc", "1.25.3-rc.1", -1},
+	}
+	for _, tt := range tests {
+		a, ok := ParseVersion(tt.a)
+		if !ok {
+			t.Fatalf("ParseVersion(%q) 失败", tt.a)
+		}
+		b, ok := ParseVersion(tt.b)
+		if !ok {
+			t.Fatalf("ParseVersion(%q) 失败", tt.b)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%s, %s) = %d，期望 %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseVersionConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		wantErr    bool
+		match      []string
+		noMatch    []string
+	}{
+		{constraint: "~1.25", match: []string{"1.25", "1.25.0", "1.25.9"}, noMatch: []string{"1.24.9", "1.26.0", "1.25.1-alpine"}},
+		{constraint: "~1.25.3", match: []string{"1.25.3", "1.25.10"}, noMatch: []string{"1.25.2", "1.26.0"}},
+		{constraint: "~1", match: []string{"1.0.0", "1.99.1"}, noMatch: []string{"0.9.9", "2.0.0"}},
+		{constraint: "~>1.25", match: []string{"1.25.4"}, noMatch: []string{"1.26.0"}},
+		{constraint: "^1.27", match: []string{"1.27.0", "1.99.0"}, noMatch: []string{"1.26.9", "2.0.0"}},
+		{constraint: "^0.3", match: []string{"0.3.0", "0.3.9"}, noMatch: []string{"0.2.9", "0.4.0", "1.0.0"}},
+		{constraint: "^0.3.2", match: []string{"0.3.2", "0.3.9"}, noMatch: []string{"0.3.1", "0.4.0"}},
+		{constraint: ">1.2", match: []string{"1.3.0", "2.0.0"}, noMatch: []string{"1.2.0", "1.2.9"}},
+		{constraint: ">1.2.3", match: []string{"1.2.4"}, noMatch: []string{"1.2.3"}},
+		{constraint: "<=1.2", match: []string{"1.2.0", "1.2.9", "1.1.0"}, noMatch: []string{"1.3.0"}},
+		{constraint: "<=1.2.3", match: []string{"1.2.3"}, noMatch: []string{"1.2.4"}},
+		{constraint: ">=1.2,<2", match: []string{"1.2.0", "1.9.9"}, noMatch: []string{"1.1.9", "2.0.0"}},
+		{constraint: ">=1.2 <2", match: []string{"1.5.0"}, noMatch: []string{"2.1.0"}},
+		{constraint: "1.25.x", match: []string{"1.25.0", "1.25.7"}, noMatch: []string{"1.26.0", "1.24.9"}},
+		{constraint: "1.x", match: []string{"1.0.0", "1.30.2"}, noMatch: []string{"2.0.0"}},
+		{constraint: "1.25.*", match: []string{"1.25.3"}, noMatch: []string{"1.26.0"}},
+		{constraint: "=1.25", match: []string{"1.25.0", "1.25.4"}, noMatch: []string{"1.26.0"}},
+		{constraint: "~1.25-alpine", match: []string{"1.25.3-alpine"}, noMatch: []string{"1.25.3", "1.25.3-slim"}},
+		{constraint: "", wantErr: true},
+		{constraint: ">=", wantErr: true},
+		{constraint: "~1.a", wantErr: true},
+		{constraint: "1.2.3.4", wantErr: true},
+		{constraint: ">=1.2-alpine,<2-slim", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraint, err := ParseVersionConstraint(tt.constraint)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望解析失败")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseVersionConstraint: %v", err)
+			}
+			for _, tag := range tt.match {
+				version, _ := ParseVersion(tag)
+				if !constraint.Match(version) {
+					t.Errorf("%s 应匹配 %s", tt.constraint, tag)
+				}
+			}
+			for _, tag := range tt.noMatch {
+				version, _ := ParseVersion(tag)
+				if constraint.Match(version) {
+					t.Errorf("%s 不应匹配 %s", tt.constraint, tag)
+				}
+			}
+		})
+	}
+}
+
+func TestNewVersionRange(t *testing.T) {
+	bound := func(numbers [3]int, inclusive bool) *versionBound {
+		return &versionBound{numbers: numbers, inclusive: inclusive}
+	}
+	tests := []struct {
+		name    string
+		op      string
+		numbers [3]int
+		parts   int
+		want    versionRange
+	}{
+		{"~ 两段", "~", [3]int{1, 25, 0}, 2, versionRange{bound([3]int{1, 25, 0}, true), bound([3]int{1, 26, 0}, false)}},
+		{"~ 三段", "~", [3]int{1, 25, 3}, 3, versionRange{bound([3]int{1, 25, 3}, true), bound([3]int{1, 26, 0}, false)}},
+		{"~ 一段", "~", [3]int{1, 0, 0}, 1, versionRange{bound([3]int{1, 0, 0}, true), bound([3]int{2, 0, 0}, false)}},
+		{"^ 主版本", "^", [3]int{1, 27, 0}, 2, versionRange{bound([3]int{1, 27, 0}, true), bound([3]int{2, 0, 0}, false)}},
+		{"^0.x", "^", [3]int{0, 3, 0}, 2, versionRange{bound([3]int{0, 3, 0}, true), bound([3]int{0, 4, 0}, false)}},
+		{"^0", "^", [3]int{0, 0, 0}, 1, versionRange{bound([3]int{0, 0, 0}, true), bound([3]int{1, 0, 0}, false)}},
+		{"> 部分版本", ">", [3]int{1, 2, 0}, 2, versionRange{lower: bound([3]int{1, 3, 0}, true)}},
+		{"> 完整版本", ">", [3]int{1, 2, 3}, 3, versionRange{lower: bound([3]int{1, 2, 3}, false)}},
+		{"<= 部分版本", "<=", [3]int{1, 2, 0}, 2, versionRange{upper: bound([3]int{1, 3, 0}, false)}},
+		{"<= 完整版本", "<=", [3]int{1, 2, 3}, 3, versionRange{upper: bound([3]int{1, 2, 3}, true)}},
+		{"<", "<", [3]int{2, 0, 0}, 1, versionRange{upper: bound([3]int{2, 0, 0}, false)}},
+		{">=", ">=", [3]int{1, 2, 0}, 2, versionRange{lower: bound([3]int{1, 2, 0}, true)}},
+		{"通配 1.25.x", "", [3]int{1, 25, 0}, 2, versionRange{bound([3]int{1, 25, 0}, true), bound([3]int{1, 26, 0}, false)}},
+		{"精确版本", "=", [3]int{1, 25, 3}, 3, versionRange{bound([3]int{1, 25, 3}, true), bound([3]int{1, 25, 3}, true)}},
+		{"全部通配", "", [3]int{}, 0, versionRange{lower: bound([3]int{}, true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newVersionRange(tt.op, tt.numbers, tt.parts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newVersionRange(%q, %v, %d) = {%v %v}，期望 {%v %v}",
+					tt.op, tt.numbers, tt.parts, got.lower, got.upper, tt.want.lower, tt.want.upper)
+			}
+		})
+	}
+}
+
+func TestMatchTags(t *testing.T) {
+	tags := []string{
+		"latest", "1.24.0", "1.25.0", "1.25.3", "1.25.3-rc1", "1.25.10", "v1.25.4",
+		"1.25.3-alpine", "1.25.10-alpine", "1.26.0-rc1", "1.26.0", "2.0.0", "stable",
+	}
+	tests := []struct {
+		constraint string
+		want       []string
+	}{
+		{"~1.25", []string{"1.25.10", "v1.25.4", "1.25.3", "1.25.0"}},
+		{"^1.25", []string{"1.26.0", "1.25.10", "v1.25.4", "1.25.3", "1.25.0"}},
+		{">=1.25,<1.26", []string{"1.25.10", "v1.25.4", "1.25.3", "1.25.0"}},
+		{"~1.25-alpine", []string{"1.25.10-alpine", "1.25.3-alpine"}},
+		{"~1.25-rc1", []string{"1.25.3-rc1"}},
+		{">2", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			constraint, err := ParseVersionConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseVersionConstraint: %v", err)
+			}
+			if got := MatchTags(tags, constraint); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchTags(%s) = %v，期望 %v", tt.constraint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortTagsBySemver(t *testing.T) {
+	tags := []string{"latest", "1.25.3-rc1", "1.25.2", "1.25.3", "1.25.3-rc10", "1.25.3-rc2", "alpine"}
+	SortTagsBySemver(tags)
+	want := []string{"1.25.3", "1.25.3-rc10", "1.25.3-rc2", "1.25.3-rc1", "1.25.2", "alpine", "latest"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("SortTagsBySemver = %v，期望 %v", tags, want)
+	}
+}
@@ -0,0 +1,77 @@
+package puller
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BundleSignatureName 离线传输清单签名文件名
+const BundleSignatureName = BundleManifestName + ".sig"
+
+// BundleSignature 对离线传输清单原始内容的 Ed25519 签名
+type BundleSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Signature []byte `json:"signature"`
+}
+
+// SignBundleManifest 使用 Ed25519 私钥签名目录中的离线传输清单
+func SignBundleManifest(dir string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(filepath.Join(dir, BundleManifestName))
+	if err != nil {
+		return fmt.Errorf("读取清单失败: %v", err)
+	}
+
+	signature := BundleSignature{
+		Algorithm: "ed25519",
+		KeyID:     KeyFingerprint(key.Public()),
+		Signature: ed25519.Sign(key, data),
+	}
+	sigData, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, BundleSignatureName), sigData, 0644); err != nil {
+		return fmt.Errorf("写入签名失败: %v", err)
+	}
+	return nil
+}
+
+// HasBundleSignature 检查目录中的离线传输清单是否已签名
+func HasBundleSignature(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, BundleSignatureName))
+	return err == nil
+}
+
+// VerifyBundleSignature 使用受信任的公钥校验目录中离线传输清单的签名，返回签名者的指纹
+func VerifyBundleSignature(dir string, trusted []ed25519.PublicKey) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, BundleManifestName))
+	if err != nil {
+		return "", fmt.Errorf("读取清单失败: %v", err)
+	}
+	sigData, err := os.ReadFile(filepath.Join(dir, BundleSignatureName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("清单未签名")
+		}
+		return "", err
+	}
+
+	var signature BundleSignature
+	if err := json.Unmarshal(sigData, &signature); err != nil {
+		return "", fmt.Errorf("解析签名失败: %v", err)
+	}
+	if signature.Algorithm != "ed25519" {
+		return "", fmt.Errorf("不支持的签名算法: %s", signature.Algorithm)
+	}
+
+	for _, key := range trusted {
+		if ed25519.Verify(key, data, signature.Signature) {
+			return KeyFingerprint(key), nil
+		}
+	}
+	return "", fmt.Errorf("签名校验失败：清单已被篡改或签名者 %s 不在受信任的公钥中", signature.KeyID)
+}
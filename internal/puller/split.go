@@ -0,0 +1,446 @@
+package puller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SplitIndexSuffix 分卷索引文件的后缀，索引与分卷位于同一目录
+const SplitIndexSuffix = ".parts.json"
+
+// SplitIndex 分卷索引，记录原始文件和每个分卷的大小及 sha256
+type SplitIndex struct {
+	Version  int         `json:"version"`
+	Name     string      `json:"name"` // 合并后的文件名
+	Size     int64       `json:"size"`
+	SHA256   string      `json:"sha256"`
+	PartSize int64       `json:"part_size"`
+	Parts    []SplitPart `json:"parts"`
+}
+
+// SplitPart 单个分卷
+type SplitPart struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ParseSize 解析大小字符串，支持 K/M/G/T 单位（按 1024 进制），例如 4095M、2G、1.5G
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("无效的大小: %s（示例：4095M、2G）", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+// SplitIndexPath 返回输出文件对应的分卷索引路径
+func SplitIndexPath(outputFile string) string {
+	return outputFile + SplitIndexSuffix
+}
+
+// IsSplitIndex 判断文件名是否为分卷索引
+func IsSplitIndex(name string) bool {
+	return strings.HasSuffix(name, SplitIndexSuffix)
+}
+
+// ResolveSplitIndex 根据索引、任一分卷或合并后的文件名找到分卷索引
+func ResolveSplitIndex(name string) (string, bool) {
+	if IsSplitIndex(name) {
+		return name, true
+	}
+
+	candidates := []string{SplitIndexPath(name)}
+	if ext := filepath.Ext(name); len(ext) == 4 {
+		if _, err := strconv.Atoi(ext[1:]); err == nil {
+			candidates = append(candidates, SplitIndexPath(strings.TrimSuffix(name, ext)))
+		}
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// LoadSplitIndex 加载分卷索引
+func LoadSplitIndex(indexPath string) (*SplitIndex, error) {
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var index SplitIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("解析分卷索引 %s 失败: %v", indexPath, err)
+	}
+	if len(index.Parts) == 0 {
+		return nil, fmt.Errorf("分卷索引 %s 中没有分卷", indexPath)
+	}
+	// 分卷和合并后的文件都位于索引所在目录，名称中不允许出现路径，防止读写或删除目录外的文件
+	if !isPlainFileName(index.Name) {
+		return nil, fmt.Errorf("分卷索引 %s 中的文件名无效: %q", indexPath, index.Name)
+	}
+	for _, part := range index.Parts {
+		if !isPlainFileName(part.Name) {
+			return nil, fmt.Errorf("分卷索引 %s 中的分卷名称无效: %q", indexPath, part.Name)
+		}
+	}
+	return &index, nil
+}
+
+// splitWriter 将数据按固定大小写入编号分卷 <name>.001、<name>.002 ...，关闭时写入索引
+type splitWriter struct {
+	base     string
+	partSize int64
+	index    SplitIndex
+	total    hash.Hash
+	file     *os.File
+	part     hash.Hash
+	written  int64
+}
+
+// newSplitWriter 创建分卷写入器，outputFile 为合并后的文件路径
+func newSplitWriter(outputFile string, partSize int64) *splitWriter {
+	return &splitWriter{
+		base:     outputFile,
+		partSize: partSize,
+		index:    SplitIndex{Version: 1, Name: filepath.Base(outputFile), PartSize: partSize},
+		total:    sha256.New(),
+	}
+}
+
+func (w *splitWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if w.file == nil || w.written == w.partSize {
+			if err := w.nextPart(); err != nil {
+				return n, err
+			}
+		}
+
+		chunk := p
+		if remaining := w.partSize - w.written; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		written, err := w.file.Write(chunk)
+		w.part.Write(chunk[:written])
+		w.total.Write(chunk[:written])
+		w.written += int64(written)
+		w.index.Size += int64(written)
+		n += written
+		if err != nil {
+			return n, err
+		}
+		p = p[written:]
+	}
+	return n, nil
+}
+
+// nextPart 结束当前分卷并创建下一个分卷
+func (w *splitWriter) nextPart() error {
+	if err := w.finishPart(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s.%03d", w.base, len(w.index.Parts)+1)
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("创建分卷失败: %v", err)
+	}
+	w.file = file
+	w.part = sha256.New()
+	w.written = 0
+	w.index.Parts = append(w.index.Parts, SplitPart{Name: filepath.Base(name)})
+	return nil
+}
+
+// finishPart 关闭当前分卷并记录其大小和 sha256
+func (w *splitWriter) finishPart() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+
+	part := &w.index.Parts[len(w.index.Parts)-1]
+	part.Size = w.written
+	part.SHA256 = "sha256:" + hex.EncodeToString(w.part.Sum(nil))
+	if err != nil {
+		return fmt.Errorf("写入分卷 %s 失败: %v", part.Name, err)
+	}
+	return nil
+}
+
+// Close 关闭最后一个分卷并写入索引
+func (w *splitWriter) Close() error {
+	// 空数据也生成一个分卷，保证索引可以正常合并
+	if len(w.index.Parts) == 0 {
+		if err := w.nextPart(); err != nil {
+			return err
+		}
+	}
+	if err := w.finishPart(); err != nil {
+		return err
+	}
+
+	w.index.SHA256 = "sha256:" + hex.EncodeToString(w.total.Sum(nil))
+	data, err := json.MarshalIndent(w.index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化分卷索引失败: %v", err)
+	}
+	if err := os.WriteFile(SplitIndexPath(w.base), data, 0644); err != nil {
+		return fmt.Errorf("写入分卷索引失败: %v", err)
+	}
+	return nil
+}
+
+// Abort 删除已写入的分卷
+func (w *splitWriter) Abort() {
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	dir := filepath.Dir(w.base)
+	for _, part := range w.index.Parts {
+		os.Remove(filepath.Join(dir, part.Name))
+	}
+	os.Remove(SplitIndexPath(w.base))
+}
+
+// removeSplitSet 删除已存在的分卷和索引，用于 --force 覆盖
+func removeSplitSet(outputFile string) {
+	indexPath := SplitIndexPath(outputFile)
+	if index, err := LoadSplitIndex(indexPath); err == nil {
+		dir := filepath.Dir(indexPath)
+		for _, part := range index.Parts {
+			os.Remove(filepath.Join(dir, part.Name))
+		}
+	}
+	os.Remove(indexPath)
+}
+
+// SplitFile 将已有文件切分为分卷并删除原文件，返回索引路径
+func SplitFile(path string, partSize int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	removeSplitSet(path)
+	writer := newSplitWriter(path, partSize)
+	if _, err := io.Copy(writer, file); err != nil {
+		writer.Abort()
+		return "", fmt.Errorf("切分文件失败: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		writer.Abort()
+		return "", err
+	}
+
+	file.Close()
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("删除原文件失败: %v", err)
+	}
+	return SplitIndexPath(path), nil
+}
+
+// splitReader 按顺序读取全部分卷，并在每个分卷读完时校验其大小和 sha256
+type splitReader struct {
+	dir   string
+	index *SplitIndex
+	next  int
+	file  *os.File
+	part  *SplitPart
+	hash  hash.Hash
+	read  int64
+}
+
+// OpenSplit 打开分卷索引，返回合并后的数据流；任一分卷缺失或损坏时读取返回错误
+func OpenSplit(indexPath string) (io.ReadCloser, *SplitIndex, error) {
+	index, err := LoadSplitIndex(indexPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &splitReader{dir: filepath.Dir(indexPath), index: index}, index, nil
+}
+
+func (r *splitReader) Read(p []byte) (int, error) {
+	for {
+		if r.file == nil {
+			if r.next >= len(r.index.Parts) {
+				return 0, io.EOF
+			}
+			r.part = &r.index.Parts[r.next]
+			r.next++
+			file, err := os.Open(filepath.Join(r.dir, r.part.Name))
+			if err != nil {
+				return 0, fmt.Errorf("打开分卷 %s 失败: %v", r.part.Name, err)
+			}
+			r.file = file
+			r.hash = sha256.New()
+			r.read = 0
+		}
+
+		n, err := r.file.Read(p)
+		r.hash.Write(p[:n])
+		r.read += int64(n)
+		if err == io.EOF {
+			r.file.Close()
+			r.file = nil
+			if r.read != r.part.Size {
+				return n, fmt.Errorf("分卷 %s 大小不一致（索引 %d，实际 %d）", r.part.Name, r.part.Size, r.read)
+			}
+			if digest := "sha256:" + hex.EncodeToString(r.hash.Sum(nil)); digest != r.part.SHA256 {
+				return n, fmt.Errorf("分卷 %s 已损坏（索引 %s，实际 %s）", r.part.Name, r.part.SHA256, digest)
+			}
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+func (r *splitReader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
+
+// VerifySplit 校验分卷索引中的全部分卷以及合并后的 sha256
+func VerifySplit(indexPath string) (*SplitIndex, error) {
+	reader, index, err := OpenSplit(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return index, err
+	}
+	if digest := "sha256:" + hex.EncodeToString(hasher.Sum(nil)); digest != index.SHA256 {
+		return index, fmt.Errorf("合并后的 sha256 不一致（索引 %s，实际 %s）", index.SHA256, digest)
+	}
+	return index, nil
+}
+
+// JoinSplit 校验并合并分卷，output 为空时输出到索引所在目录下的原文件名，返回合并后的文件路径
+func JoinSplit(indexPath, output string, force bool) (string, error) {
+	reader, index, err := OpenSplit(indexPath)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	if output == "" {
+		output = filepath.Join(filepath.Dir(indexPath), index.Name)
+	}
+	if !force {
+		if _, err := os.Stat(output); err == nil {
+			return "", fmt.Errorf("输出文件 %s 已存在，使用 --force 覆盖", output)
+		}
+	}
+
+	// 先写入临时文件，全部校验通过后再重命名，避免留下不完整的文件
+	tmpFile := output + ".joining"
+	file, err := os.Create(tmpFile)
+	if err != nil {
+		return "", fmt.Errorf("创建输出文件失败: %v", err)
+	}
+
+	hasher := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hasher), reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		if digest := "sha256:" + hex.EncodeToString(hasher.Sum(nil)); digest != index.SHA256 {
+			err = fmt.Errorf("合并后的 sha256 不一致（索引 %s，实际 %s）", index.SHA256, digest)
+		}
+	}
+	if err != nil {
+		os.Remove(tmpFile)
+		return "", err
+	}
+
+	if err := os.Rename(tmpFile, output); err != nil {
+		os.Remove(tmpFile)
+		return "", fmt.Errorf("重命名输出文件失败: %v", err)
+	}
+	return output, nil
+}
+
+// RemoveArchive 删除归档，分卷索引会连同全部分卷一起删除
+func RemoveArchive(path string) error {
+	if IsSplitIndex(path) {
+		index, err := LoadSplitIndex(path)
+		if err != nil {
+			return err
+		}
+		for _, part := range index.Parts {
+			if err := os.Remove(filepath.Join(filepath.Dir(path), part.Name)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return os.Remove(path)
+}
+
+// openArchiveStream 打开归档的原始数据流，分卷索引返回合并后的数据流
+func openArchiveStream(path string) (io.ReadCloser, error) {
+	if IsSplitIndex(path) {
+		reader, _, err := OpenSplit(path)
+		return reader, err
+	}
+	return os.Open(path)
+}
+
+// detectArchiveCompression 检测归档（包括分卷索引）的压缩格式
+func detectArchiveCompression(path string) (string, error) {
+	if !IsSplitIndex(path) {
+		return DetectFileCompression(path)
+	}
+
+	stream, err := openArchiveStream(path)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	magic := make([]byte, 4)
+	n, _ := io.ReadFull(stream, magic)
+	return compressionFromMagic(magic[:n]), nil
+}
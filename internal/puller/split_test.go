@@ -0,0 +1,224 @@
+package puller
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// splitTestFile 写入指定大小的测试数据并切分，返回数据和索引路径
+func splitTestFile(t *testing.T, size int, partSize int64) ([]byte, string) {
+	t.Helper()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i*7 + i/251)
+	}
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	indexPath, err := SplitFile(path, partSize)
+	if err != nil {
+		t.Fatalf("SplitFile: %v", err)
+	}
+	return data, indexPath
+}
+
+func TestSplitJoinRoundTrip(t *testing.T) {
+	const partSize = 1024
+	tests := []struct {
+		name      string
+		size      int
+		wantParts []int64
+	}{
+		{"空文件", 0, []int64{0}},
+		{"小于分卷大小", partSize - 1, []int64{partSize - 1}},
+		{"等于分卷大小", partSize, []int64{partSize}},
+		{"超出一个字节", partSize + 1, []int64{partSize, 1}},
+		{"分卷大小的整数倍", 3 * partSize, []int64{partSize, partSize, partSize}},
+		{"多个分卷", 2*partSize + 100, []int64{partSize, partSize, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, indexPath := splitTestFile(t, tt.size, partSize)
+			dir := filepath.Dir(indexPath)
+			if _, err := os.Stat(filepath.Join(dir, "image.tar")); !os.IsNotExist(err) {
+				t.Errorf("切分后应删除原文件")
+			}
+
+			index, err := LoadSplitIndex(indexPath)
+			if err != nil {
+				t.Fatalf("LoadSplitIndex: %v", err)
+			}
+			if index.Name != "image.tar" || index.Size != int64(tt.size) || index.PartSize != partSize {
+				t.Errorf("索引 = %+v", index)
+			}
+			if len(index.Parts) != len(tt.wantParts) {
+				t.Fatalf("分卷数 = %d，期望 %d", len(index.Parts), len(tt.wantParts))
+			}
+			for i, part := range index.Parts {
+				if part.Size != tt.wantParts[i] {
+					t.Errorf("分卷 %s 大小 = %d，期望 %d", part.Name, part.Size, tt.wantParts[i])
+				}
+			}
+
+			if _, err := VerifySplit(indexPath); err != nil {
+				t.Errorf("VerifySplit: %v", err)
+			}
+			output, err := JoinSplit(indexPath, "", false)
+			if err != nil {
+				t.Fatalf("JoinSplit: %v", err)
+			}
+			if output != filepath.Join(dir, "image.tar") {
+				t.Errorf("输出路径 = %s", output)
+			}
+			joined, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(joined, data) {
+				t.Errorf("合并后的内容与原文件不一致")
+			}
+
+			if _, err := JoinSplit(indexPath, "", false); err == nil || !strings.Contains(err.Error(), "--force") {
+				t.Errorf("输出文件已存在时应返回错误，实际 %v", err)
+			}
+			if _, err := JoinSplit(indexPath, "", true); err != nil {
+				t.Errorf("JoinSplit --force: %v", err)
+			}
+		})
+	}
+}
+
+func TestJoinSplitDetectsDamage(t *testing.T) {
+	const partSize = 1024
+	tests := []struct {
+		name    string
+		damage  func(t *testing.T, dir string, index *SplitIndex)
+		wantErr string
+	}{
+		{
+			name: "分卷被截断",
+			damage: func(t *testing.T, dir string, index *SplitIndex) {
+				if err := os.Truncate(filepath.Join(dir, index.Parts[1].Name), partSize-10); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "大小不一致",
+		},
+		{
+			name: "分卷内容被修改",
+			damage: func(t *testing.T, dir string, index *SplitIndex) {
+				path := filepath.Join(dir, index.Parts[0].Name)
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data[10] ^= 0xff
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "已损坏",
+		},
+		{
+			name: "分卷缺失",
+			damage: func(t *testing.T, dir string, index *SplitIndex) {
+				os.Remove(filepath.Join(dir, index.Parts[2].Name))
+			},
+			wantErr: "打开分卷",
+		},
+		{
+			name: "索引中的 sha256 错误",
+			damage: func(t *testing.T, dir string, index *SplitIndex) {
+				index.SHA256 = "sha256:" + strings.Repeat("0", 64)
+			},
+			wantErr: "合并后的 sha256 不一致",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, indexPath := splitTestFile(t, 2*partSize+100, partSize)
+			dir := filepath.Dir(indexPath)
+			index, err := LoadSplitIndex(indexPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.damage(t, dir, index)
+			writeSplitIndex(t, indexPath, index)
+
+			if _, err := VerifySplit(indexPath); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifySplit 期望错误包含 %q，实际 %v", tt.wantErr, err)
+			}
+			if _, err := JoinSplit(indexPath, "", false); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("JoinSplit 期望错误包含 %q，实际 %v", tt.wantErr, err)
+			}
+			for _, name := range []string{"image.tar", "image.tar.joining"} {
+				if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+					t.Errorf("合并失败后不应留下 %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitIndexRejectsPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(index *SplitIndex)
+	}{
+		{"文件名指向上级目录", func(index *SplitIndex) { index.Name = "../../escaped.tar" }},
+		{"文件名为绝对路径", func(index *SplitIndex) { index.Name = "/tmp/escaped.tar" }},
+		{"文件名包含反斜杠", func(index *SplitIndex) { index.Name = `..\escaped.tar` }},
+		{"文件名为空", func(index *SplitIndex) { index.Name = "" }},
+		{"分卷名称指向上级目录", func(index *SplitIndex) { index.Parts[0].Name = "../image.tar.001" }},
+		{"分卷名称包含子目录", func(index *SplitIndex) { index.Parts[0].Name = "sub/image.tar.001" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, indexPath := splitTestFile(t, 100, 1024)
+			// 索引放在子目录中，使 ../ 指向的位置仍在测试的临时目录内
+			subDir := filepath.Join(filepath.Dir(indexPath), "a", "b")
+			if err := os.MkdirAll(subDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			index, err := LoadSplitIndex(indexPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, part := range index.Parts {
+				if err := os.Rename(filepath.Join(filepath.Dir(indexPath), part.Name), filepath.Join(subDir, part.Name)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tt.modify(index)
+			maliciousPath := filepath.Join(subDir, "image.tar"+SplitIndexSuffix)
+			writeSplitIndex(t, maliciousPath, index)
+
+			if _, err := LoadSplitIndex(maliciousPath); err == nil || !strings.Contains(err.Error(), "无效") {
+				t.Errorf("LoadSplitIndex 期望返回名称无效的错误，实际 %v", err)
+			}
+			if _, err := JoinSplit(maliciousPath, "", true); err == nil {
+				t.Errorf("JoinSplit 期望返回错误")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(indexPath), "escaped.tar")); !os.IsNotExist(err) {
+				t.Errorf("不应在索引目录外写入文件")
+			}
+		})
+	}
+}
+
+// writeSplitIndex 写入分卷索引
+func writeSplitIndex(t *testing.T, path string, index *SplitIndex) {
+	t.Helper()
+	data, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
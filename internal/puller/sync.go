@@ -0,0 +1,294 @@
+package puller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SyncConfig 同步任务配置文件
+type SyncConfig struct {
+	Target       string      `yaml:"target"`        // 目标仓库地址和命名空间，例如 harbor.local/mirror
+	State        string      `yaml:"state"`         // 状态文件，相对路径基于配置文件所在目录
+	Platforms    []string    `yaml:"platforms"`     // 要同步的平台，为空时只同步默认架构
+	AllPlatforms bool        `yaml:"all_platforms"` // 同步全部平台
+	Images       []SyncImage `yaml:"images"`
+}
+
+// SyncImage 同步配置中的单个源镜像
+type SyncImage struct {
+	Source string   `yaml:"source"` // 源镜像，例如 bitnami/redis 或 nginx:1.25
+	Tags   []string `yaml:"tags"`   // 标签或通配模式（例如 7.2.*），为空时使用 source 中的标签
+	Target string   `yaml:"target"` // 目标镜像路径（不含标签），默认为 <target>/<源镜像路径>
+}
+
+// UnmarshalYAML 支持直接使用字符串表示源镜像
+func (s *SyncImage) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		s.Source = strings.TrimSpace(value.Value)
+		return nil
+	}
+
+	type plain SyncImage
+	return value.Decode((*plain)(s))
+}
+
+// LoadSyncConfig 加载同步任务配置，state 未指定时为配置文件同目录的 <名称>.state.json
+func LoadSyncConfig(configPath string) (*SyncConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取同步配置失败: %v", err)
+	}
+
+	var syncConfig SyncConfig
+	if err := yaml.Unmarshal(data, &syncConfig); err != nil {
+		return nil, fmt.Errorf("解析同步配置失败: %v", err)
+	}
+
+	syncConfig.Target = strings.TrimSuffix(strings.TrimSpace(syncConfig.Target), "/")
+	for i, image := range syncConfig.Images {
+		if image.Source == "" {
+			return nil, fmt.Errorf("同步配置第 %d 项缺少源镜像", i+1)
+		}
+		if image.Target == "" && syncConfig.Target == "" {
+			return nil, fmt.Errorf("同步配置缺少目标仓库 target")
+		}
+	}
+
+	dir := filepath.Dir(configPath)
+	if syncConfig.State == "" {
+		name := strings.TrimSuffix(filepath.Base(configPath), filepath.Ext(configPath))
+		syncConfig.State = filepath.Join(dir, name+".state.json")
+	} else if !filepath.IsAbs(syncConfig.State) {
+		syncConfig.State = filepath.Join(dir, syncConfig.State)
+	}
+	return &syncConfig, nil
+}
+
+// SyncJob 展开后的单个同步任务
+type SyncJob struct {
+	Source string // 带标签的源镜像引用
+	Target string // 带标签的目标镜像引用
+}
+
+// isTagPattern 判断标签是否为通配模式
+func isTagPattern(tag string) bool {
+	return strings.ContainsAny(tag, "*?[")
+}
+
+// ExpandSyncJobs 展开同步配置中的标签和通配模式，通配模式会查询镜像源的标签列表；
+// 无法展开的源镜像以失败结果返回
+func (p *MultiRegistryImagePuller) ExpandSyncJobs(syncConfig *SyncConfig, username, password string) ([]SyncJob, []SyncResult) {
+	var jobs []SyncJob
+	var failed []SyncResult
+	seen := make(map[string]bool)
+
+	for _, image := range syncConfig.Images {
+		imageInfo := p.ParseImageInput(image.Source)
+		target := image.Target
+		if target == "" {
+			target = syncConfig.Target + "/" + imageInfo.Repository
+		}
+		name := strings.TrimSuffix(image.Source, ":"+imageInfo.Tag)
+
+		tags := image.Tags
+		if len(tags) == 0 {
+			tags = []string{imageInfo.Tag}
+		}
+
+		var patterns []string
+		var resolved []string
+		for _, tag := range tags {
+			if isTagPattern(tag) {
+				patterns = append(patterns, tag)
+			} else {
+				resolved = append(resolved, tag)
+			}
+		}
+
+		if len(patterns) > 0 {
+			_, _, available, err := p.ListTags(name, username, password)
+			matched := 0
+			for _, tag := range available {
+				for _, pattern := range patterns {
+					if ok, _ := path.Match(pattern, tag); ok {
+						resolved = append(resolved, tag)
+						matched++
+						break
+					}
+				}
+			}
+			if err == nil && matched == 0 {
+				err = fmt.Errorf("没有匹配 %s 的标签", strings.Join(patterns, ", "))
+			}
+			if err != nil {
+				failed = append(failed, SyncResult{
+					Job:    SyncJob{Source: name + ":" + strings.Join(patterns, ","), Target: target},
+					Status: SyncFailed,
+					Err:    err,
+				})
+			}
+		}
+
+		for _, tag := range resolved {
+			job := SyncJob{Source: name + ":" + tag, Target: target + ":" + tag}
+			if !seen[job.Target] {
+				seen[job.Target] = true
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs, failed
+}
+
+// 同步状态
+const (
+	SyncAdded     = "added"
+	SyncUpdated   = "updated"
+	SyncUnchanged = "unchanged"
+	SyncFailed    = "failed"
+)
+
+// SyncResult 单个同步任务的结果
+type SyncResult struct {
+	Job    SyncJob
+	Status string
+	Copy   *CopyResult
+	Err    error
+}
+
+// SyncState 同步状态文件，记录每个目标镜像上次同步的源清单digest
+type SyncState struct {
+	Version   int                       `json:"version"`
+	UpdatedAt string                    `json:"updated_at"`
+	Images    map[string]SyncStateEntry `json:"images"` // 键为目标镜像引用
+}
+
+// SyncStateEntry 单个目标镜像的同步状态
+type SyncStateEntry struct {
+	Source       string `json:"source"`
+	Registry     string `json:"registry"`
+	SourceDigest string `json:"source_digest"`
+	Digest       string `json:"digest"`
+	Options      string `json:"options,omitempty"` // 复制选项指纹，选项变化后即使源清单未变也重新复制
+	SyncedAt     string `json:"synced_at"`
+}
+
+// syncFingerprint 计算影响复制结果的选项（平台、全部平台、单架构和目标）的指纹
+func syncFingerprint(target string, options CopyOptions) string {
+	platforms := append([]string(nil), options.Platforms...)
+	sort.Strings(platforms)
+	arch := ""
+	if len(platforms) == 0 && !options.AllPlatforms {
+		arch = options.Arch
+	}
+	data := fmt.Sprintf("target=%s\nplatforms=%s\nall_platforms=%t\narch=%s", target, strings.Join(platforms, ","), options.AllPlatforms, arch)
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LoadSyncState 加载同步状态文件，文件不存在时返回空状态
+func LoadSyncState(statePath string) (*SyncState, error) {
+	state := &SyncState{Images: make(map[string]SyncStateEntry)}
+	data, err := os.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析状态文件 %s 失败: %v", statePath, err)
+	}
+	if state.Images == nil {
+		state.Images = make(map[string]SyncStateEntry)
+	}
+	return state, nil
+}
+
+// Save 保存同步状态，先写临时文件再重命名，避免中断时损坏状态文件
+func (s *SyncState) Save(statePath string) error {
+	s.Version = 1
+	s.UpdatedAt = time.Now().Format(time.RFC3339)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态文件失败: %v", err)
+	}
+	if dir := filepath.Dir(statePath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	tmpPath := statePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+	return os.Rename(tmpPath, statePath)
+}
+
+// Sync 依次执行同步任务，只复制源清单digest或复制选项发生变化的镜像，并更新 state；
+// statePath 不为空时每个任务成功后立即保存状态，中断后重新运行不会重复复制已完成的镜像
+func (p *MultiRegistryImagePuller) Sync(jobs []SyncJob, state *SyncState, statePath string, options CopyOptions) []SyncResult {
+	results := make([]SyncResult, 0, len(jobs))
+	for _, job := range jobs {
+		previous, known := state.Images[job.Target]
+		fingerprint := syncFingerprint(job.Target, options)
+
+		jobOptions := options
+		if known && previous.Options == fingerprint {
+			jobOptions.PreviousDigest = previous.SourceDigest
+			jobOptions.PreviousTargetDigest = previous.Digest
+		}
+
+		result := SyncResult{Job: job}
+		copyResult, err := p.CopyImage(job.Source, job.Target, jobOptions)
+		result.Copy = copyResult
+		switch {
+		case err != nil:
+			result.Status = SyncFailed
+			result.Err = err
+		case copyResult.Skipped:
+			result.Status = SyncUnchanged
+		case known:
+			result.Status = SyncUpdated
+		default:
+			result.Status = SyncAdded
+		}
+
+		if err == nil {
+			state.Images[job.Target] = SyncStateEntry{
+				Source:       job.Source,
+				Registry:     copyResult.Registry,
+				SourceDigest: copyResult.SourceDigest,
+				Digest:       copyResult.Digest,
+				Options:      fingerprint,
+				SyncedAt:     time.Now().Format(time.RFC3339),
+			}
+			if statePath != "" {
+				if err := state.Save(statePath); err != nil {
+					log.Printf("⚠️ 保存状态文件失败: %v", err)
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// SortSyncResults 按目标镜像排序
+func SortSyncResults(results []SyncResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Job.Target < results[j].Job.Target
+	})
+}
@@ -0,0 +1,116 @@
+package puller
+
+import (
+	"fmt"
+	"strings"
+
+	"dockerops/internal/config"
+)
+
+// 镜像打标签模式
+const (
+	TagModeDefault  = "default"  // 沿用 remove_registry_prefix 的行为
+	TagModeOriginal = "original" // 使用用户输入的原始镜像引用
+	TagModeMirror   = "mirror"   // 使用实际拉取的镜像仓库地址和路径
+	TagModeCustom   = "custom"   // 只使用 --tag 指定的目标
+)
+
+// ValidateTagMode 校验打标签模式，多个模式可以用逗号分隔
+func ValidateTagMode(mode string) error {
+	for _, m := range splitTagModes(mode) {
+		switch m {
+		case TagModeDefault, TagModeOriginal, TagModeMirror, TagModeCustom:
+		default:
+			return fmt.Errorf("不支持的打标签模式: %s（可选: default, original, mirror, custom）", m)
+		}
+	}
+	return nil
+}
+
+// splitTagModes 拆分逗号分隔的打标签模式
+func splitTagModes(mode string) []string {
+	var modes []string
+	for _, m := range strings.Split(mode, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m != "" {
+			modes = append(modes, m)
+		}
+	}
+	return modes
+}
+
+// normalizeReference 为没有标签的镜像引用补全标签；docker load 不接受 RepoTags 中的摘要引用，
+// 带 @sha256: 的引用会去掉摘要部分，只保留标签（没有标签时使用 defaultTag）
+func normalizeReference(ref, defaultTag string) string {
+	ref = strings.TrimSpace(ref)
+	if i := strings.Index(ref, "@"); i != -1 {
+		ref = ref[:i]
+	}
+	name := ref
+	if i := strings.LastIndex(name, "/"); i != -1 {
+		name = name[i+1:]
+	}
+	if strings.Contains(name, ":") {
+		return ref
+	}
+	// 按摘要拉取时 defaultTag 可能是摘要，不能作为标签
+	if defaultTag == "" || strings.Contains(defaultTag, ":") {
+		defaultTag = "latest"
+	}
+	return ref + ":" + defaultTag
+}
+
+// resolveRepoTags 根据打标签模式生成导入后的 RepoTags
+func (p *MultiRegistryImagePuller) resolveRepoTags(imageInput string, registry *config.RegistryConfig, imageInfo ImageInfo, options PullOptions) []string {
+	mode := options.TagMode
+	if mode == "" {
+		mode = p.configManager.GetConfig().Settings.TagMode
+	}
+	modes := splitTagModes(mode)
+	if len(modes) == 0 {
+		modes = []string{TagModeDefault}
+		if len(options.Tags) > 0 {
+			modes = []string{TagModeCustom}
+		}
+	}
+
+	var repoTags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			repoTags = append(repoTags, tag)
+		}
+	}
+
+	for _, m := range modes {
+		switch m {
+		case TagModeOriginal:
+			add(normalizeReference(imageInput, imageInfo.Tag))
+		case TagModeMirror:
+			add(fmt.Sprintf("%s/%s:%s", registry.URL, imageInfo.Repository, imageInfo.Tag))
+		case TagModeDefault:
+			add(p.defaultRepoTag(imageInfo))
+		}
+	}
+
+	// --tag 指定的目标总是追加在最后
+	for _, tag := range options.Tags {
+		add(normalizeReference(tag, imageInfo.Tag))
+	}
+
+	// custom 模式下未指定任何 --tag 时回退到默认行为
+	if len(repoTags) == 0 {
+		add(p.defaultRepoTag(imageInfo))
+	}
+
+	return repoTags
+}
+
+// defaultRepoTag 按 remove_registry_prefix 设置生成默认的 RepoTag
+func (p *MultiRegistryImagePuller) defaultRepoTag(imageInfo ImageInfo) string {
+	if p.configManager.GetConfig().Settings.RemoveRegistryPrefix {
+		return fmt.Sprintf("%s:%s", imageInfo.Image, imageInfo.Tag)
+	}
+	return fmt.Sprintf("%s:%s", imageInfo.Repository, imageInfo.Tag)
+}
@@ -0,0 +1,110 @@
+package puller
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"dockerops/internal/config"
+)
+
+// tagListPageSize 每次请求标签列表的数量
+const tagListPageSize = 1000
+
+// FetchTags 获取仓库中镜像的全部标签，按 Link 头自动翻页
+func (p *MultiRegistryImagePuller) FetchTags(registry *config.RegistryConfig, repository, token string) ([]string, error) {
+	base := fmt.Sprintf("https://%s", registry.URL)
+	next := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", base, repository, tagListPageSize)
+
+	var tags []string
+	for next != "" {
+		req, err := http.NewRequest("GET", next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := p.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("请求失败: %v", err)
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("获取标签列表失败，状态码: %d", resp.StatusCode)
+		} else if decodeErr := json.NewDecoder(resp.Body).Decode(&page); decodeErr != nil {
+			err = fmt.Errorf("解析标签列表失败: %v", decodeErr)
+		}
+		link := resp.Header.Get("Link")
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, page.Tags...)
+		next = nextPageURL(base, link)
+	}
+	return tags, nil
+}
+
+// nextPageURL 解析分页响应中的 Link 头（<url>; rel="next"），没有下一页时返回空字符串
+func nextPageURL(base, link string) string {
+	if link == "" || !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start == -1 || end <= start {
+		return ""
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	return baseURL.ResolveReference(ref).String()
+}
+
+// ListTags 依次在可用的镜像仓库中查询镜像的标签列表，返回第一个成功的仓库和全部标签。
+// imageInput 中的标签会被忽略
+func (p *MultiRegistryImagePuller) ListTags(imageInput, username, password string) (*config.RegistryConfig, ImageInfo, []string, error) {
+	imageInfo := p.ParseImageInput(imageInput)
+
+	availableRegistries := p.availableRegistries()
+	if len(availableRegistries) == 0 {
+		return nil, imageInfo, nil, fmt.Errorf("没有可用的镜像仓库")
+	}
+
+	for _, registry := range availableRegistries {
+		token, err := p.GetAuthToken(&registry, imageInfo.Repository, username, password)
+		if err != nil {
+			log.Printf("无法获取 %s 的认证: %v", registry.Name, err)
+			continue
+		}
+
+		tags, err := p.FetchTags(&registry, imageInfo.Repository, token)
+		if err != nil {
+			log.Printf("从 %s 获取标签列表失败: %v", registry.Name, err)
+			continue
+		}
+		if len(tags) == 0 {
+			continue
+		}
+
+		log.Printf("✅ 在 %s 找到镜像 %s 的 %d 个标签", registry.Name, imageInfo.Repository, len(tags))
+		return &registry, imageInfo, tags, nil
+	}
+
+	return nil, imageInfo, nil, fmt.Errorf("在所有可用仓库中都未找到镜像 %s 的标签", imageInfo.Repository)
+}
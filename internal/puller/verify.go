@@ -0,0 +1,392 @@
+package puller
+
+import (
+	"archive/tar"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxMetadataSize 检查归档时缓存到内存中的单个文件上限，用于读取 manifest.json 和镜像配置
+const maxMetadataSize = 4 << 20
+
+// ArchiveInfo 镜像归档的检查结果
+type ArchiveInfo struct {
+	Path   string
+	Size   int64
+	SHA256 string
+	Images []ArchiveImageInfo
+	Errors []string // 与具体镜像无关的错误，例如 tar 损坏
+	Delta  bool     // 增量归档，缺少的层需要通过 merge 从基线补全
+	// 加密归档；未提供密钥时只计算文件的 sha256，不检查内容
+	Encrypted bool
+}
+
+// ArchiveImageInfo 归档中单个镜像的信息和校验结果
+type ArchiveImageInfo struct {
+	ImageID  string
+	RepoTags []string
+	Platform string
+	Layers   int
+	DiffIDs  []string
+	Errors   []string
+}
+
+// OK 归档及其中所有镜像均校验通过
+func (a *ArchiveInfo) OK() bool {
+	if len(a.Errors) > 0 {
+		return false
+	}
+	for _, image := range a.Images {
+		if len(image.Errors) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashingWriter 计算写入数据的 sha256，并缓存不超过上限的内容
+type hashingWriter struct {
+	hash.Hash
+	data     []byte
+	overflow bool
+}
+
+func (w *hashingWriter) Write(p []byte) (int, error) {
+	w.Hash.Write(p)
+	if !w.overflow {
+		if len(w.data)+len(p) > maxMetadataSize {
+			w.overflow = true
+			w.data = nil
+		} else {
+			w.data = append(w.data, p...)
+		}
+	}
+	return len(p), nil
+}
+
+// InspectArchive 读取镜像归档（支持 gzip/zstd 压缩及分卷索引），计算文件的 sha256，
+// 并根据归档内的 manifest.json 校验每个镜像的配置 digest 和各层的 diff_id
+func InspectArchive(archivePath string) (*ArchiveInfo, error) {
+	return InspectArchiveWithKeys(archivePath, nil)
+}
+
+// InspectArchiveWithKeys 与 InspectArchive 相同，加密归档使用 keys 解密后检查内容，
+// keys 为空时只计算加密文件的 sha256
+func InspectArchiveWithKeys(archivePath string, keys *Keyring) (*ArchiveInfo, error) {
+	file, err := openArchiveStream(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info := &ArchiveInfo{Path: archivePath}
+	fileHasher := sha256.New()
+	counter := &countingWriter{}
+	raw := io.TeeReader(file, io.MultiWriter(fileHasher, counter))
+
+	buffered := bufio.NewReader(raw)
+	var plain io.Reader = buffered
+	if magic, _ := buffered.Peek(len(encryptionMagic)); IsEncrypted(magic) {
+		info.Encrypted = true
+		if keys == nil {
+			if _, err := io.Copy(io.Discard, buffered); err != nil {
+				return nil, fmt.Errorf("读取归档失败: %v", err)
+			}
+			info.Size = counter.n
+			info.SHA256 = "sha256:" + hex.EncodeToString(fileHasher.Sum(nil))
+			return info, nil
+		}
+		if plain, err = newDecryptReader(buffered, keys); err != nil {
+			return nil, err
+		}
+	}
+
+	reader, err := newDecompressReader(plain)
+	if err != nil {
+		return nil, fmt.Errorf("解压归档失败: %v", err)
+	}
+	defer reader.Close()
+
+	digests := make(map[string]string)
+	contents := make(map[string][]byte)
+	links := make(map[string]string)
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			info.Errors = append(info.Errors, fmt.Sprintf("读取归档失败: %v", err))
+			break
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			links[name] = path.Clean(path.Join(path.Dir(name), header.Linkname))
+		case tar.TypeLink:
+			links[name] = path.Clean(header.Linkname)
+		case tar.TypeReg:
+			writer := &hashingWriter{Hash: sha256.New()}
+			if _, err := io.Copy(writer, tarReader); err != nil {
+				info.Errors = append(info.Errors, fmt.Sprintf("读取 %s 失败: %v", name, err))
+				break
+			}
+			digests[name] = "sha256:" + hex.EncodeToString(writer.Sum(nil))
+			if !writer.overflow {
+				contents[name] = writer.data
+			}
+		}
+	}
+
+	// 读完剩余数据，保证整个文件都参与哈希计算
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return nil, fmt.Errorf("读取归档失败: %v", err)
+	}
+	info.Size = counter.n
+	info.SHA256 = "sha256:" + hex.EncodeToString(fileHasher.Sum(nil))
+
+	manifestData, ok := contents["manifest.json"]
+	if !ok {
+		info.Errors = append(info.Errors, "归档中未找到 manifest.json")
+		return info, nil
+	}
+
+	var entries []ArchiveManifestEntry
+	if err := json.Unmarshal(manifestData, &entries); err != nil {
+		info.Errors = append(info.Errors, fmt.Sprintf("解析 manifest.json 失败: %v", err))
+		return info, nil
+	}
+
+	resolve := func(name string) string {
+		name = path.Clean(name)
+		for i := 0; i < 16; i++ {
+			target, ok := links[name]
+			if !ok {
+				break
+			}
+			name = target
+		}
+		return name
+	}
+
+	// 增量归档中基线已有的层不在归档内，不视为缺失
+	missing := make(map[string]bool)
+	if data, ok := contents[DeltaMetadataName]; ok {
+		delta, err := parseDeltaInfo(data)
+		if err != nil {
+			info.Errors = append(info.Errors, err.Error())
+		} else {
+			info.Delta = true
+			for _, layer := range delta.Layers {
+				missing[path.Clean(layer.Path)] = true
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		info.Images = append(info.Images, verifyArchiveImage(entry, resolve, digests, contents, missing))
+	}
+
+	return info, nil
+}
+
+// verifyArchiveImage 校验归档中的单个镜像
+func verifyArchiveImage(entry ArchiveManifestEntry, resolve func(string) string, digests map[string]string, contents map[string][]byte, missing map[string]bool) ArchiveImageInfo {
+	image := ArchiveImageInfo{
+		RepoTags: entry.RepoTags,
+		Layers:   len(entry.Layers),
+	}
+
+	configName := resolve(entry.Config)
+	configDigest, ok := digests[configName]
+	if !ok {
+		image.Errors = append(image.Errors, fmt.Sprintf("缺少镜像配置 %s", entry.Config))
+		return image
+	}
+	image.ImageID = configDigest
+
+	// 配置文件名即镜像ID：<hex>.json 或 blobs/sha256/<hex>
+	expectedID := "sha256:" + strings.TrimSuffix(path.Base(configName), ".json")
+	if expectedID != configDigest {
+		image.Errors = append(image.Errors, fmt.Sprintf("镜像配置 %s 已损坏（实际 %s）", entry.Config, configDigest))
+	}
+
+	var imageConfig ImageConfig
+	if err := json.Unmarshal(contents[configName], &imageConfig); err != nil {
+		image.Errors = append(image.Errors, fmt.Sprintf("解析镜像配置失败: %v", err))
+		return image
+	}
+	image.Platform = imageConfig.OS + "/" + imageConfig.Architecture
+
+	diffIDs := imageConfig.RootFS.DiffIDs
+	image.DiffIDs = diffIDs
+	if len(diffIDs) != len(entry.Layers) {
+		image.Errors = append(image.Errors, fmt.Sprintf("层数量(%d)与 diff_ids 数量(%d)不一致", len(entry.Layers), len(diffIDs)))
+		return image
+	}
+
+	for i, layerPath := range entry.Layers {
+		resolved := resolve(layerPath)
+		digest, ok := digests[resolved]
+		if !ok && (missing[path.Clean(layerPath)] || missing[resolved]) {
+			continue
+		}
+		if !ok {
+			image.Errors = append(image.Errors, fmt.Sprintf("缺少第 %d 层 %s", i+1, layerPath))
+			continue
+		}
+		if digest == diffIDs[i] {
+			continue
+		}
+		// OCI 布局中的层 blob 可能是压缩的，此时只能校验其内容与地址一致
+		if strings.HasPrefix(resolved, "blobs/sha256/") && digest == "sha256:"+path.Base(resolved) {
+			continue
+		}
+		image.Errors = append(image.Errors, fmt.Sprintf("第 %d 层 %s 已损坏（期望 %s，实际 %s）", i+1, layerPath, diffIDs[i], digest))
+	}
+
+	return image
+}
+
+// hashArchiveStream 计算归档原始数据（分卷合并后）的大小和 sha256
+func hashArchiveStream(archivePath string) (int64, string, error) {
+	file, err := openArchiveStream(archivePath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, "sha256:" + hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// FileVerifyResult 单个归档文件的校验结果
+type FileVerifyResult struct {
+	Name       string // 相对于清单目录的路径
+	Path       string
+	InManifest bool
+	Info       *ArchiveInfo
+	Errors     []string // 文件级错误：缺失、大小或 sha256 不一致
+}
+
+// OK 文件及其中所有镜像均校验通过
+func (r *FileVerifyResult) OK() bool {
+	return len(r.Errors) == 0 && (r.Info == nil || r.Info.OK())
+}
+
+// IsArchiveFile 判断文件名是否为支持的镜像归档（包括分卷索引）
+func IsArchiveFile(name string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".tar.zst", SplitIndexSuffix, EncryptedSuffix} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyPath 校验目录或单个归档：重新计算文件的 sha256 并与离线传输清单比对，
+// 同时根据每个归档内部的 manifest.json 校验各层的 digest；加密归档在提供 keys 时解密后检查内容
+func VerifyPath(target string, keys *Keyring) ([]FileVerifyResult, error) {
+	stat, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := target
+	var names []string
+	if !stat.IsDir() {
+		dir = filepath.Dir(target)
+		names = []string{filepath.Base(target)}
+	}
+
+	manifest, err := LoadBundleManifest(filepath.Join(dir, BundleManifestName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if stat.IsDir() {
+		seen := make(map[string]bool)
+		if manifest != nil {
+			for _, file := range manifest.Files {
+				names = append(names, file.Name)
+				seen[file.Name] = true
+			}
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && IsArchiveFile(entry.Name()) && !seen[entry.Name()] {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	var results []FileVerifyResult
+	for _, name := range names {
+		result := FileVerifyResult{
+			Name: name,
+			Path: filepath.Join(dir, name),
+		}
+
+		var expected *BundleManifestFile
+		if manifest != nil {
+			expected = manifest.Find(name)
+			result.InManifest = expected != nil
+		}
+
+		if _, err := os.Stat(result.Path); err != nil {
+			result.Errors = append(result.Errors, "文件不存在")
+			results = append(results, result)
+			continue
+		}
+
+		info, err := InspectArchiveWithKeys(result.Path, keys)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			results = append(results, result)
+			continue
+		}
+		result.Info = info
+
+		if expected != nil {
+			if expected.Size != info.Size {
+				result.Errors = append(result.Errors, fmt.Sprintf("文件大小不一致（清单 %d，实际 %d）", expected.Size, info.Size))
+			}
+			if expected.SHA256 != info.SHA256 {
+				result.Errors = append(result.Errors, fmt.Sprintf("sha256 不一致（清单 %s，实际 %s）", expected.SHA256, info.SHA256))
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,47 @@
+package puller
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPathRejectsManifestPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr bool
+	}{
+		{"普通文件名", "nginx.tar", false},
+		{"上级目录", "../secret.tar", true},
+		{"子目录", "sub/nginx.tar", true},
+		{"Windows 路径", `..\secret.tar`, true},
+		{"绝对路径", "/etc/passwd", true},
+		{"空名称", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			data, err := json.Marshal(BundleManifest{Version: 1, Files: []BundleManifestFile{{Name: tt.file}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, BundleManifestName), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = VerifyPath(dir, nil)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "文件名无效") {
+					t.Fatalf("期望文件名无效的错误，实际 %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyPath: %v", err)
+			}
+		})
+	}
+}